@@ -0,0 +1,75 @@
+package sse
+
+import (
+	"sort"
+	"strings"
+)
+
+// HeaderCapabilities is the header clients use to advertise their Capabilities to servers.
+const HeaderCapabilities = "Sse-Capabilities"
+
+// Capabilities are features that a client advertises to the server when connecting, such as the supported
+// payload encodings or the maximum event size. The server can use them to adapt the events sent to each client
+// – for example, to send uncompressed payloads to older clients.
+//
+// Capabilities are sent using the Sse-Capabilities header, as a comma-separated list of names,
+// each optionally having a value:
+//
+//	Sse-Capabilities: encoding=msgpack, max-event-size=65536, conflation
+//
+// Names are case-insensitive and are stored lowercase. Values must not contain commas.
+// There is no predefined set of capabilities – the client and the server must agree on them.
+type Capabilities map[string]string
+
+// ParseCapabilities parses the value of a Sse-Capabilities header. Empty entries are ignored.
+func ParseCapabilities(header string) Capabilities {
+	c := Capabilities{}
+
+	for _, entry := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(entry, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		c[name] = strings.TrimSpace(value)
+	}
+
+	return c
+}
+
+// Has reports whether the capability with the given name was advertised.
+func (c Capabilities) Has(name string) bool {
+	_, ok := c[strings.ToLower(name)]
+	return ok
+}
+
+// Get returns the value of the capability with the given name. It is empty if the capability
+// doesn't have a value or if it wasn't advertised – use Has to distinguish between the two.
+func (c Capabilities) Get(name string) string {
+	return c[strings.ToLower(name)]
+}
+
+// String returns the header representation of the capabilities. The capabilities are sorted by name.
+func (c Capabilities) String() string {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	sb := strings.Builder{}
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+
+		sb.WriteString(strings.ToLower(name))
+		if v := c[name]; v != "" {
+			sb.WriteByte('=')
+			sb.WriteString(v)
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package sse_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestParseCapabilities(t *testing.T) {
+	t.Parallel()
+
+	c := sse.ParseCapabilities(" Encoding=msgpack, max-event-size = 1024,,conflation ")
+
+	require.Equal(t, sse.Capabilities{"encoding": "msgpack", "max-event-size": "1024", "conflation": ""}, c, "invalid capabilities parsed")
+	require.True(t, c.Has("Conflation"), "capability names are case-insensitive")
+	require.False(t, c.Has("compression"), "unexpected capability")
+	require.Equal(t, "msgpack", c.Get("ENCODING"), "invalid capability value")
+	require.Equal(t, "conflation, encoding=msgpack, max-event-size=1024", c.String(), "invalid header representation")
+	require.Empty(t, sse.ParseCapabilities(""), "no capabilities expected")
+}
+
+func TestClient_Capabilities(t *testing.T) {
+	var header string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(sse.HeaderCapabilities)
+
+		sess, err := sse.Upgrade(w, r)
+		require.NoError(t, err, "unexpected Upgrade error")
+		require.True(t, sess.Capabilities.Has("conflation"), "session capabilities not parsed")
+	}))
+	defer ts.Close()
+
+	c := &sse.Client{
+		HTTPClient:        ts.Client(),
+		ResponseValidator: sse.NoopValidator,
+		Capabilities:      sse.Capabilities{"encoding": "json", "conflation": ""},
+	}
+
+	require.ErrorIs(t, c.NewConnection(req(t, "", ts.URL, nil)).Connect(), io.EOF, "unexpected Connect error")
+	require.Equal(t, "conflation, encoding=json", header, "capabilities not advertised")
+}
+
+func TestLowBandwidthMode(t *testing.T) {
+	t.Parallel()
+
+	caps := sse.LowBandwidthMode{Conflation: true}.Capabilities()
+	require.Equal(t, "conflation, encoding=msgpack, keep-alive=60", caps.String(), "invalid default capabilities")
+
+	m, ok := sse.LowBandwidthModeFrom(caps)
+	require.True(t, ok, "low bandwidth mode not detected")
+	require.Equal(t, sse.LowBandwidthMode{Encoding: sse.EncodingMsgpack, KeepAlive: time.Minute, Conflation: true}, m, "invalid mode parsed")
+
+	_, ok = sse.LowBandwidthModeFrom(sse.Capabilities{"encoding": "msgpack"})
+	require.False(t, ok, "low bandwidth mode should require a keep-alive interval")
+}
+
+func TestClient_LowBandwidth(t *testing.T) {
+	var mode sse.LowBandwidthMode
+	var ok bool
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := sse.Upgrade(w, r)
+		require.NoError(t, err, "unexpected Upgrade error")
+		mode, ok = sse.LowBandwidthModeFrom(sess.Capabilities)
+	}))
+	defer ts.Close()
+
+	c := &sse.Client{
+		HTTPClient:        ts.Client(),
+		ResponseValidator: sse.NoopValidator,
+		Capabilities:      sse.Capabilities{"Encoding": "json"},
+		LowBandwidth:      &sse.LowBandwidthMode{KeepAlive: 5 * time.Minute},
+	}
+
+	require.ErrorIs(t, c.NewConnection(req(t, "", ts.URL, nil)).Connect(), io.EOF, "unexpected Connect error")
+	require.True(t, ok, "low bandwidth mode not advertised")
+	require.Equal(t, sse.LowBandwidthMode{Encoding: "json", KeepAlive: 5 * time.Minute}, mode, "explicit capabilities should take precedence")
+}
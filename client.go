@@ -3,12 +3,16 @@ package sse
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 	"unicode"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/tmaxmax/go-sse/internal/parser"
+	"golang.org/x/exp/slog"
 )
 
 // The ResponseValidator type defines the type of the function
@@ -20,6 +24,13 @@ import (
 // you're being rate limited (response code 429), for example.
 type ResponseValidator func(*http.Response) error
 
+// The RequestRedactor type defines the type of the function that removes secrets,
+// such as credentials, from requests which are exposed through errors, so they
+// can be logged safely. See the Client's documentation for more info.
+//
+// The given request must not be modified. Return a modified copy instead.
+type RequestRedactor func(*http.Request) *http.Request
+
 // The Client struct is used to initialize new connections to different servers.
 // It is safe for concurrent use.
 //
@@ -39,6 +50,11 @@ type Client struct {
 	// Otherwise, the error will be considered permanent and no reconnections
 	// will be attempted.
 	ResponseValidator ResponseValidator
+	// ShouldRetry decides whether a failed connection attempt is retried. It is called with the
+	// error and a nil response if the request failed, or with the error of the ResponseValidator
+	// and the rejected response. See DefaultShouldRetry for a classifier which follows the spec.
+	// If it is nil, all the failed attempts are retried, up to MaxRetries.
+	ShouldRetry func(err error, resp *http.Response) bool
 	// The maximum number of reconnection to attempt when an error occurs.
 	// If MaxRetries is negative (-1), infinite reconnection attempts will be done.
 	// Defaults to 0 (no retries).
@@ -49,6 +65,165 @@ type Client struct {
 	// time. This can be overridden by retry values sent by the server.
 	// Defaults to 5 seconds.
 	DefaultReconnectionTime time.Duration
+	// IgnoreServerRetry makes the client ignore the retry values sent by the server. By default,
+	// each retry value received replaces the reconnection delay for all the subsequent reconnection
+	// attempts of the Connect call, and resets the number of retries – see BackoffStrategy.SetRetry.
+	IgnoreServerRetry bool
+	// IgnoreRetryAfter makes the client ignore the Retry-After header of the responses with the status
+	// 429 Too Many Requests or 503 Service Unavailable. By default, if the attempt which received such
+	// a response is retried, the client waits for the duration in the header instead of its usual delay.
+	IgnoreRetryAfter bool
+	// Backoff creates the strategy which determines how long to wait before each reconnection attempt,
+	// once for each Connect call. If it is set, MaxRetries and DefaultReconnectionTime are ignored.
+	// If it is nil, a ConstantBackoff with those values is used.
+	Backoff func() BackoffStrategy
+	// UTF8Policy determines how received fields which are not valid UTF-8 are handled.
+	// Defaults to UTF8Replace, which is the behavior required by the spec.
+	UTF8Policy UTF8Policy
+	// MaxEventSize is the maximum size of a received event in bytes, including its field names,
+	// comments and newlines. Connect fails with ErrEventTooLarge when the server sends a larger event,
+	// so servers can't make the client buffer an unbounded amount of data. Defaults to 64 KiB.
+	// The data streamed to data stream callbacks isn't limited, as it isn't buffered – see StreamEventData.
+	MaxEventSize int
+	// The capabilities advertised to the server on each connection attempt,
+	// using the Sse-Capabilities header. Nothing is advertised if it is empty.
+	Capabilities Capabilities
+	// LowBandwidth makes the client advertise the capabilities of the given mode on each connection
+	// attempt, in addition to the Capabilities, which take precedence. Use it for constrained clients,
+	// such as mobile or IoT devices. If it is nil, nothing more is advertised.
+	LowBandwidth *LowBandwidthMode
+	// A function which removes secrets from the requests included in the errors
+	// returned by Connect or passed to OnRetry (see ConnectionError).
+	// Defaults to DefaultRedactor.
+	Redactor RequestRedactor
+	// ReresolveOnRetry makes reconnection attempts open a new connection to the server, so its hostname
+	// is resolved again, instead of reusing a pooled connection to an address that may be gone.
+	// To do this, the idle connections of the HTTPClient are closed before each reconnection attempt,
+	// so use an HTTP client dedicated to event streams. See FailoverDialer for also preferring the
+	// addresses which didn't fail recently.
+	ReresolveOnRetry bool
+	// The source of the bearer tokens sent in the Authorization header on each connection attempt.
+	// If it is a TokenRefresher, it also receives the new tokens pushed by the server.
+	// If it is nil, the request's Authorization header is left as is.
+	TokenSource TokenSource
+	// OnRequest is called before each connection attempt with the attempt's number, starting from 1,
+	// and the request about to be sent, which it can modify – for example, to refresh credentials,
+	// rotate headers or sign the request. The changes to the request's headers are kept for the
+	// next attempts. If it returns an error, the attempt fails with it and is retried as usual.
+	OnRequest func(attempt int, req *http.Request) error
+	// Logger logs the connection attempts, the rejected responses, the reconnections, the payloads
+	// which fail to decode in SubscribeJSON callbacks and the end of the connections, with the
+	// connection's ID and other details as attributes. By default, nothing is logged.
+	Logger *slog.Logger
+	// ProfileEvents enables measuring how long the network, the parser and the callbacks
+	// take for each received event. See ConnectionStats for more info.
+	ProfileEvents bool
+	// ErrorReporter receives the connection errors which are retried and the panics of the callbacks,
+	// which are then propagated further, and the payloads which fail to decode in SubscribeJSON callbacks.
+	// The errors returned by Connect are not reported.
+	ErrorReporter ErrorReporter
+	// MaxConnectionAge makes connections reconnect proactively after being connected for this long,
+	// sending the ID of the last event received. Use it with load balancers which kill connections
+	// at a fixed age, or to rebalance the connections across backends. A random duration of up to
+	// MaxConnectionAgeJitter is subtracted from the age of each connection, so connections made
+	// together don't reconnect together. If it is 0, connections are not recycled.
+	MaxConnectionAge time.Duration
+	// The maximum duration subtracted from MaxConnectionAge. Defaults to a tenth of MaxConnectionAge.
+	MaxConnectionAgeJitter time.Duration
+	// StreamEndIsSuccess makes Connect return nil instead of ErrStreamEnded when the server
+	// ends the stream by responding with 204 No Content.
+	StreamEndIsSuccess bool
+	// The URL of the server's EchoHandler. If it is set, each heartbeat received from the server
+	// triggers a round trip to it, used to estimate the offset between the client's clock and the
+	// server's. See ConnectionStats.ClockOffset and Connection.LocalTime.
+	EchoURL string
+	// The buffer size of the channels returned by Connection.Messages and Connection.Events,
+	// and of the channels through which the members of a ConsumerGroup receive events.
+	// Defaults to 0 (unbuffered).
+	ChannelBuffer int
+	// What the channels set by ChannelBuffer do with new events when they are full.
+	// Defaults to BackpressureBlock.
+	ChannelBackpressure Backpressure
+	// The number of events the PriorityLow callbacks of a connection may fall behind by, before
+	// the connection waits for them to catch up. See Priority. Defaults to DefaultLowPriorityBuffer.
+	LowPriorityBuffer int
+	// LastEventIDStore persists the ID of the last event received by each connection, so the stream
+	// is resumed from it even after the program restarts. The ID is loaded when Connect is called,
+	// unless one was set using Connection.StartFromEventID, and saved after the events with a new ID
+	// are dispatched. The IDs are saved in the background, so a slow store doesn't delay the events:
+	// if multiple IDs are received while an ID is saved, only the latest is saved afterwards. The latest
+	// ID is always saved before Connect returns. If it is nil, streams are resumed only within the same Connection.
+	LastEventIDStore LastEventIDStore
+	// LastEventIDKey returns the key with which the LastEventIDStore identifies the stream of a request.
+	// Defaults to DefaultLastEventIDKey.
+	LastEventIDKey func(*http.Request) string
+	// Codec decodes the payloads of the received events in Event.DecodePayload.
+	// Defaults to JSONCodec, which uses encoding/json.
+	Codec Codec
+	// ReadTimeout makes a connection attempt fail with ErrReadTimeout if nothing is received from the
+	// server for this long, so connections which died silently are reattempted as usual instead of
+	// hanging forever. Use it with servers which send keep-alive comments or heartbeats more often
+	// than this – see Server.KeepAlive. If it is 0, there is no timeout.
+	ReadTimeout time.Duration
+	// RecordEventMeta makes the received events carry the bytes they were parsed from and the time
+	// they were received at, for debugging, latency measurements or recording. See Event.Meta.
+	RecordEventMeta bool
+	// StreamEventData makes the connections pass the data of the events to the callbacks subscribed
+	// using SubscribeDataStream as it is received, instead of buffering the entire event first.
+	// Use it for events with very large data. See SubscribeDataStream.
+	StreamEventData bool
+	// PooledEventData makes the connections reuse the memory of the received events' data,
+	// instead of allocating it for each event, for consumers which receive many events.
+	// The Data of the events passed to the callbacks is then valid only until they return:
+	// use Event.Clone to retain the events, or copy the parts of their data which are retained.
+	// The events received through channels, iterators, consumer groups and PriorityLow callbacks
+	// are copied, as they are used after the dispatch returns.
+	// It has no effect on the events read when StreamEventData is set.
+	PooledEventData bool
+	// EventHooks filter or transform the events received by the connections before they are
+	// dispatched to the callbacks. They are called in order. See EventHook.
+	// They aren't called for the events whose data is streamed – see StreamEventData.
+	EventHooks []EventHook
+	// TrackLastEventIDs makes the connections record the ID of the last event received of each type,
+	// for servers which namespace the IDs of the streams they multiplex over one connection by type.
+	// See Connection.LastEventIDs.
+	TrackLastEventIDs bool
+	// AcceptEncoding is advertised to the servers using the Accept-Encoding header, so they compress
+	// the streams – for example, "gzip". The compressed streams are decoded before being parsed,
+	// regardless of this option. If it is empty, the header is set only by the HTTP client's transport.
+	AcceptEncoding string
+	// ContentDecoders decode the streams compressed using the content encodings they are mapped to,
+	// such as "br" or "zstd", which go-sse doesn't support itself. The gzip and deflate encodings are
+	// always supported, unless they are overridden here. Connections which receive streams compressed
+	// using other encodings fail with ErrUnsupportedEncoding.
+	ContentDecoders map[string]ContentDecoder
+}
+
+// UTF8Policy determines what a Connection does with received fields that aren't valid UTF-8.
+type UTF8Policy int
+
+const (
+	// UTF8Replace replaces invalid UTF-8 sequences with the Unicode replacement character (U+FFFD),
+	// as required by the spec. This is the default policy.
+	UTF8Replace UTF8Policy = iota
+	// UTF8PassThrough leaves the received bytes untouched. Use this if the server sends
+	// binary-ish data and you want to handle it yourself.
+	UTF8PassThrough
+	// UTF8Reject makes Connect return ErrInvalidUTF8 when invalid UTF-8 is received.
+	// The connection is not retried afterwards.
+	UTF8Reject
+)
+
+// mode returns the parser's mode which implements the policy.
+func (p UTF8Policy) mode() parser.UTF8Mode {
+	switch p {
+	case UTF8PassThrough:
+		return parser.UTF8PassThrough
+	case UTF8Reject:
+		return parser.UTF8Reject
+	default:
+		return parser.UTF8Replace
+	}
 }
 
 // NewConnection initializes and configures a connection. On connect, the given
@@ -69,15 +244,37 @@ func (c *Client) NewConnection(r *http.Request) *Connection {
 	conn := &Connection{
 		client:       *c,                   // we clone the client so the config cannot be modified from outside
 		request:      r.Clone(r.Context()), // we clone the request so its fields cannot be modified from outside
-		callbacks:    map[string]map[int]EventCallback{},
-		callbacksAll: map[int]EventCallback{},
+		callbacks:    map[string]map[int]callback{},
+		callbacksAll: map[int]callback{},
+		comments:     map[int]CommentCallback{},
+		retries:      map[int]RetryCallback{},
+		states:       map[int]StateCallback{},
+		dataStreams:  map[string]map[int]DataStreamCallback{},
+		state:        StateClosed,
+		stats:        newConnectionStats(),
+		id:           newConnectionID(),
 	}
 
 	return conn
 }
 
+// newBackoff returns the backoff of a Connect call and the function which sets the server's retry values.
+func (c *Client) newBackoff(ctx context.Context) (backoff.BackOff, func(time.Duration)) {
+	b, setRetry := c.newBaseBackoff(ctx)
+	if c.IgnoreServerRetry {
+		setRetry = func(time.Duration) {}
+	}
+
+	return b, setRetry
+}
+
 // See https://github.com/tmaxmax/go-sse/issues/20
-func (c *Client) newBackoff(ctx context.Context) (b backoff.BackOff, setRetry func(time.Duration)) {
+func (c *Client) newBaseBackoff(ctx context.Context) (b backoff.BackOff, setRetry func(time.Duration)) {
+	if c.Backoff != nil {
+		strategy := c.Backoff()
+		return backoff.WithContext(strategy, ctx), strategy.SetRetry
+	}
+
 	base := backoff.NewConstantBackOff(c.DefaultReconnectionTime)
 	b = backoff.WithContext(base, ctx)
 	if c.MaxRetries >= 0 {
@@ -93,6 +290,26 @@ func (c *Client) newBackoff(ctx context.Context) (b backoff.BackOff, setRetry fu
 	}
 }
 
+// connectionAge returns for how long the next connection is kept, or 0 if it is kept indefinitely.
+func (c *Client) connectionAge() time.Duration {
+	if c.MaxConnectionAge <= 0 {
+		return 0
+	}
+
+	jitter := c.MaxConnectionAgeJitter
+	if jitter <= 0 {
+		jitter = c.MaxConnectionAge / 10
+	}
+	if jitter >= c.MaxConnectionAge {
+		jitter = c.MaxConnectionAge - 1
+	}
+	if jitter <= 0 {
+		return c.MaxConnectionAge
+	}
+
+	return c.MaxConnectionAge - time.Duration(rand.Int63n(int64(jitter)+1)) //nolint:gosec // Jitter doesn't need a secure generator.
+}
+
 /* func (c *Client) newBackoff(ctx context.Context) (b backoff.BackOff, setRetry func(time.Duration)) {
 	base := backoff.NewExponentialBackOff()
 	base.InitialInterval = c.DefaultReconnectionTime
@@ -143,12 +360,56 @@ var NoopValidator ResponseValidator = func(_ *http.Response) error {
 	return nil
 }
 
+// RedactedValue is the value with which DefaultRedactor replaces secrets.
+const RedactedValue = "[REDACTED]"
+
+var redactedHeaders = [...]string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// DefaultRedactor is the default request redaction function. It returns a copy of the
+// request where the values of the Authorization, Proxy-Authorization and Cookie headers
+// and the password from the URL's user info are replaced with RedactedValue.
+// The request is returned as is if it contains no secrets.
+var DefaultRedactor RequestRedactor = func(r *http.Request) *http.Request {
+	_, hasPassword := r.URL.User.Password()
+	hasHeaders := false
+	for _, h := range redactedHeaders {
+		if _, ok := r.Header[h]; ok {
+			hasHeaders = true
+			break
+		}
+	}
+
+	if !hasPassword && !hasHeaders {
+		return r
+	}
+
+	redacted := r.Clone(r.Context())
+	if hasPassword {
+		redacted.URL.User = url.UserPassword(r.URL.User.Username(), RedactedValue)
+	}
+	for _, h := range redactedHeaders {
+		if vs, ok := redacted.Header[h]; ok {
+			for i := range vs {
+				vs[i] = RedactedValue
+			}
+		}
+	}
+
+	return redacted
+}
+
+// NoopRedactor is a request redaction function that returns the requests as they are.
+var NoopRedactor RequestRedactor = func(r *http.Request) *http.Request {
+	return r
+}
+
 // DefaultClient is the client that is used when creating a new connection using the NewConnection function.
 // Unset properties on new clients are replaced with the ones set for the default client.
 var DefaultClient = &Client{
 	HTTPClient:              http.DefaultClient,
 	DefaultReconnectionTime: time.Second * 5,
 	ResponseValidator:       DefaultValidator,
+	Redactor:                DefaultRedactor,
 }
 
 // NewConnection creates a connection using the default client.
@@ -169,4 +430,21 @@ func mergeDefaults(c *Client) {
 	if c.ResponseValidator == nil {
 		c.ResponseValidator = DefaultClient.ResponseValidator
 	}
+	if c.Redactor == nil {
+		c.Redactor = DefaultClient.Redactor
+	}
+}
+
+// capabilities returns the capabilities advertised to the server.
+func (c *Client) capabilities() Capabilities {
+	if c.LowBandwidth == nil {
+		return c.Capabilities
+	}
+
+	caps := c.LowBandwidth.Capabilities()
+	for name, value := range c.Capabilities {
+		caps[strings.ToLower(name)] = value
+	}
+
+	return caps
 }
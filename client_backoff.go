@@ -0,0 +1,148 @@
+package sse
+
+import (
+	"math/rand"
+	"time"
+)
+
+// StopBackoff is returned by a BackoffStrategy to stop reconnecting.
+const StopBackoff time.Duration = -1
+
+// BackoffStrategy determines how long a Connection waits before each reconnection attempt.
+// Its methods are called from the goroutine which called Connect, so it doesn't have to be
+// safe for concurrent use. Set the Client's Backoff to a function which creates a strategy
+// for each Connect call.
+//
+// ConstantBackoff, ExponentialBackoff and NoRetry are provided, but you can implement
+// other policies too – for example, ones which honor the server's retry values differently.
+type BackoffStrategy interface {
+	// NextBackOff returns the duration to wait before the next reconnection attempt,
+	// or StopBackoff to stop reconnecting. Connect then returns the last error.
+	NextBackOff() time.Duration
+	// Reset is called after a connection attempt succeeds.
+	Reset()
+	// SetRetry is called with the retry values received from the server.
+	SetRetry(time.Duration)
+}
+
+// ConstantBackoff waits the same interval before each reconnection attempt.
+// The retry values received from the server replace the interval.
+type ConstantBackoff struct {
+	// The interval between reconnection attempts.
+	Interval time.Duration
+	// The maximum number of consecutive reconnection attempts. If it is negative,
+	// reconnection is attempted indefinitely. If it is 0, no reconnection is attempted.
+	MaxRetries int
+
+	retries int
+}
+
+// NextBackOff returns the interval, if retries are left.
+func (b *ConstantBackoff) NextBackOff() time.Duration {
+	if b.MaxRetries >= 0 && b.retries >= b.MaxRetries {
+		return StopBackoff
+	}
+	b.retries++
+
+	return b.Interval
+}
+
+// Reset resets the number of retries.
+func (b *ConstantBackoff) Reset() { b.retries = 0 }
+
+// SetRetry sets the interval and resets the number of retries.
+func (b *ConstantBackoff) SetRetry(d time.Duration) {
+	b.Interval = d
+	b.Reset()
+}
+
+// ExponentialBackoff multiplies the time it waits after each failed reconnection attempt,
+// so failing servers aren't overwhelmed. The retry values received from the server replace
+// the initial interval.
+type ExponentialBackoff struct {
+	// The time waited before the first reconnection attempt. Defaults to 500 milliseconds.
+	InitialInterval time.Duration
+	// The maximum time waited before a reconnection attempt. Defaults to 1 minute.
+	MaxInterval time.Duration
+	// The factor by which the interval is multiplied after each attempt. Defaults to 2.
+	Multiplier float64
+	// Jitter randomizes each interval by up to this fraction of it, in both directions, so clients
+	// which were disconnected together don't reconnect together. It is between 0 and 1.
+	Jitter float64
+	// The maximum number of consecutive reconnection attempts. If it is negative,
+	// reconnection is attempted indefinitely. If it is 0, no reconnection is attempted.
+	MaxRetries int
+
+	retries  int
+	interval time.Duration
+}
+
+// NextBackOff returns the current interval, randomized by the Jitter, and multiplies it.
+func (b *ExponentialBackoff) NextBackOff() time.Duration {
+	if b.MaxRetries >= 0 && b.retries >= b.MaxRetries {
+		return StopBackoff
+	}
+	b.retries++
+
+	if b.interval <= 0 {
+		b.interval = b.initialInterval()
+	}
+
+	next := b.interval
+	if b.Jitter > 0 {
+		delta := b.Jitter * float64(next)
+		next = time.Duration(float64(next) - delta + rand.Float64()*2*delta) //nolint:gosec // Jitter doesn't need a secure generator.
+	}
+
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	maxInterval := b.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = time.Minute
+	}
+
+	if b.interval = time.Duration(float64(b.interval) * multiplier); b.interval > maxInterval {
+		b.interval = maxInterval
+	}
+
+	return next
+}
+
+// Reset resets the number of retries and the interval.
+func (b *ExponentialBackoff) Reset() {
+	b.retries = 0
+	b.interval = b.initialInterval()
+}
+
+// SetRetry sets the initial interval and resets the backoff.
+func (b *ExponentialBackoff) SetRetry(d time.Duration) {
+	b.InitialInterval = d
+	b.Reset()
+}
+
+func (b *ExponentialBackoff) initialInterval() time.Duration {
+	if b.InitialInterval <= 0 {
+		return 500 * time.Millisecond
+	}
+	return b.InitialInterval
+}
+
+// NoRetry never reconnects: Connect returns the first error which occurs.
+type NoRetry struct{}
+
+// NextBackOff always returns StopBackoff.
+func (NoRetry) NextBackOff() time.Duration { return StopBackoff }
+
+// Reset does nothing.
+func (NoRetry) Reset() {}
+
+// SetRetry does nothing.
+func (NoRetry) SetRetry(time.Duration) {}
+
+var (
+	_ BackoffStrategy = (*ConstantBackoff)(nil)
+	_ BackoffStrategy = (*ExponentialBackoff)(nil)
+	_ BackoffStrategy = NoRetry{}
+)
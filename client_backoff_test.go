@@ -0,0 +1,119 @@
+package sse_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestConstantBackoff(t *testing.T) {
+	t.Parallel()
+
+	b := &sse.ConstantBackoff{Interval: time.Second, MaxRetries: 2}
+	require.Equal(t, time.Second, b.NextBackOff(), "invalid interval")
+	require.Equal(t, time.Second, b.NextBackOff(), "invalid interval")
+	require.Equal(t, sse.StopBackoff, b.NextBackOff(), "retries should be exhausted")
+
+	b.SetRetry(time.Millisecond)
+	require.Equal(t, time.Millisecond, b.NextBackOff(), "retry value should replace the interval and reset the retries")
+
+	b = &sse.ConstantBackoff{Interval: time.Second, MaxRetries: -1}
+	for i := 0; i < 100; i++ {
+		require.Equal(t, time.Second, b.NextBackOff(), "negative MaxRetries should retry indefinitely")
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	t.Parallel()
+
+	b := &sse.ExponentialBackoff{InitialInterval: time.Second, MaxInterval: 5 * time.Second, MaxRetries: -1}
+	var intervals []time.Duration
+	for i := 0; i < 5; i++ {
+		intervals = append(intervals, b.NextBackOff())
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}, intervals, "invalid intervals")
+
+	b.Reset()
+	require.Equal(t, time.Second, b.NextBackOff(), "reset should restore the initial interval")
+
+	b.SetRetry(time.Millisecond)
+	require.Equal(t, time.Millisecond, b.NextBackOff(), "retry value should replace the initial interval")
+
+	b = &sse.ExponentialBackoff{InitialInterval: time.Second, Jitter: 0.5, MaxRetries: 1}
+	d := b.NextBackOff()
+	require.True(t, d >= time.Second/2 && d <= time.Second*3/2, "jittered interval out of range: %v", d)
+	require.Equal(t, sse.StopBackoff, b.NextBackOff(), "retries should be exhausted")
+
+	require.Equal(t, sse.StopBackoff, sse.NoRetry{}.NextBackOff(), "NoRetry should never retry")
+}
+
+func TestClient_Backoff(t *testing.T) {
+	attempts := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	for strategy, expected := range map[string]int{"none": 1, "exponential": 4} {
+		attempts = 0
+
+		c := &sse.Client{
+			HTTPClient: ts.Client(),
+			MaxRetries: -1,
+			Backoff: func() sse.BackoffStrategy {
+				if strategy == "none" {
+					return sse.NoRetry{}
+				}
+				return &sse.ExponentialBackoff{InitialInterval: time.Millisecond, MaxRetries: 3}
+			},
+		}
+
+		err := c.NewConnection(req(t, "", ts.URL, nil)).Connect()
+		require.Error(t, err, "Connect should fail")
+		require.Equal(t, expected, attempts, "invalid number of attempts for %s", strategy)
+	}
+}
+
+func TestClient_ServerRetry(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("retry: 1\n\n"))
+	}))
+	defer ts.Close()
+
+	for _, ignore := range []bool{false, true} {
+		requests.Store(0)
+		var delays []time.Duration
+		c := &sse.Client{
+			HTTPClient:              ts.Client(),
+			MaxRetries:              2,
+			DefaultReconnectionTime: 5 * time.Millisecond,
+			IgnoreServerRetry:       ignore,
+			OnRetry:                 func(_ error, d time.Duration) { delays = append(delays, d) },
+		}
+
+		require.Error(t, c.NewConnection(req(t, "", ts.URL, nil)).Connect(), "Connect should fail")
+
+		expected := time.Millisecond
+		if ignore {
+			expected = 5 * time.Millisecond
+		}
+		require.NotEmpty(t, delays, "no reconnection attempted")
+		for _, d := range delays {
+			require.Equal(t, expected, d, "invalid reconnection delay (ignore: %t)", ignore)
+		}
+	}
+}
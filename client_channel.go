@@ -0,0 +1,172 @@
+package sse
+
+import "context"
+
+// Backpressure determines what a channel returned by Connection.Messages or Connection.Events
+// does with new events when it is full, because its receiver can't keep up.
+type Backpressure int
+
+const (
+	// BackpressureBlock waits until the receiver receives the event. This slows down the whole
+	// connection: the other callbacks and channels don't receive events in the meantime either.
+	// This is the default.
+	BackpressureBlock Backpressure = iota
+	// BackpressureDropNewest drops the new event.
+	BackpressureDropNewest
+	// BackpressureDropOldest drops the oldest event in the channel's buffer to make room for the
+	// new one. If the channel is unbuffered, it drops the new event instead.
+	BackpressureDropOldest
+	// BackpressureUnsubscribe drops the new event and closes the channel, so receivers which
+	// can't keep up are removed instead of losing events without noticing.
+	BackpressureUnsubscribe
+)
+
+// ChannelOptions configures a channel returned by Connection.EventsWithOptions.
+type ChannelOptions struct {
+	// OnDrop is called with each event dropped because the channel is full. It is called in
+	// the same goroutine as the event callbacks, so the same restrictions apply to it.
+	OnDrop func(dropped Event)
+	// The channel's buffer size. Defaults to 0 (unbuffered).
+	Buffer int
+	// What the channel does with new events when it is full. Defaults to BackpressureBlock.
+	Backpressure Backpressure
+}
+
+// Messages returns a channel which receives the events without type, the same as the callbacks
+// subscribed using SubscribeMessages. See Events for more info.
+func (c *Connection) Messages(ctx context.Context) <-chan Event {
+	return c.Events(ctx, "")
+}
+
+// Events returns a channel which receives the events with the given type, the same as the callbacks
+// subscribed using SubscribeEvent, so they can be received in a select loop:
+//
+//	updates := conn.Events(ctx, "update")
+//	go conn.Connect()
+//
+//	for {
+//		select {
+//		case e, ok := <-updates:
+//			// ...
+//		case <-ticker.C:
+//			// ...
+//		}
+//	}
+//
+// The channel's buffer size and what happens when it is full are set by the Client's ChannelBuffer
+// and ChannelBackpressure options. The channel is closed when the given context is done or when
+// Connect returns, so make sure to call Connect after creating the channel.
+func (c *Connection) Events(ctx context.Context, typ string) <-chan Event {
+	return c.EventsWithOptions(ctx, typ, ChannelOptions{
+		Buffer:       c.client.ChannelBuffer,
+		Backpressure: c.client.ChannelBackpressure,
+	})
+}
+
+// EventsWithOptions returns a channel which receives the events with the given type, as Events does,
+// configured using the given options instead of the Client's, so each channel can have its own limit
+// and backpressure. It is also closed if it overflows and its backpressure is BackpressureUnsubscribe.
+func (c *Connection) EventsWithOptions(ctx context.Context, typ string, opts ChannelOptions) <-chan Event {
+	if opts.Buffer < 0 {
+		opts.Buffer = 0
+	}
+
+	ch := make(chan Event, opts.Buffer)
+	done := make(chan struct{})
+	closing := make(chan struct{}, 1)
+	signalClosing := func() {
+		select {
+		case closing <- struct{}{}:
+		default:
+		}
+	}
+
+	// The callbacks are called from the same goroutine, so this isn't shared.
+	overflowed := false
+
+	remove := c.SubscribeEvent(typ, func(e Event) {
+		if overflowed {
+			return
+		}
+		if !c.sendToChannel(ctx, done, ch, e, opts) {
+			overflowed = true
+			signalClosing()
+		}
+	})
+	removeLifecycle := c.addSubscriberToAll(callback{priority: PriorityLow, lifecycle: true, fn: func(e Event) {
+		if e.Lifecycle == LifecycleClosed {
+			signalClosing()
+		}
+	}})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-closing:
+		}
+
+		// Unblock the pending send, if any, so the callbacks can be removed.
+		close(done)
+		remove()
+		removeLifecycle()
+		close(ch)
+	}()
+
+	return ch
+}
+
+// sendToChannel sends the event to the channel according to the options' backpressure.
+// It returns false if the channel overflowed and must be closed.
+func (c *Connection) sendToChannel(ctx context.Context, done <-chan struct{}, ch chan Event, e Event, opts ChannelOptions) bool {
+	// The receivers use the event after the callback returns.
+	e = c.retain(e)
+
+	drop := func(e Event) {
+		if opts.OnDrop != nil {
+			opts.OnDrop(e)
+		}
+	}
+
+	switch opts.Backpressure {
+	case BackpressureDropNewest:
+		select {
+		case ch <- e:
+		default:
+			drop(e)
+		}
+	case BackpressureDropOldest:
+		for {
+			select {
+			case ch <- e:
+				return true
+			default:
+			}
+
+			if cap(ch) == 0 {
+				drop(e)
+				return true
+			}
+
+			select {
+			case old := <-ch:
+				drop(old)
+			default:
+			}
+		}
+	case BackpressureUnsubscribe:
+		select {
+		case ch <- e:
+		default:
+			drop(e)
+			return false
+		}
+	default:
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+		case <-done:
+		}
+	}
+
+	return true
+}
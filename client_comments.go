@@ -0,0 +1,39 @@
+package sse
+
+// CommentCallback is a function that is used to receive the comments sent by the server.
+// It is called with the comment's text, without the leading colon and space.
+type CommentCallback func(comment string)
+
+// SubscribeComments subscribes the given callback to the comment lines sent by the server,
+// which are not part of any event. Servers use them for keep-alives, as the Server does (see
+// Server.KeepAlive), or for out-of-band diagnostics. The callback is called in the same goroutine
+// and order as the event callbacks, as soon as the comment is received, so the same restrictions
+// apply to it. Remove the callback by calling the returned function.
+func (c *Connection) SubscribeComments(cb CommentCallback) EventCallbackRemover {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.callbackID
+	c.comments[id] = cb
+	c.callbackID++
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.comments, id)
+	}
+}
+
+func (c *Connection) dispatchComment(comment string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.client.ErrorReporter != nil {
+		defer reportPanic(c.request.Context(), c.client.ErrorReporter)
+	}
+
+	for _, cb := range c.comments {
+		cb(comment)
+	}
+}
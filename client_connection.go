@@ -1,6 +1,8 @@
 package sse
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -9,10 +11,12 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
 	"github.com/tmaxmax/go-sse/internal/parser"
+	"golang.org/x/exp/slog"
 )
 
 // The Event struct represents an event sent to the client by a server.
@@ -24,6 +28,20 @@ type Event struct {
 	Type string
 	// The event's payload.
 	Data string
+	// The logical stream the event is part of, if the server multiplexes
+	// multiple streams over one connection. See Connection.Stream.
+	Stream string
+	// The ID of the trace the event is part of, if the server sent one using the non-standard
+	// "trace" field. Use Context to propagate it to the operations done in callbacks.
+	TraceID string
+	// Set only for the synthetic events which notify about the connection's lifecycle.
+	// See SubscribeToAllWithLifecycle.
+	Lifecycle Lifecycle
+
+	// The Codec of the connection's client, used by DecodePayload.
+	codec Codec
+	// Set if the connection's client records event metadata. See Meta.
+	meta *EventMeta
 }
 
 // EventCallback is a function that is used to receive events from a Connection.
@@ -42,12 +60,44 @@ type EventCallbackRemover func()
 type Connection struct { //nolint:govet // The current order aids readability.
 	mu           sync.RWMutex
 	request      *http.Request
-	callbacks    map[string]map[int]EventCallback
-	callbacksAll map[int]EventCallback
+	attemptCtx   context.Context
+	callbacks    map[string]map[int]callback
+	callbacksAll map[int]callback
+	comments     map[int]CommentCallback
+	retries      map[int]RetryCallback
+	states       map[int]StateCallback
+	patterns     []string
+	dataStreams  map[string]map[int]DataStreamCallback
 	lastEventID  string
+	lastEventIDs map[string]string
+	idMu         sync.Mutex
+	savedID      string
+	saver        *lastEventIDSaver
+	low          *lowPriorityQueue
+	eventHasID   bool
 	client       Client
+	id           string
+	token        string
 	callbackID   int
+	attempt      int
 	isRetry      bool
+	received     bool
+	closedBy     *ClosedError
+
+	teeMu sync.Mutex
+	tee   io.Writer
+
+	statsMu  sync.Mutex
+	stats    connectionStats
+	profiler eventProfiler
+	echoing  atomic.Bool
+
+	lifecycleMu sync.Mutex
+	cancel      context.CancelFunc
+	done        <-chan struct{}
+	draining    bool
+	closing     bool
+	state       ConnectionState
 }
 
 // SubscribeMessages subscribes the given callback to all events without type (without or with empty `event` field).
@@ -58,22 +108,109 @@ func (c *Connection) SubscribeMessages(cb EventCallback) EventCallbackRemover {
 
 // SubscribeEvent subscribes the given callback to all the events with the provided type
 // (the `event` field has the value given here).
+// The type may be a pattern, in which each '*' matches any sequence of characters: for example,
+// "orders.*" subscribes to all the events whose type starts with "orders.".
 // Remove the callback by calling the returned function.
 func (c *Connection) SubscribeEvent(typ string, cb EventCallback) EventCallbackRemover {
-	return c.addSubscriber(typ, cb)
+	return c.SubscribeEventWithPriority(typ, PriorityNormal, cb)
+}
+
+// SubscribeEvents subscribes the given callback to all the events with any of the provided types,
+// as SubscribeEvent does for each of them. If an event's type matches more than one of the types,
+// which is possible only with patterns, the callback receives the event once for each of them.
+// Remove the callback from all the types by calling the returned function.
+func (c *Connection) SubscribeEvents(types []string, cb EventCallback) EventCallbackRemover {
+	removers := make([]EventCallbackRemover, 0, len(types))
+	for _, typ := range types {
+		removers = append(removers, c.SubscribeEvent(typ, cb))
+	}
+
+	return func() {
+		for _, remove := range removers {
+			remove()
+		}
+	}
 }
 
 // SubscribeToAll subscribes the given callback to all events, with or without type.
 // Remove the callback by calling the returned function.
 func (c *Connection) SubscribeToAll(cb EventCallback) EventCallbackRemover {
-	return c.addSubscriberToAll(cb)
+	return c.SubscribeToAllWithPriority(PriorityNormal, cb)
 }
 
-func (c *Connection) addSubscriberToAll(cb EventCallback) EventCallbackRemover {
+// SubscribeFiltered subscribes the given callback to the events, with or without type, for which
+// the given filter returns true – for example, the events whose ID has a certain prefix or whose data
+// contains a certain value. The filter is called in the same goroutine as the callbacks, before
+// the callback. Remove the callback by calling the returned function.
+func (c *Connection) SubscribeFiltered(filter func(Event) bool, cb EventCallback) EventCallbackRemover {
+	s := newCallback(cb, PriorityNormal)
+	s.filter = filter
+
+	return c.addSubscriberToAll(s)
+}
+
+// Priority determines how the callbacks subscribed to a Connection receive the events.
+// PriorityHigh callbacks receive each event before the PriorityNormal ones, and both are called
+// in the goroutine which receives the events. PriorityLow callbacks are called in a separate goroutine,
+// in the order the events are received, so slow PriorityLow callbacks don't delay the delivery of the next
+// events to the other callbacks – unless they fall behind by more than the Client's LowPriorityBuffer
+// events, in which case the connection waits for them to catch up. As PriorityLow callbacks run
+// concurrently with the others, the state they share with them must be synchronized.
+//
+// Callbacks with the same priority receive events in an unspecified order.
+// Values outside the range of the defined priorities are clamped to it.
+type Priority int
+
+// Subscription priorities. Use PriorityHigh for critical callbacks (e.g. revoking a session)
+// and PriorityLow for bulk processing (e.g. telemetry), so critical callbacks aren't delayed
+// by the bulk ones. Callbacks subscribed without a priority have PriorityNormal.
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+)
+
+var priorities = [...]Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+// SubscribeEventWithPriority is the same as SubscribeEvent, but the callback has the given priority.
+func (c *Connection) SubscribeEventWithPriority(typ string, p Priority, cb EventCallback) EventCallbackRemover {
+	return c.addSubscriber(typ, newCallback(cb, p))
+}
+
+// SubscribeToAllWithPriority is the same as SubscribeToAll, but the callback has the given priority.
+func (c *Connection) SubscribeToAllWithPriority(p Priority, cb EventCallback) EventCallbackRemover {
+	return c.addSubscriberToAll(newCallback(cb, p))
+}
+
+type callback struct {
+	fn        EventCallback
+	stats     *subscriptionStats
+	stream    *string
+	filter    func(Event) bool
+	priority  Priority
+	lifecycle bool
+}
+
+func (c callback) receives(ev Event, p Priority) bool {
+	return c.priority == p && (c.stream == nil || *c.stream == ev.Stream) && (c.filter == nil || c.filter(ev))
+}
+
+func newCallback(fn EventCallback, p Priority) callback {
+	if p > PriorityHigh {
+		p = PriorityHigh
+	} else if p < PriorityLow {
+		p = PriorityLow
+	}
+
+	return callback{fn: fn, priority: p}
+}
+
+func (c *Connection) addSubscriberToAll(cb callback) EventCallbackRemover {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	id := c.callbackID
+	cb.stats = c.subscribed(id, "", true)
 	c.callbacksAll[id] = cb
 	c.callbackID++
 
@@ -81,19 +218,26 @@ func (c *Connection) addSubscriberToAll(cb EventCallback) EventCallbackRemover {
 		c.mu.Lock()
 		defer c.mu.Unlock()
 
-		delete(c.callbacksAll, id)
+		if _, ok := c.callbacksAll[id]; ok {
+			delete(c.callbacksAll, id)
+			c.unsubscribed()
+		}
 	}
 }
 
-func (c *Connection) addSubscriber(event string, cb EventCallback) EventCallbackRemover {
+func (c *Connection) addSubscriber(event string, cb callback) EventCallbackRemover {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if _, ok := c.callbacks[event]; !ok {
-		c.callbacks[event] = map[int]EventCallback{}
+		c.callbacks[event] = map[int]callback{}
+		if isPattern(event) {
+			c.patterns = append(c.patterns, event)
+		}
 	}
 
 	id := c.callbackID
+	cb.stats = c.subscribed(id, event, false)
 	c.callbacks[event][id] = cb
 	c.callbackID++
 
@@ -101,16 +245,62 @@ func (c *Connection) addSubscriber(event string, cb EventCallback) EventCallback
 		c.mu.Lock()
 		defer c.mu.Unlock()
 
+		if _, ok := c.callbacks[event][id]; ok {
+			c.unsubscribed()
+		}
 		delete(c.callbacks[event], id)
 		if len(c.callbacks[event]) == 0 {
 			delete(c.callbacks, event)
+			c.patterns = removePattern(c.patterns, event)
 		}
 	}
 }
 
+// Tee copies the raw bytes received from the server to the given writer, while the events
+// continue to be dispatched as usual. This is useful for capturing samples of the traffic
+// or debugging encoding issues. Call Tee with a nil writer to stop copying.
+//
+// Write errors are ignored, so a failing writer doesn't affect the connection.
+// The writer is only written to from the goroutine Connect was called in.
+func (c *Connection) Tee(w io.Writer) {
+	c.teeMu.Lock()
+	defer c.teeMu.Unlock()
+
+	c.tee = w
+}
+
+type teeReader struct {
+	r io.Reader
+	c *Connection
+}
+
+func (t teeReader) Read(p []byte) (int, error) {
+	var start time.Time
+	if t.c.client.ProfileEvents {
+		start = time.Now()
+	}
+
+	n, err := t.r.Read(p)
+
+	if t.c.client.ProfileEvents {
+		t.c.profiler.network += time.Since(start)
+	}
+
+	if n > 0 {
+		t.c.teeMu.Lock()
+		if t.c.tee != nil {
+			_, _ = t.c.tee.Write(p[:n])
+		}
+		t.c.teeMu.Unlock()
+	}
+
+	return n, err
+}
+
 // ConnectionError is the type that wraps all the connection errors that occur.
 type ConnectionError struct {
-	// The request for which the connection failed.
+	// The request for which the connection failed. It is a copy of the connection's
+	// request, from which secrets were removed using the client's Redactor.
 	Req *http.Request
 	// The reason the operation failed.
 	Err error
@@ -126,6 +316,28 @@ func (e *ConnectionError) Unwrap() error {
 	return e.Err
 }
 
+// StartFromEventID makes the connection resume the stream from the event with the given ID,
+// by sending it to the server in the Last-Event-ID header. Use this to catch up from a known
+// checkpoint, for example the ID of the last event a previous run of your program processed.
+// The ID is also used on reconnections, until an event with another ID is received, and
+// it is the LastEventID of the events received before any event with an ID.
+//
+// Unlike setting the Last-Event-ID header on the request directly, which is only sent
+// on the first connection attempt, the ID set here is sent on each attempt.
+// StartFromEventID must be called before Connect.
+func (c *Connection) StartFromEventID(id string) {
+	c.setLastEventID(id)
+	if id == "" {
+		c.request.Header.Del("Last-Event-ID")
+	} else {
+		c.request.Header.Set("Last-Event-ID", id)
+	}
+}
+
+func (c *Connection) newError(reason string, err error) *ConnectionError {
+	return &ConnectionError{Req: c.client.Redactor(c.request), Reason: reason, Err: err}
+}
+
 func (c *Connection) resetRequest() error {
 	if !c.isRetry {
 		c.isRetry = true
@@ -143,73 +355,224 @@ func (c *Connection) resetRequest() error {
 }
 
 func (c *Connection) dispatch(ev Event) {
+	c.recordEventID(ev.Type)
+
+	if c.refreshToken(ev) {
+		return
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	cbs := c.callbacks[ev.Type]
-	cbCount := len(cbs) + len(c.callbacksAll)
-	if cbCount == 0 {
-		return
+	c.received = true
+
+	if ev.Type == HeartbeatEventType {
+		c.observeHeartbeat(ev.Data, time.Now())
+	}
+	if ev.Type == CloseEventType {
+		c.observeClose(ev.Data)
+	}
+
+	if c.client.ErrorReporter != nil {
+		defer reportPanic(c.request.Context(), c.client.ErrorReporter)
 	}
 
 	if l := len(ev.Data); l > 0 {
 		ev.Data = ev.Data[:l-1]
 	}
 	ev.LastEventID = c.lastEventID
+	ev.codec = c.client.Codec
+
+	ev, ok := c.hookEvent(ev)
+	if !ok {
+		return
+	}
+
+	cbs := c.callbacks[ev.Type]
+	var matched []map[int]callback
+	for _, p := range c.patterns {
+		if p != ev.Type && matchPattern(p, ev.Type) {
+			matched = append(matched, c.callbacks[p])
+		}
+	}
+	streams := c.dataStreams[ev.Type]
+	cbCount := len(cbs) + len(c.callbacksAll) + len(streams) + len(matched)
+	if cbCount == 0 {
+		return
+	}
+
+	var low []callback
+	call := func(cb callback) {
+		if cb.priority == PriorityLow {
+			low = append(low, cb)
+		} else {
+			c.call(cb, ev)
+		}
+	}
+
+	for _, p := range priorities {
+		for _, cb := range cbs {
+			if cb.receives(ev, p) {
+				call(cb)
+			}
+		}
+		for _, m := range matched {
+			for _, cb := range m {
+				if cb.receives(ev, p) {
+					call(cb)
+				}
+			}
+		}
+		for _, cb := range c.callbacksAll {
+			if cb.receives(ev, p) {
+				call(cb)
+			}
+		}
+	}
 
-	for _, cb := range c.callbacks[ev.Type] {
-		cb(ev)
+	if len(low) > 0 {
+		c.dispatchLowPriority(low, ev)
 	}
-	for _, cb := range c.callbacksAll {
-		cb(ev)
+
+	for _, cb := range streams {
+		cb(ev, strings.NewReader(ev.Data))
 	}
 }
 
 func (c *Connection) read(r io.Reader, setRetry func(time.Duration)) error {
-	p := parser.New(r)
+	p := parser.New(teeReader{r: r, c: c})
+	p.KeepComments(true)
+	if limit := c.client.MaxEventSize; limit > 0 {
+		size := 4096
+		if limit < size {
+			size = limit
+		}
+		p.Buffer(make([]byte, 0, size), limit)
+	}
+	p.UTF8(c.client.UTF8Policy.mode())
+	pooled := c.client.PooledEventData
+	if pooled {
+		p.ReuseBuffer(true)
+	}
 	ev, dirty := Event{}, false
+	var data *[]byte // The event's data, if it's pooled.
+
+	dispatch := c.dispatch
+	if c.client.ProfileEvents {
+		dispatch = c.dispatchProfiled
+		c.profiler.reset()
+	}
 
 	for f := (parser.Field{}); p.Next(&f); {
-		switch f.Name { //nolint:exhaustive // Comment fields are not parsed.
-		case parser.FieldNameData:
-			ev.Data += f.Value + "\n"
-			dirty = true
-		case parser.FieldNameEvent:
-			ev.Type = f.Value
-			dirty = true
-		case parser.FieldNameID:
-			// empty IDs are valid, only IDs that contain the null byte must be ignored:
-			// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
-			if strings.IndexByte(f.Value, 0) != -1 {
-				break
+		if c.client.RecordEventMeta && ev.meta == nil {
+			ev.meta = &EventMeta{ReceivedAt: time.Now()}
+		}
+
+		if f.Name != "" && pooled {
+			if f.Name == parser.FieldNameData {
+				if data == nil {
+					data = getEventData()
+				}
+				*data = append(append(*data, f.Value...), '\n')
+				dirty = true
+				continue
 			}
+			// The other values are retained, so they can't share memory with the parser's buffer.
+			f.Value = cloneString(f.Value)
+		}
 
-			c.lastEventID = f.Value
-			dirty = true
-		case parser.FieldNameRetry:
-			n, err := strconv.ParseInt(f.Value, 10, 64)
-			if err != nil {
-				break
+		if f.Name != "" {
+			if c.processField(&ev, f, setRetry) {
+				dirty = true
 			}
-			if n > 0 {
-				setRetry(time.Duration(n) * time.Millisecond)
+			continue
+		}
+
+		if dirty && c.isClosing() {
+			return ErrConnectionClosed
+		}
+		// Blocks with only comments don't make events.
+		if dirty {
+			if ev.meta != nil {
+				ev.meta.Raw = p.Raw()
+				if pooled {
+					ev.meta.Raw = cloneString(ev.meta.Raw)
+				}
 			}
-			dirty = true
-		default:
-			c.dispatch(ev)
-			ev = Event{}
-			dirty = false
+			dispatchPooled(dispatch, ev, data)
+			c.saveLastEventID()
 		}
+		ev, data = Event{}, nil
+		dirty = false
 	}
 
 	err := p.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		return ErrEventTooLarge
+	}
+	if errors.Is(err, parser.ErrInvalidUTF8) {
+		return ErrInvalidUTF8
+	}
 	if dirty && err == io.EOF { //nolint:errorlint // Our scanner returns io.EOF unwrapped
-		c.dispatch(ev)
+		if c.isClosing() {
+			return ErrConnectionClosed
+		}
+		dispatchPooled(dispatch, ev, data)
+		c.saveLastEventID()
 	}
 
 	return err
 }
 
+// checkUTF8 applies the client's UTF8Policy to the field's value.
+func (c *Connection) checkUTF8(f *parser.Field) error {
+	if parser.CheckUTF8(f, c.client.UTF8Policy.mode()) != nil {
+		return ErrInvalidUTF8
+	}
+
+	return nil
+}
+
+// processField applies a field, other than the blank line which ends the event, to the event
+// being received. It returns true if the field is part of the event – comments and invalid
+// fields are not.
+func (c *Connection) processField(ev *Event, f parser.Field, setRetry func(time.Duration)) bool {
+	switch f.Name { //nolint:exhaustive // Blank lines are handled by the caller.
+	case parser.FieldNameComment:
+		c.dispatchComment(f.Value)
+		return false
+	case parser.FieldNameData:
+		ev.Data += f.Value + "\n"
+	case parser.FieldNameEvent:
+		ev.Type = f.Value
+	case parser.FieldNameStream:
+		ev.Stream = f.Value
+	case parser.FieldNameTrace:
+		ev.TraceID = f.Value
+	case parser.FieldNameID:
+		// empty IDs are valid, only IDs that contain the null byte must be ignored:
+		// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+		if strings.IndexByte(f.Value, 0) != -1 {
+			return false
+		}
+
+		c.setLastEventID(f.Value)
+		c.eventHasID = true
+	case parser.FieldNameRetry:
+		n, err := strconv.ParseInt(f.Value, 10, 64)
+		if err != nil {
+			return false
+		}
+		if n > 0 {
+			delay := time.Duration(n) * time.Millisecond
+			setRetry(delay)
+			c.dispatchRetry(delay)
+		}
+	}
+
+	return true
+}
+
 // Connect sends the request the connection was created with to the server
 // and, if successful, it starts receiving events. The caller goroutine
 // is blocked until the request's context is done or an error occurs.
@@ -217,57 +580,384 @@ func (c *Connection) read(r io.Reader, setRetry func(time.Duration)) error {
 // If the request's context is cancelled, Connect returns its error.
 // Otherwise, if the maximum number or retries is made, the last error
 // that occurred is returned. Connect never returns otherwise – either
-// the context is cancelled, or it's done retrying.
+// the context is cancelled, or it's done retrying. If the connection
+// is stopped using Drain, Connect returns ErrDrained; and if it is stopped
+// using Close, ErrConnectionClosed.
+// If the server responds with 204 No Content, which tells clients to stop
+// reconnecting, Connect returns ErrStreamEnded, or nil if the Client's
+// StreamEndIsSuccess option is set.
 //
 // All errors returned other than the context errors will be wrapped
-// inside a *ConnectionError.
+// inside a *ConnectionError. If the server told why it closed the connection,
+// using a message with the type CloseEventType, the error wrapped is a *ClosedError.
 func (c *Connection) Connect() error {
-	ctx := c.request.Context()
+	return c.ConnectCtx(context.Background())
+}
+
+// ConnectCtx is the same as Connect, but the connection is also closed when the given context
+// is done, without having to create the connection with a new request. In that case, ConnectCtx
+// returns ErrConnectionClosed, so closing the connection on purpose can be told apart from
+// transport errors or the cancellation of the request's context.
+func (c *Connection) ConnectCtx(ctx context.Context) error {
+	caller := ctx
+	parent := c.request.Context()
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	if done := caller.Done(); done != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+
+		go func() {
+			select {
+			case <-done:
+				cancel()
+			case <-stop:
+			}
+		}()
+	}
+
+	defer close(c.start(cancel))
+
+	c.setState(StateConnecting)
+	defer c.setState(StateClosed)
+
+	c.request = c.request.WithContext(ctx)
+	defer func() { c.request = c.request.WithContext(parent) }()
+
+	if err := c.loadLastEventID(ctx); err != nil {
+		return err
+	}
+	stopSaving := c.startSavingLastEventIDs(parent)
+	defer stopSaving()
+	stopLowPriority := c.startLowPriority(parent)
+	defer stopLowPriority()
+
 	b, setRetry := c.client.newBackoff(ctx)
+	delayed := &retryAfterBackOff{BackOff: b}
+	c.attempt = 0
 
 	c.request.Header.Set("Accept", "text/event-stream")
 	c.request.Header.Set("Connection", "keep-alive")
 	c.request.Header.Set("Cache", "no-cache")
+	if caps := c.client.capabilities(); len(caps) > 0 {
+		c.request.Header.Set(HeaderCapabilities, caps.String())
+	}
+	if c.client.AcceptEncoding != "" {
+		c.request.Header.Set("Accept-Encoding", c.client.AcceptEncoding)
+	}
+
+	var downSince time.Time
 
 	op := func() error {
+		defer func() {
+			// The operation never returns successfully, so the connection is down
+			// from now on, if it wasn't already.
+			if downSince.IsZero() {
+				downSince = time.Now()
+			}
+		}()
+
 		if err := c.resetRequest(); err != nil {
-			wrapped := &ConnectionError{Req: c.request, Reason: "request reset failed", Err: err}
+			wrapped := c.newError("request reset failed", err)
 			return backoff.Permanent(wrapped)
 		}
 
-		res, err := c.client.HTTPClient.Do(c.request)
+		if err := c.setToken(); err != nil {
+			if errors.Is(err, ctx.Err()) {
+				return backoff.Permanent(err)
+			}
+			return c.newError("token retrieval failed", err)
+		}
+
+		c.setState(StateConnecting)
+
+		attemptCtx, cancelAttempt := context.WithCancel(ctx)
+		defer cancelAttempt()
+
+		c.attempt++
+		c.attemptCtx = attemptCtx
+		defer func() { c.attemptCtx = nil }()
+
+		r := c.request.WithContext(attemptCtx)
+		c.log(ctx, slog.LevelDebug, "sse: connecting", "attempt", c.attempt, "url", c.client.Redactor(r).URL.String())
+		if c.client.OnRequest != nil {
+			if err := c.client.OnRequest(c.attempt, r); err != nil {
+				if errors.Is(err, ctx.Err()) {
+					return backoff.Permanent(err)
+				}
+				return c.newError("request preparation failed", err)
+			}
+		}
+
+		res, err := c.client.HTTPClient.Do(r)
 		if err != nil {
 			concrete := err.(*url.Error) //nolint:errorlint // We know the concrete type here
 			if errors.Is(err, ctx.Err()) {
 				return backoff.Permanent(concrete.Err)
 			}
-			return &ConnectionError{Req: c.request, Reason: "connection to server failed", Err: concrete.Err}
+			return c.client.retryable(c.newError("connection to server failed", concrete.Err), nil)
 		}
 		defer res.Body.Close()
 
+		if res.StatusCode == http.StatusNoContent {
+			return backoff.Permanent(ErrStreamEnded)
+		}
+
 		if err := c.client.ResponseValidator(res); err != nil {
-			return &ConnectionError{Req: c.request, Reason: "response validation failed", Err: err}
+			c.log(ctx, slog.LevelWarn, "sse: response validation failed", "status", res.StatusCode, "error", err)
+			c.rejectToken(res)
+			if !c.client.IgnoreRetryAfter {
+				delayed.after = retryAfter(res)
+			}
+			return c.client.retryable(c.newError("response validation failed", err), res)
 		}
 
 		b.Reset()
 
-		err = c.read(res.Body, setRetry)
+		if !downSince.IsZero() {
+			c.observeDowntime(time.Since(downSince))
+			downSince = time.Time{}
+		}
+
+		c.log(ctx, slog.LevelInfo, "sse: connected", "attempt", c.attempt)
+		c.setState(StateOpen)
+		c.dispatchLifecycle(LifecycleConnected, nil)
+		if c.received && c.lastEventID == "" {
+			c.dispatchLifecycle(LifecycleGapDetected, nil)
+		}
+
+		var recycled atomic.Bool
+		if age := c.client.connectionAge(); age > 0 {
+			t := time.AfterFunc(age, func() {
+				recycled.Store(true)
+				cancelAttempt()
+			})
+			defer t.Stop()
+		}
+
+		body, timedOut, stopTimeout := c.client.withReadTimeout(res.Body, cancelAttempt)
+		defer stopTimeout()
+
+		body, err = c.client.decodeBody(res, body)
+		if errors.Is(err, ErrUnsupportedEncoding) {
+			return backoff.Permanent(c.newError("response decoding failed", err))
+		}
+		if err != nil {
+			if errors.Is(err, ctx.Err()) {
+				return backoff.Permanent(err)
+			}
+			return c.newError("response decoding failed", err)
+		}
+
+		read := c.read
+		if c.client.StreamEventData {
+			read = c.readStreaming
+		}
+
+		err = read(body, setRetry)
 		if errors.Is(err, ctx.Err()) {
 			return backoff.Permanent(err)
 		}
+		if recycled.Load() {
+			return errRecycled
+		}
+		if timedOut() {
+			return c.newError("connection to server lost", ErrReadTimeout)
+		}
+		if errors.Is(err, ErrConnectionClosed) {
+			return backoff.Permanent(err)
+		}
+		if errors.Is(err, ErrInvalidUTF8) || errors.Is(err, ErrEventTooLarge) {
+			return backoff.Permanent(c.newError("invalid event received", err))
+		}
 
-		return &ConnectionError{Req: c.request, Reason: "connection to server lost", Err: err}
+		if closed := c.closedBy; closed != nil {
+			c.closedBy = nil
+			return c.newError("connection closed by server", closed)
+		}
+
+		return c.newError("connection to server lost", err)
 	}
 
-	err := backoff.RetryNotify(op, b, c.client.OnRetry)
+	notify := func(err error, d time.Duration) {
+		if c.client.ReresolveOnRetry {
+			c.client.HTTPClient.CloseIdleConnections()
+		}
+
+		if c.client.ErrorReporter != nil {
+			c.client.ErrorReporter.ReportError(ctx, err)
+		}
+
+		c.log(ctx, slog.LevelWarn, "sse: reconnecting", "error", err, "delay", d)
+		c.setState(StateRetrying)
+		c.dispatchLifecycle(LifecycleReconnecting, err)
+		if c.client.OnRetry != nil {
+			c.client.OnRetry(err, d)
+		}
+	}
+
+	// Recycled connections reconnect immediately, without waiting for the backoff.
+	recycle := func() error {
+		for {
+			if err := op(); !errors.Is(err, errRecycled) {
+				return err
+			}
+		}
+	}
+
+	// The wrapper is put in a context backoff again, so the waits are still cancelled with the context.
+	err := backoff.RetryNotify(recycle, backoff.WithContext(delayed, ctx), notify)
+	if stopErr := c.stopError(); stopErr != nil {
+		err = stopErr
+	} else if caller.Err() != nil && parent.Err() == nil && errors.Is(err, context.Canceled) {
+		err = ErrConnectionClosed
+	}
+	if errors.Is(err, ErrStreamEnded) && c.client.StreamEndIsSuccess {
+		err = nil
+	}
+
+	if err != nil {
+		c.log(parent, slog.LevelInfo, "sse: connection closed", "error", err)
+	} else {
+		c.log(parent, slog.LevelInfo, "sse: connection closed")
+	}
+
+	// The PriorityLow callbacks receive all the events before the connection is reported closed.
+	stopLowPriority()
+	c.dispatchLifecycle(LifecycleClosed, err)
 
 	return err
 }
 
+// start marks the connection as connected and returns a channel
+// which must be closed when the connection is done.
+func (c *Connection) start(cancel context.CancelFunc) chan<- struct{} {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	done := make(chan struct{})
+	c.cancel = cancel
+	c.done = done
+	c.draining = false
+	c.closing = false
+
+	return done
+}
+
+// stop cancels the connection, if it is connected.
+func (c *Connection) stop() {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+func (c *Connection) isClosing() bool {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	return c.closing
+}
+
+// stopError returns the error Connect returns if the connection was stopped using Drain or Close.
+func (c *Connection) stopError() error {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	switch {
+	case c.draining:
+		return ErrDrained
+	case c.closing:
+		return ErrConnectionClosed
+	default:
+		return nil
+	}
+}
+
+// Drain stops the connection gracefully: no more data is read from the server and no reconnection
+// is attempted, but the events that were already received are still dispatched. Drain waits for
+// the callbacks to return and for Connect to exit, which then returns ErrDrained.
+//
+// Use this on shutdown, if your callbacks flush events to a database, for example – this way the tail
+// of the stream isn't lost. If the given context is done before Connect returns, Drain returns
+// the context's error. Drain does nothing if the connection isn't connected.
+//
+// Drain must not be called from inside callbacks, as it would wait for itself to return.
+func (c *Connection) Drain(ctx context.Context) error {
+	c.lifecycleMu.Lock()
+	cancel, done := c.cancel, c.done
+	if cancel != nil {
+		c.draining = true
+	}
+	c.lifecycleMu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the connection: the response body is closed, no reconnection is attempted and
+// the events which were not dispatched yet are discarded. Close waits for the callbacks which are
+// running to return and for Connect to exit, which then returns ErrConnectionClosed. Close does nothing
+// if the connection isn't connected and it always returns nil.
+//
+// Close must not be called from inside callbacks, as it would wait for itself to return.
+func (c *Connection) Close() error {
+	c.lifecycleMu.Lock()
+	cancel, done := c.cancel, c.done
+	if cancel != nil {
+		c.closing = true
+	}
+	c.lifecycleMu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+
+	cancel()
+	<-done
+
+	return nil
+}
+
+// ErrDrained is returned by Connect when the connection was stopped using Drain.
+var ErrDrained = errors.New("go-sse.client: connection drained")
+
+// ErrConnectionClosed is returned by Connect when the connection was stopped using Close,
+// and by ConnectCtx when the connection was closed using the given context.
+var ErrConnectionClosed = errors.New("go-sse.client: connection closed by caller")
+
+// ErrStreamEnded is returned by Connect when the server responded with 204 No Content,
+// which is how servers tell clients to stop reconnecting – for example, when retiring an endpoint.
+var ErrStreamEnded = errors.New("go-sse.client: stream ended by server")
+
+// errRecycled is returned by a connection attempt which ended because of the Client's MaxConnectionAge.
+var errRecycled = errors.New("go-sse.client: connection recycled")
+
 // ErrNoGetBody is a sentinel error returned when the connection cannot be reattempted
 // due to GetBody not existing on the original request.
 var ErrNoGetBody = errors.New("the GetBody function doesn't exist on the request")
 
+// ErrInvalidUTF8 is returned by Connect when the server sends data that is not valid UTF-8
+// and the client's UTF8Policy is UTF8Reject.
+var ErrInvalidUTF8 = errors.New("go-sse.client: received invalid UTF-8")
+
+// ErrEventTooLarge is returned by Connect when the server sends an event larger than
+// the client's MaxEventSize. The connection is not reattempted.
+var ErrEventTooLarge = errors.New("go-sse.client: received event is too large")
+
 func resetRequestBody(r *http.Request) error {
 	if r.Body == nil || r.Body == http.NoBody {
 		return nil
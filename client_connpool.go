@@ -0,0 +1,226 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrPoolConnectionExists is returned by ConnectionPool.Add if the pool already has a connection with the given name.
+var ErrPoolConnectionExists = errors.New("go-sse.client: pool already has a connection with this name")
+
+// ErrPoolConnected is returned by ConnectionPool.Connect if the pool is already connected.
+var ErrPoolConnected = errors.New("go-sse.client: pool already connected")
+
+// PoolCallback is a function that is used to receive the events of the connections of a ConnectionPool.
+// It is called with the name of the connection which received the event.
+type PoolCallback func(name string, e Event)
+
+// PoolHealth describes the connections of a ConnectionPool. Retrieve it using ConnectionPool.Health.
+type PoolHealth struct {
+	// The state of each connection, by name.
+	States map[string]ConnectionState
+	// The error each connection which stopped retrying ended with, by name.
+	Errors map[string]error
+	// The number of open connections.
+	Open int
+}
+
+// ConnectionPool manages connections to multiple streams – for example, to different feeds or to the
+// shards of a service – and merges their events, so applications which consume many streams don't have
+// to manage each connection. Add connections to the pool using Add, subscribe to the events of all of
+// them using Subscribe or SubscribeEvent and connect them all using Connect.
+//
+// Each connection retries according to the Client's configuration, independently of the others.
+// The connections which stop retrying are not reconnected, but they don't stop the other connections:
+// use Health to find out which connections are open and why the others stopped.
+//
+// The callbacks are called for one event at a time, so they don't need to be synchronized, but slow
+// callbacks delay the events of all the connections. Callbacks must not subscribe other callbacks.
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type ConnectionPool struct {
+	// The client used to create the connections. Defaults to DefaultClient.
+	Client *Client
+
+	conns      map[string]*poolConnection
+	callbacks  map[int]poolCallback
+	callbackID int
+	ctx        context.Context
+	wg         sync.WaitGroup
+	mu         sync.Mutex
+	dispatchMu sync.Mutex
+}
+
+type poolConnection struct {
+	conn   *Connection
+	cancel context.CancelFunc
+	err    error
+}
+
+type poolCallback struct {
+	fn  PoolCallback
+	typ *string
+}
+
+// Add adds a connection with the given name, created for the given request, to the pool. If the pool
+// is connected, the connection is connected immediately. It returns ErrPoolConnectionExists if the pool
+// already has a connection with the given name.
+func (p *ConnectionPool) Add(name string, r *http.Request) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.conns[name]; ok {
+		return ErrPoolConnectionExists
+	}
+	if p.conns == nil {
+		p.conns = map[string]*poolConnection{}
+	}
+
+	client := DefaultClient
+	if p.Client != nil {
+		client = p.Client
+	}
+
+	pc := &poolConnection{conn: client.NewConnection(r)}
+	pc.conn.SubscribeToAll(func(e Event) { p.dispatch(name, e) })
+	p.conns[name] = pc
+
+	if p.ctx != nil {
+		p.start(pc)
+	}
+
+	return nil
+}
+
+// Remove removes the connection with the given name from the pool, stopping it if it is connected.
+// It returns false if the pool has no connection with the given name.
+func (p *ConnectionPool) Remove(name string) bool {
+	p.mu.Lock()
+	pc, ok := p.conns[name]
+	delete(p.conns, name)
+	p.mu.Unlock()
+
+	if ok && pc.cancel != nil {
+		pc.cancel()
+	}
+
+	return ok
+}
+
+// Subscribe subscribes the given callback to all the events of all the connections.
+// Remove the callback by calling the returned function.
+func (p *ConnectionPool) Subscribe(cb PoolCallback) EventCallbackRemover {
+	return p.addCallback(poolCallback{fn: cb})
+}
+
+// SubscribeEvent subscribes the given callback to the events with the given type of all the connections.
+// The type may be a pattern, as for Connection.SubscribeEvent. Remove the callback by calling the returned function.
+func (p *ConnectionPool) SubscribeEvent(typ string, cb PoolCallback) EventCallbackRemover {
+	return p.addCallback(poolCallback{fn: cb, typ: &typ})
+}
+
+func (p *ConnectionPool) addCallback(cb poolCallback) EventCallbackRemover {
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+
+	if p.callbacks == nil {
+		p.callbacks = map[int]poolCallback{}
+	}
+
+	id := p.callbackID
+	p.callbacks[id] = cb
+	p.callbackID++
+
+	return func() {
+		p.dispatchMu.Lock()
+		defer p.dispatchMu.Unlock()
+
+		delete(p.callbacks, id)
+	}
+}
+
+func (p *ConnectionPool) dispatch(name string, e Event) {
+	p.dispatchMu.Lock()
+	defer p.dispatchMu.Unlock()
+
+	for _, cb := range p.callbacks {
+		if cb.typ == nil || matchPattern(*cb.typ, e.Type) {
+			cb.fn(name, e)
+		}
+	}
+}
+
+// Connect connects all the connections of the pool, and the ones added while it is connected,
+// and blocks until the given context is done. It returns the context's error after all the
+// connections are stopped, or ErrPoolConnected if the pool is already connected.
+func (p *ConnectionPool) Connect(ctx context.Context) error {
+	p.mu.Lock()
+	if p.ctx != nil {
+		p.mu.Unlock()
+		return ErrPoolConnected
+	}
+
+	p.ctx = ctx
+	for _, pc := range p.conns {
+		p.start(pc)
+	}
+	p.mu.Unlock()
+
+	<-ctx.Done()
+
+	p.mu.Lock()
+	p.ctx = nil
+	p.mu.Unlock()
+
+	p.wg.Wait()
+
+	return ctx.Err()
+}
+
+// start connects the connection in a new goroutine. The pool must be locked.
+func (p *ConnectionPool) start(pc *poolConnection) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	pc.cancel, pc.err = cancel, nil
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer cancel()
+
+		err := pc.conn.ConnectCtx(ctx)
+		if ctx.Err() != nil {
+			// The connection was stopped, it didn't fail.
+			err = nil
+		}
+
+		p.mu.Lock()
+		pc.err = err
+		p.mu.Unlock()
+	}()
+}
+
+// Health returns the state of the pool's connections.
+func (p *ConnectionPool) Health() PoolHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	h := PoolHealth{
+		States: make(map[string]ConnectionState, len(p.conns)),
+		Errors: map[string]error{},
+	}
+
+	for name, pc := range p.conns {
+		state := pc.conn.State()
+		h.States[name] = state
+		if state == StateOpen {
+			h.Open++
+		}
+		if pc.err != nil {
+			h.Errors[name] = pc.err
+		}
+	}
+
+	return h
+}
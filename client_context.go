@@ -0,0 +1,93 @@
+package sse
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+)
+
+// EventCallbackContext is a function that is used to receive events from a Connection, together
+// with a context for the operations done for the event. The context is derived from the context
+// of the current connection attempt, so it is cancelled when the connection is lost or closed.
+// It carries the connection's ID, the attempt's number and the event – see ConnectionIDFromContext,
+// AttemptFromContext and EventFromContext – and the event's trace ID, if it has one.
+type EventCallbackContext func(context.Context, Event)
+
+// SubscribeMessagesContext is the same as SubscribeMessages, but the callback also receives a context.
+func (c *Connection) SubscribeMessagesContext(cb EventCallbackContext) EventCallbackRemover {
+	return c.SubscribeEventContext("", cb)
+}
+
+// SubscribeEventContext is the same as SubscribeEvent, but the callback also receives a context.
+func (c *Connection) SubscribeEventContext(typ string, cb EventCallbackContext) EventCallbackRemover {
+	return c.SubscribeEvent(typ, c.withContext(cb))
+}
+
+// SubscribeToAllContext is the same as SubscribeToAll, but the callback also receives a context.
+func (c *Connection) SubscribeToAllContext(cb EventCallbackContext) EventCallbackRemover {
+	return c.SubscribeToAll(c.withContext(cb))
+}
+
+// ID returns the connection's ID, which is unique in the process. It is carried by the contexts
+// received by the callbacks subscribed with a context, so it can be used to correlate logs, for example.
+func (c *Connection) ID() string {
+	return c.id
+}
+
+type callbackContextKey struct{}
+
+type callbackContext struct {
+	connectionID string
+	event        Event
+	attempt      int
+}
+
+// ConnectionIDFromContext returns the ID of the connection which received the event, if the context
+// was received by an EventCallbackContext. See Connection.ID.
+func ConnectionIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(callbackContextKey{}).(callbackContext)
+	return v.connectionID
+}
+
+// AttemptFromContext returns the number of the connection attempt on which the event was received,
+// starting from 1 for each Connect call, if the context was received by an EventCallbackContext.
+// Otherwise, it returns 0.
+func AttemptFromContext(ctx context.Context) int {
+	v, _ := ctx.Value(callbackContextKey{}).(callbackContext)
+	return v.attempt
+}
+
+// EventFromContext returns the event for which the context was received by an EventCallbackContext.
+func EventFromContext(ctx context.Context) (Event, bool) {
+	v, ok := ctx.Value(callbackContextKey{}).(callbackContext)
+	return v.event, ok
+}
+
+// connectionIDs generates the IDs of the connections.
+var connectionIDs atomic.Uint64
+
+func newConnectionID() string {
+	return strconv.FormatUint(connectionIDs.Add(1), 10)
+}
+
+func (c *Connection) withContext(cb EventCallbackContext) EventCallback {
+	return func(ev Event) {
+		cb(c.eventContext(ev), ev)
+	}
+}
+
+// eventContext returns the context of the given event. It is called from the goroutine Connect was called in.
+func (c *Connection) eventContext(ev Event) context.Context {
+	parent := c.attemptCtx
+	if parent == nil {
+		parent = c.request.Context()
+	}
+
+	ctx := context.WithValue(parent, callbackContextKey{}, callbackContext{
+		connectionID: c.id,
+		event:        ev,
+		attempt:      c.attempt,
+	})
+
+	return ev.Context(ctx)
+}
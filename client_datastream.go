@@ -0,0 +1,242 @@
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tmaxmax/go-sse/internal/parser"
+)
+
+// DataStreamCallback is a function that is used to receive the data of events as a stream.
+// The reader returns the event's data, with its lines joined by newlines as in Event.Data, and
+// io.EOF after the event ends. If the connection is lost before the event ends, it returns
+// io.ErrUnexpectedEOF instead. The reader must not be used after the callback returns.
+type DataStreamCallback func(e Event, data io.Reader)
+
+// SubscribeDataStream subscribes the given callback to the events of the given type, which it
+// receives with their data as a reader instead of a string.
+//
+// If the client's StreamEventData option is set, the callback is called as soon as the first
+// data line of the event is received and reads the data as it arrives, so events with very large
+// data, such as long generated responses, are never buffered entirely. The Event passed has no Data
+// and only the fields received before its first data line. Such events aren't dispatched to the
+// other callbacks. Each callback is run in its own goroutine and the connection doesn't receive
+// anything more until every callback read the data received so far or returned, so callbacks must
+// do either promptly. The next event is received only after all callbacks returned.
+// The client's UTF8Policy isn't applied to the streamed data and, as it isn't buffered,
+// neither is the client's MaxEventSize.
+//
+// Whether an event is streamed is decided when its first data line is received, using the
+// type received until then, so the server must send the event field before the data –
+// go-sse servers do. Otherwise the event is received entirely and, if it has data stream
+// callbacks of its type, they receive it as described below.
+//
+// Otherwise the callback receives the event after it is received entirely, in the same goroutine
+// as and after the other callbacks.
+//
+// Remove the callback by calling the returned function.
+func (c *Connection) SubscribeDataStream(typ string, cb DataStreamCallback) EventCallbackRemover {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.dataStreams[typ]; !ok {
+		c.dataStreams[typ] = map[int]DataStreamCallback{}
+	}
+
+	id := c.callbackID
+	c.dataStreams[typ][id] = cb
+	c.callbackID++
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.dataStreams[typ], id)
+		if len(c.dataStreams[typ]) == 0 {
+			delete(c.dataStreams, typ)
+		}
+	}
+}
+
+// dataStream passes the data of an event to the readers of the data stream callbacks.
+type dataStream struct {
+	writers []*io.PipeWriter
+	wg      sync.WaitGroup
+	written bool
+}
+
+// openDataStream starts the data stream callbacks of the event's type, if there are any.
+func (c *Connection) openDataStream(ev Event) *dataStream {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cbs := c.dataStreams[ev.Type]
+	if len(cbs) == 0 {
+		return nil
+	}
+
+	c.received = true
+	ev.LastEventID = c.lastEventID
+	ev.codec = c.client.Codec
+
+	s := &dataStream{}
+	for _, cb := range cbs {
+		pr, pw := io.Pipe()
+		s.writers = append(s.writers, pw)
+		s.wg.Add(1)
+
+		go func(cb DataStreamCallback) {
+			defer s.wg.Done()
+			// Closing the reader makes the writes to it fail, so callbacks which return
+			// before reading the entire data don't block the connection.
+			defer pr.Close()
+
+			if c.client.ErrorReporter != nil {
+				defer reportPanic(c.request.Context(), c.client.ErrorReporter)
+			}
+
+			cb(ev, pr)
+		}(cb)
+	}
+
+	return s
+}
+
+// line starts a new data line, separating it from the previous one.
+func (s *dataStream) line() {
+	if s.written {
+		s.write([]byte{'\n'})
+	}
+	s.written = true
+}
+
+func (s *dataStream) write(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	for _, w := range s.writers {
+		// Writes fail only if the callback returned, so the error is ignored.
+		_, _ = w.Write(p)
+	}
+}
+
+// close ends the data, with the given error if it's not nil, and waits for the callbacks to return.
+func (s *dataStream) close(err error) {
+	for _, w := range s.writers {
+		_ = w.CloseWithError(err)
+	}
+	s.wg.Wait()
+}
+
+// readStreaming reads the events as read does, except that the data of the events which have
+// data stream callbacks is passed to them as it arrives. See SubscribeDataStream.
+func (c *Connection) readStreaming(r io.Reader, setRetry func(time.Duration)) error {
+	limit := c.client.MaxEventSize
+	if limit <= 0 {
+		limit = bufio.MaxScanTokenSize
+	}
+	fr := parser.NewFieldReader(teeReader{r: r, c: c}, 4096, limit)
+	fr.KeepComments(true)
+
+	var (
+		ev      Event
+		dirty   bool
+		decided bool // whether the event's data is known to be streamed or not
+		stream  *dataStream
+		value   []byte // the value received so far of a data line which isn't streamed
+		more    bool   // whether the value of the current data line continues
+	)
+
+	dispatch := c.dispatch
+	if c.client.ProfileEvents {
+		dispatch = c.dispatchProfiled
+		c.profiler.reset()
+	}
+
+	defer func() {
+		if stream != nil {
+			stream.close(io.ErrUnexpectedEOF)
+		}
+	}()
+
+	for f := (parser.Field{}); ; {
+		cont := more
+
+		var err error
+		if more, err = fr.Next(&f); err != nil {
+			if errors.Is(err, bufio.ErrTooLong) {
+				return ErrEventTooLarge
+			}
+			if dirty && stream == nil && !cont && errors.Is(err, io.EOF) {
+				if c.isClosing() {
+					return ErrConnectionClosed
+				}
+				dispatch(ev)
+				c.saveLastEventID()
+			}
+
+			return err
+		}
+
+		if c.client.RecordEventMeta && ev.meta == nil {
+			ev.meta = &EventMeta{ReceivedAt: time.Now()}
+		}
+
+		if f.Name == parser.FieldNameData {
+			if !decided {
+				decided = true
+				stream = c.openDataStream(ev)
+			}
+			if stream != nil {
+				if !cont {
+					stream.line()
+				}
+				stream.write([]byte(f.Value))
+				dirty = true
+				continue
+			}
+			if cont || more {
+				value = append(value, f.Value...)
+				if len(value)+len(ev.Data) > limit {
+					return ErrEventTooLarge
+				}
+				if more {
+					continue
+				}
+				f.Value = string(value)
+				value = value[:0]
+			}
+		}
+
+		if err := c.checkUTF8(&f); err != nil {
+			return err
+		}
+
+		if f.Name != "" {
+			if c.processField(&ev, f, setRetry) {
+				dirty = true
+			}
+			if len(ev.Data) > limit {
+				return ErrEventTooLarge
+			}
+			continue
+		}
+
+		if stream != nil {
+			stream.close(nil)
+			stream = nil
+			c.recordEventID(ev.Type)
+			c.saveLastEventID()
+		} else if dirty {
+			if c.isClosing() {
+				return ErrConnectionClosed
+			}
+			dispatch(ev)
+			c.saveLastEventID()
+		}
+		ev, dirty, decided = Event{}, false, false
+	}
+}
@@ -0,0 +1,56 @@
+package sse
+
+import "golang.org/x/exp/slog"
+
+// DecodeJSON decodes the event's payload into a value of the given type,
+// using Event.DecodePayload. For example:
+//
+//	chunk, err := sse.DecodeJSON[openai.ChatCompletionChunk](ev)
+func DecodeJSON[T any](e Event) (T, error) {
+	var v T
+	err := e.DecodePayload(&v)
+
+	return v, err
+}
+
+// A DecodeError is reported to the Client's ErrorReporter for the events which the callbacks
+// subscribed using SubscribeJSON don't receive, because their payload failed to decode.
+type DecodeError struct {
+	// The error with which decoding failed.
+	Err error
+	// The event which failed to decode.
+	Event Event
+}
+
+func (e *DecodeError) Error() string {
+	return "go-sse.client: invalid event payload: " + e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// SubscribeJSON subscribes the given callback to the events of the connection with the given type,
+// as Connection.SubscribeEvent does, giving it each event's payload decoded into a value of
+// the given type. The events whose payload fails to decode are skipped, and a *DecodeError
+// is reported for each to the Client's ErrorReporter, if it has one. For example:
+//
+//	sse.SubscribeJSON(conn, "order", func(o Order, e sse.Event) {
+//		fmt.Println(o.ID, o.Total)
+//	})
+//
+// Remove the callback by calling the returned function.
+func SubscribeJSON[T any](c *Connection, typ string, cb func(T, Event)) EventCallbackRemover {
+	return c.SubscribeEvent(typ, func(e Event) {
+		v, err := DecodeJSON[T](e)
+		if err != nil {
+			c.log(c.request.Context(), slog.LevelWarn, "sse: invalid event payload", "type", e.Type, "error", err)
+			if c.client.ErrorReporter != nil {
+				c.client.ErrorReporter.ReportError(c.request.Context(), &DecodeError{Err: err, Event: e})
+			}
+			return
+		}
+
+		cb(v, e)
+	})
+}
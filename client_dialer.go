@@ -0,0 +1,152 @@
+package sse
+
+import (
+	"context"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FailoverDialer dials connections by resolving the host on every dial and by trying its addresses
+// in order, preferring the addresses to which dialing didn't fail recently. It improves recovery time
+// when endpoints move during incidents: combine it with the Client's ReresolveOnRetry option, so the
+// reconnection attempts don't reuse a pooled connection to an address which is gone.
+//
+// Use it with an http.Transport:
+//
+//	d := &sse.FailoverDialer{}
+//	client := &sse.Client{
+//		HTTPClient:       &http.Client{Transport: &http.Transport{DialContext: d.DialContext}},
+//		ReresolveOnRetry: true,
+//	}
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type FailoverDialer struct {
+	// The dialer used to connect to each address. Defaults to a net.Dialer with no options set.
+	Dialer *net.Dialer
+	// The function used to resolve hosts to addresses. Defaults to net.DefaultResolver.LookupHost.
+	LookupHost func(ctx context.Context, host string) ([]string, error)
+	// For how long an address to which dialing failed is avoided. Defaults to 30 seconds.
+	FailureTTL time.Duration
+
+	failures map[string]time.Time
+	mu       sync.Mutex
+}
+
+// DialContext connects to the address on the named network. See net.Dialer.DialContext.
+// The host is resolved and its addresses are tried one by one, the recently failed ones last.
+func (d *FailoverDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := d.lookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs = d.order(addrs, time.Now())
+
+	// net.Dialer also returns only the first error when it tries multiple addresses.
+	var firstErr error
+	for _, addr := range addrs {
+		conn, err := d.dialer().DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			d.succeeded(addr)
+			return conn, nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+		if ctx.Err() != nil {
+			// The context error is not the address's fault.
+			break
+		}
+
+		d.failed(addr, time.Now())
+	}
+
+	if firstErr == nil {
+		firstErr = &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	return nil, firstErr
+}
+
+func (d *FailoverDialer) dialer() *net.Dialer {
+	if d.Dialer != nil {
+		return d.Dialer
+	}
+
+	return &net.Dialer{}
+}
+
+func (d *FailoverDialer) lookupHost(ctx context.Context, host string) ([]string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []string{host}, nil
+	}
+	if d.LookupHost != nil {
+		return d.LookupHost(ctx, host)
+	}
+
+	return net.DefaultResolver.LookupHost(ctx, host)
+}
+
+func (d *FailoverDialer) failureTTL() time.Duration {
+	if d.FailureTTL > 0 {
+		return d.FailureTTL
+	}
+
+	return 30 * time.Second
+}
+
+// order returns the addresses which didn't fail recently in the given order,
+// followed by the recently failed ones, the least recently failed first.
+func (d *FailoverDialer) order(addrs []string, now time.Time) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	ttl := d.failureTTL()
+	failedAt := func(addr string) time.Time {
+		t, ok := d.failures[addr]
+		if !ok || now.Sub(t) >= ttl {
+			return time.Time{}
+		}
+		return t
+	}
+
+	ordered := append([]string(nil), addrs...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return failedAt(ordered[i]).Before(failedAt(ordered[j]))
+	})
+
+	return ordered
+}
+
+func (d *FailoverDialer) failed(addr string, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.failures == nil {
+		d.failures = map[string]time.Time{}
+	}
+
+	ttl := d.failureTTL()
+	for a, t := range d.failures {
+		if now.Sub(t) >= ttl {
+			delete(d.failures, a)
+		}
+	}
+
+	d.failures[addr] = now
+}
+
+func (d *FailoverDialer) succeeded(addr string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.failures, addr)
+}
@@ -0,0 +1,61 @@
+package sse_test
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestFailoverDialer(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err, "failed to listen")
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	var lookups int
+	d := &sse.FailoverDialer{
+		LookupHost: func(_ context.Context, host string) ([]string, error) {
+			require.Equal(t, "events.example.com", host, "unexpected host lookup")
+			lookups++
+			// Nothing listens on 127.0.0.2, so dialing it fails.
+			return []string{"127.0.0.2", "127.0.0.1"}, nil
+		},
+	}
+
+	var dialed []string
+	d.Dialer = &net.Dialer{Control: func(_, address string, _ syscall.RawConn) error {
+		dialed = append(dialed, address)
+		return nil
+	}}
+
+	for i := 0; i < 2; i++ {
+		conn, err := d.DialContext(context.Background(), "tcp", net.JoinHostPort("events.example.com", port))
+		require.NoError(t, err, "unexpected dial error")
+		require.Equal(t, l.Addr().String(), conn.RemoteAddr().String(), "connected to wrong address")
+		_ = conn.Close()
+	}
+
+	require.Equal(t, 2, lookups, "host should be resolved on every dial")
+	require.Equal(t, []string{
+		net.JoinHostPort("127.0.0.2", port),
+		net.JoinHostPort("127.0.0.1", port),
+		net.JoinHostPort("127.0.0.1", port),
+	}, dialed, "recently failed address should be tried last")
+}
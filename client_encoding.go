@@ -0,0 +1,65 @@
+package sse
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrUnsupportedEncoding is returned, wrapped, by Connect if the server compressed the stream using
+// a content encoding which the client can't decode. See Client.ContentDecoders.
+var ErrUnsupportedEncoding = errors.New("go-sse.client: unsupported content encoding")
+
+// A ContentDecoder decodes a stream compressed using a content encoding, such as br or zstd.
+type ContentDecoder func(r io.Reader) (io.Reader, error)
+
+func decodeGzip(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+
+func decodeDeflate(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) }
+
+// contentDecoder returns the decoder of the given content encoding, if the client has one.
+func (c *Client) contentDecoder(encoding string) ContentDecoder {
+	if d, ok := c.ContentDecoders[encoding]; ok {
+		return d
+	}
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		return decodeGzip
+	case "deflate":
+		return decodeDeflate
+	}
+
+	return nil
+}
+
+// decodeBody decodes the response's body according to its Content-Encoding header,
+// undoing the encodings in the reverse order of their application.
+func (c *Client) decodeBody(res *http.Response, body io.Reader) (io.Reader, error) {
+	var encodings []string
+	for _, v := range res.Header.Values("Content-Encoding") {
+		for _, e := range strings.Split(v, ",") {
+			if e = strings.ToLower(strings.TrimSpace(e)); e != "" && e != "identity" {
+				encodings = append(encodings, e)
+			}
+		}
+	}
+
+	for i := len(encodings) - 1; i >= 0; i-- {
+		decode := c.contentDecoder(encodings[i])
+		if decode == nil {
+			return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, encodings[i])
+		}
+
+		var err error
+		if body, err = decode(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
@@ -0,0 +1,151 @@
+package sse
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// PartitionFunc returns the partition key of an event. A ConsumerGroup delivers all the events
+// with the same key to the same member, as long as the group's members don't change.
+// Events with an empty key are distributed among the members in a round-robin fashion.
+type PartitionFunc func(Event) string
+
+// PartitionByEventID partitions the events by their LastEventID.
+func PartitionByEventID(e Event) string { return e.LastEventID }
+
+// A ConsumerGroup distributes the events of a type among its members, so that each event is received
+// by exactly one member instead of all of them – for example, to share the work between multiple workers.
+// Create one using Connection.SubscribeGroup.
+//
+// Each member runs its callback in its own goroutine, so the members process events concurrently.
+// The members receive the events through channels which behave the same as the channels returned by
+// Connection.Events: their buffer size and what happens when they are full are set by the Client's
+// ChannelBuffer and ChannelBackpressure options. Members which overflow with BackpressureUnsubscribe leave
+// the group. Events received while the group has no members are dropped.
+type ConsumerGroup struct {
+	partition PartitionFunc
+	unsub     EventCallbackRemover
+	opts      ChannelOptions
+
+	mu      sync.RWMutex
+	members []*groupMember
+	next    atomic.Uint64
+}
+
+type groupMember struct {
+	fn     EventCallback
+	leave  EventCallbackRemover
+	events chan Event
+	quit   chan struct{}
+	once   sync.Once
+}
+
+// SubscribeGroup creates a consumer group which receives the events with the given type. If the partition
+// function is nil, all events are distributed among the members in a round-robin fashion.
+// Add members to the group using the Join method.
+func (c *Connection) SubscribeGroup(typ string, partition PartitionFunc) *ConsumerGroup {
+	g := &ConsumerGroup{partition: partition, opts: ChannelOptions{
+		Buffer:       c.client.ChannelBuffer,
+		Backpressure: c.client.ChannelBackpressure,
+	}}
+	if g.opts.Buffer < 0 {
+		g.opts.Buffer = 0
+	}
+
+	g.unsub = c.SubscribeEvent(typ, func(e Event) {
+		g.mu.RLock()
+		defer g.mu.RUnlock()
+
+		if m := g.member(e); m != nil && !c.sendToChannel(context.Background(), m.quit, m.events, e, g.opts) {
+			// The group is locked, so the member leaves it asynchronously.
+			go m.leave()
+		}
+	})
+
+	return g
+}
+
+// Join adds a member to the group, which receives its share of events in the given callback.
+// The member leaves the group when the returned function is called. The events the member
+// has not processed yet are dropped, and the keys of its partitions are distributed among
+// the other members.
+func (g *ConsumerGroup) Join(cb EventCallback) EventCallbackRemover {
+	m := &groupMember{fn: cb, events: make(chan Event, g.opts.Buffer), quit: make(chan struct{})}
+	m.leave = func() {
+		// Stop the member before removing it, so a pending send to it doesn't block the removal.
+		if !m.stop() {
+			return
+		}
+
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		for i := range g.members {
+			if g.members[i] == m {
+				g.members = append(g.members[:i], g.members[i+1:]...)
+				break
+			}
+		}
+	}
+
+	g.mu.Lock()
+	g.members = append(g.members, m)
+	g.mu.Unlock()
+
+	go m.run()
+
+	return m.leave
+}
+
+// Close unsubscribes the group from the connection and removes all its members.
+func (g *ConsumerGroup) Close() {
+	g.unsub()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, m := range g.members {
+		m.stop()
+	}
+	g.members = nil
+}
+
+func (g *ConsumerGroup) member(e Event) *groupMember {
+	n := uint64(len(g.members))
+	if n == 0 {
+		return nil
+	}
+
+	var key string
+	if g.partition != nil {
+		key = g.partition(e)
+	}
+	if key == "" {
+		return g.members[(g.next.Add(1)-1)%n]
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return g.members[h.Sum64()%n]
+}
+
+func (m *groupMember) run() {
+	for {
+		select {
+		case e := <-m.events:
+			m.fn(e)
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+func (m *groupMember) stop() (stopped bool) {
+	m.once.Do(func() {
+		close(m.quit)
+		stopped = true
+	})
+	return
+}
@@ -0,0 +1,35 @@
+package sse
+
+// An EventHook filters or transforms the events received by a connection before they are dispatched
+// to its callbacks – for example, to drop the events of certain types or to redact their data. Hooks
+// are set using the Client's EventHooks option, so the programs which receive events can be extended
+// without changing their callbacks, for example by loading hooks from Go plugins.
+//
+// HookEvent returns the event to dispatch, which may be modified, and whether to dispatch it.
+// Hooks are called in the goroutine which receives the events, before the callbacks, so they
+// must not block. If the Client's PooledEventData option is set, the event's Data is valid only
+// until HookEvent returns, unless it is replaced.
+type EventHook interface {
+	HookEvent(Event) (Event, bool)
+}
+
+// EventHookFunc is a function which implements the EventHook interface.
+type EventHookFunc func(Event) (Event, bool)
+
+// HookEvent calls the function.
+func (f EventHookFunc) HookEvent(e Event) (Event, bool) {
+	return f(e)
+}
+
+// hookEvent passes the event through the Client's EventHooks, in order. It returns false
+// if a hook dropped the event.
+func (c *Connection) hookEvent(ev Event) (Event, bool) {
+	for _, h := range c.client.EventHooks {
+		var ok bool
+		if ev, ok = h.HookEvent(ev); !ok {
+			return ev, false
+		}
+	}
+
+	return ev, true
+}
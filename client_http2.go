@@ -0,0 +1,30 @@
+package sse
+
+import "net/http"
+
+// The HTTP/2 flow-control windows of the clients created by NewHTTP2Client. The stream window lets
+// servers send bursts of events without waiting for the client to acknowledge them, and the connection
+// window lets many streams share a connection without one slow stream stalling the others.
+const (
+	http2StreamWindow     = 1 << 20
+	http2ConnectionWindow = 16 << 20
+)
+
+// NewHTTP2Client creates a client with the settings of DefaultClient, whose HTTP client is configured
+// for streams which stay open indefinitely over HTTP/2: there are no request or response timeouts,
+// HTTP/2 is used for all TLS connections, the flow-control windows are sized for long-lived streams
+// and dead connections are detected using pings. Multiple connections to the same server share
+// a single TCP connection.
+//
+// The flow-control windows and the pings are configured only when built with Go 1.24 or newer.
+// Configure the client further before using it, if needed.
+func NewHTTP2Client() *Client {
+	t := http.DefaultTransport.(*http.Transport).Clone() //nolint:forcetypeassert // The default transport is always an *http.Transport.
+	t.ForceAttemptHTTP2 = true
+	configureHTTP2(t)
+
+	c := *DefaultClient
+	c.HTTPClient = &http.Client{Transport: t}
+
+	return &c
+}
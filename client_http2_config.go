@@ -0,0 +1,19 @@
+//go:build go1.24
+
+package sse
+
+import (
+	"net/http"
+	"time"
+)
+
+// configureHTTP2 sets the HTTP/2 flow-control windows of the transport and makes it detect dead
+// connections, which would otherwise be noticed only when the operating system times them out.
+func configureHTTP2(t *http.Transport) {
+	t.HTTP2 = &http.HTTP2Config{
+		MaxReceiveBufferPerStream:     http2StreamWindow,
+		MaxReceiveBufferPerConnection: http2ConnectionWindow,
+		SendPingTimeout:               30 * time.Second,
+		PingTimeout:                   15 * time.Second,
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !go1.24
+
+package sse
+
+import "net/http"
+
+// configureHTTP2 does nothing before Go 1.24, which is required to configure HTTP/2 using the standard library.
+func configureHTTP2(_ *http.Transport) {}
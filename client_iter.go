@@ -0,0 +1,64 @@
+//go:build go1.23
+
+package sse
+
+import "iter"
+
+// All returns an iterator over all the events received, which connects when the iteration starts:
+//
+//	for ev, err := range conn.All() {
+//		if err != nil {
+//			// the connection ended
+//		}
+//		// handle ev
+//	}
+//
+// The connection is stopped when the iteration is stopped. Otherwise, once the connection ends,
+// the iteration ends with the error Connect returned, if any, yielded together with an empty event.
+// Don't call Connect on the connection while iterating.
+func (c *Connection) All() iter.Seq2[Event, error] {
+	return c.seq(c.SubscribeToAll)
+}
+
+// ByType returns an iterator over the events with the given type. See All for more info.
+func (c *Connection) ByType(typ string) iter.Seq2[Event, error] {
+	return c.seq(func(cb EventCallback) EventCallbackRemover { return c.SubscribeEvent(typ, cb) })
+}
+
+func (c *Connection) seq(subscribe func(EventCallback) EventCallbackRemover) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		events := make(chan Event)
+		stop := make(chan struct{})
+
+		remove := subscribe(func(e Event) {
+			// The event is yielded after the callback returns.
+			select {
+			case events <- c.retain(e):
+			case <-stop:
+			}
+		})
+		defer remove()
+
+		errc := make(chan error, 1)
+		go func() { errc <- c.Connect() }()
+
+		for {
+			select {
+			case e := <-events:
+				if !yield(e, nil) {
+					// Unblock the callback before stopping the connection.
+					close(stop)
+					c.stop()
+					<-errc
+					return
+				}
+			case err := <-errc:
+				// Events are handed over synchronously, so none are pending.
+				if err != nil {
+					yield(Event{}, err)
+				}
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+//go:build go1.23
+
+package sse_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestConnection_All(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "data: 1\n\nevent: a\ndata: 2\n\ndata: 3\n\n")
+	}))
+	defer ts.Close()
+
+	c := &sse.Client{HTTPClient: ts.Client(), ResponseValidator: sse.NoopValidator}
+
+	var data []string
+	var last error
+	for ev, err := range c.NewConnection(req(t, "", ts.URL, nil)).All() {
+		if err != nil {
+			last = err
+			continue
+		}
+		data = append(data, ev.Data)
+	}
+	require.Equal(t, []string{"1", "2", "3"}, data, "invalid events")
+	require.ErrorIs(t, last, io.EOF, "iteration should end with the connection's error")
+
+	data = nil
+	for ev, err := range c.NewConnection(req(t, "", ts.URL, nil)).ByType("a") {
+		require.NoError(t, err, "iteration should be stopped before the connection ends")
+		data = append(data, ev.Data)
+		break
+	}
+	require.Equal(t, []string{"2"}, data, "invalid events by type")
+
+	conn := c.NewConnection(req(t, "", ts.URL, nil))
+	for range conn.All() {
+		break
+	}
+	for ev, err := range conn.ByType("") {
+		if err == nil {
+			data = append(data, ev.Data)
+		}
+	}
+	require.Equal(t, []string{"2", "1", "3"}, data, "connection should be reusable after the iteration is stopped")
+}
+
+func TestConnection_All_pooled(t *testing.T) {
+	ts, expected := pooledEvents(t)
+
+	c := &sse.Client{HTTPClient: ts.Client(), ResponseValidator: sse.NoopValidator, PooledEventData: true}
+
+	var received []string
+	for ev, err := range c.NewConnection(req(t, "", ts.URL, nil)).All() {
+		if err != nil {
+			break
+		}
+		// Yield to the connection, so it reads the next events while this one is used.
+		time.Sleep(time.Microsecond * 50)
+		received = append(received, ev.Data)
+	}
+	require.Equal(t, expected, received, "iterator yielded altered data")
+}
@@ -0,0 +1,135 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// JSONArrayTransport is an http.RoundTripper which converts the responses of legacy streaming
+// endpoints, which send their events as the elements of a JSON array that is never closed, into
+// event streams. Each element of the array becomes an event, whose data is the compacted element.
+// This allows consuming such endpoints with a Connection until they are migrated to server-sent events.
+//
+// Use it as the transport of the Client's HTTP client:
+//
+//	client := &sse.Client{
+//		HTTPClient: &http.Client{Transport: &sse.JSONArrayTransport{}},
+//	}
+//
+// Only the successful responses with the application/json content type are converted,
+// the others are returned as they are. If the array is malformed, reading the response fails,
+// which the Connection treats as a lost connection. The zero value is ready to use.
+type JSONArrayTransport struct {
+	// The transport used to make the requests. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// The type of the events created from the array's elements. If it is empty,
+	// the events have no type, so they are received by the SubscribeMessages callbacks.
+	EventType string
+}
+
+// RoundTrip executes the request and converts its response, if necessary.
+func (t *JSONArrayTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	res, err := transport.RoundTrip(r)
+	if err != nil || res.StatusCode != http.StatusOK || contentType(res.Header.Get("Content-Type")) != "application/json" {
+		return res, err
+	}
+
+	res.Header = res.Header.Clone()
+	res.Header.Set("Content-Type", "text/event-stream")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	body := &jsonArrayBody{body: res.Body, eventType: t.EventType}
+	body.dec = json.NewDecoder(errorRecorder{r: res.Body, err: &body.readErr})
+	res.Body = body
+
+	return res, nil
+}
+
+// jsonArrayBody reads the elements of a JSON array and returns them as events in the wire format.
+type jsonArrayBody struct {
+	body      io.ReadCloser
+	dec       *json.Decoder
+	eventType string
+	buf       bytes.Buffer
+	// The error returned by the body, if any. The decoder reports it as a syntax error
+	// if the stream ends in the middle of the array, which is expected for the legacy endpoints.
+	readErr error
+	started bool
+}
+
+type errorRecorder struct {
+	r   io.Reader
+	err *error
+}
+
+func (e errorRecorder) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err != nil {
+		*e.err = err
+	}
+	return n, err
+}
+
+func (j *jsonArrayBody) Read(p []byte) (int, error) {
+	for j.buf.Len() == 0 {
+		if err := j.next(); err != nil {
+			if j.readErr != nil {
+				return 0, j.readErr
+			}
+			return 0, err
+		}
+	}
+
+	return j.buf.Read(p)
+}
+
+func (j *jsonArrayBody) next() error {
+	if !j.started {
+		tok, err := j.dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('[') {
+			return fmt.Errorf("go-sse.client: expected JSON array, received %v", tok)
+		}
+
+		j.started = true
+	}
+
+	if !j.dec.More() {
+		if _, err := j.dec.Token(); err != nil {
+			return err
+		}
+		return io.EOF
+	}
+
+	var element json.RawMessage
+	if err := j.dec.Decode(&element); err != nil {
+		return err
+	}
+
+	if j.eventType != "" {
+		j.buf.Write(fieldBytesEvent)
+		j.buf.WriteString(j.eventType)
+		j.buf.Write(newline)
+	}
+	j.buf.Write(fieldBytesData)
+	// Compacting removes the newlines, as strings can't contain unescaped ones.
+	_ = json.Compact(&j.buf, element)
+	j.buf.Write(newline)
+	j.buf.Write(newline)
+
+	return nil
+}
+
+func (j *jsonArrayBody) Close() error {
+	return j.body.Close()
+}
@@ -0,0 +1,219 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// lastEventIDSaveTimeout limits how long the latest ID is persisted for after the connection ends,
+// when the connection's context may be canceled already.
+const lastEventIDSaveTimeout = 5 * time.Second
+
+// A LastEventIDStore persists the ID of the last event received by connections, so their streams
+// can be resumed exactly where they were left off after the program restarts – for example,
+// using a file or Redis. Connections are identified by the key given to the store, which is
+// returned by the Client's LastEventIDKey. It must be safe for concurrent use.
+type LastEventIDStore interface {
+	// Load returns the ID persisted for the given key, or an empty string if there is none.
+	Load(ctx context.Context, key string) (string, error)
+	// Save persists the ID for the given key.
+	Save(ctx context.Context, key, id string) error
+}
+
+// DefaultLastEventIDKey returns the URL of the request without its user info and query string,
+// so the credentials and the tokens sent in the URL aren't given to the LastEventIDStore.
+// Set the Client's LastEventIDKey if the query string identifies the stream.
+func DefaultLastEventIDKey(r *http.Request) string {
+	u := *r.URL
+	u.User, u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = nil, "", false, "", ""
+
+	return u.String()
+}
+
+// LastEventID returns the ID of the last event received, or the ID set using StartFromEventID
+// if no event with an ID was received yet. It is safe for concurrent use.
+func (c *Connection) LastEventID() string {
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+
+	return c.lastEventID
+}
+
+// setLastEventID sets the ID of the last event received. The IDs are written only by the goroutine
+// which receives the events, so it can read them without locking.
+func (c *Connection) setLastEventID(id string) {
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+
+	c.lastEventID = id
+}
+
+// LastEventIDs returns the ID of the last event received of each type, for servers which multiplex
+// multiple streams with separate IDs over one connection, if the Client's TrackLastEventIDs option is set.
+// Events without type are under the empty string. Types whose events had no ID are not included.
+// It is safe for concurrent use.
+func (c *Connection) LastEventIDs() map[string]string {
+	if !c.client.TrackLastEventIDs {
+		return nil
+	}
+
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+
+	ids := make(map[string]string, len(c.lastEventIDs))
+	for typ, id := range c.lastEventIDs {
+		ids[typ] = id
+	}
+
+	return ids
+}
+
+// recordEventID records the ID of the event being dispatched as the last one of its type,
+// if the event has an ID and the Client's TrackLastEventIDs option is set.
+func (c *Connection) recordEventID(typ string) {
+	hasID := c.eventHasID
+	c.eventHasID = false
+
+	if !hasID || !c.client.TrackLastEventIDs {
+		return
+	}
+
+	c.idMu.Lock()
+	defer c.idMu.Unlock()
+
+	if c.lastEventIDs == nil {
+		c.lastEventIDs = map[string]string{}
+	}
+	c.lastEventIDs[typ] = c.lastEventID
+}
+
+// loadLastEventID starts the connection from the ID persisted by the Client's LastEventIDStore,
+// unless an ID was already set using StartFromEventID or received by a previous Connect call.
+func (c *Connection) loadLastEventID(ctx context.Context) error {
+	store := c.client.LastEventIDStore
+	if store == nil || c.lastEventID != "" {
+		return nil
+	}
+
+	id, err := store.Load(ctx, c.client.lastEventIDKey(c.request))
+	if err != nil {
+		if errors.Is(err, ctx.Err()) {
+			return err
+		}
+		return c.newError("last event ID load failed", err)
+	}
+
+	c.StartFromEventID(id)
+	c.savedID = id
+
+	return nil
+}
+
+func (c *Client) lastEventIDKey(r *http.Request) string {
+	if c.LastEventIDKey != nil {
+		return c.LastEventIDKey(r)
+	}
+	return DefaultLastEventIDKey(r)
+}
+
+// lastEventIDSaver persists the IDs queued by the goroutine which receives the events in the background,
+// so a slow LastEventIDStore doesn't delay the events. Only the latest ID queued is persisted.
+type lastEventIDSaver struct {
+	notify chan struct{}
+	queued string // The latest ID queued.
+	saved  string // The latest ID persisted.
+	dirty  bool   // Whether the queued ID wasn't persisted yet.
+	mu     sync.Mutex
+}
+
+// startSavingLastEventIDs persists the IDs queued by saveLastEventID in a new goroutine, until the returned
+// function is called, which persists the latest ID, if it wasn't already, and waits for the goroutine to end.
+// The latest ID is persisted with a new context, as the given one is canceled if the connection was stopped
+// by canceling it. Errors are given to the Client's ErrorReporter.
+func (c *Connection) startSavingLastEventIDs(ctx context.Context) (stop func()) {
+	store := c.client.LastEventIDStore
+	if store == nil {
+		return func() {}
+	}
+
+	s := &lastEventIDSaver{notify: make(chan struct{}, 1), queued: c.savedID, saved: c.savedID}
+	c.saver = s
+	key := c.client.lastEventIDKey(c.request)
+
+	save := func(ctx context.Context) {
+		s.mu.Lock()
+		id, dirty := s.queued, s.dirty
+		s.dirty = false
+		s.mu.Unlock()
+
+		if !dirty {
+			return
+		}
+
+		err := store.Save(ctx, key, id)
+
+		s.mu.Lock()
+		if err == nil {
+			s.saved = id
+		} else if s.queued == id {
+			// Retried when the next ID is queued or when the connection ends.
+			s.dirty = true
+		}
+		s.mu.Unlock()
+
+		if err != nil && c.client.ErrorReporter != nil {
+			c.client.ErrorReporter.ReportError(ctx, c.newError("last event ID save failed", err))
+		}
+	}
+
+	done, quit := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-s.notify:
+				save(ctx)
+			case <-quit:
+				ctx, cancel := context.WithTimeout(context.Background(), lastEventIDSaveTimeout)
+				save(ctx)
+				cancel()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+
+		c.saver = nil
+		c.savedID = s.saved
+	}
+}
+
+// saveLastEventID queues the ID of the last event received to be persisted, after the event was
+// dispatched, if it changed since it was last queued.
+func (c *Connection) saveLastEventID() {
+	s := c.saver
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	changed := s.queued != c.lastEventID
+	if changed {
+		s.queued, s.dirty = c.lastEventID, true
+	}
+	s.mu.Unlock()
+
+	if changed {
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package sse
+
+// Lifecycle identifies the synthetic events which notify about changes in a connection's state.
+// Events received from the server have an empty Lifecycle.
+type Lifecycle string
+
+// The lifecycle events delivered to the callbacks subscribed using SubscribeToAllWithLifecycle.
+const (
+	// The server accepted the connection. Sent on the first connection and on every reconnection.
+	LifecycleConnected Lifecycle = "connected"
+	// The connection failed and it will be reattempted. The event's Data is the error which occurred.
+	LifecycleReconnecting Lifecycle = "reconnecting"
+	// The connection was reestablished, but events may have been missed: events were received
+	// before, but none of them had an ID, so the server doesn't know where to resume the stream from.
+	LifecycleGapDetected Lifecycle = "gap-detected"
+	// Connect is about to return. The event's Data is the error Connect returns.
+	LifecycleClosed Lifecycle = "closed"
+)
+
+// SubscribeToAllWithLifecycle is the same as SubscribeToAll, but the callback also receives
+// lifecycle events, which have a non-empty Lifecycle field. They are delivered inline with the
+// events received from the server, so the callback is notified about reconnections and possible
+// gaps in the stream exactly where they happen.
+//
+// Lifecycle events have the connection's last event ID, an empty Type and, depending on the
+// Lifecycle value, an empty Data.
+func (c *Connection) SubscribeToAllWithLifecycle(cb EventCallback) EventCallbackRemover {
+	return c.addSubscriberToAll(callback{fn: cb, priority: PriorityNormal, lifecycle: true})
+}
+
+func (c *Connection) dispatchLifecycle(l Lifecycle, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ev := Event{LastEventID: c.lastEventID, Lifecycle: l}
+	if err != nil {
+		ev.Data = err.Error()
+	}
+
+	for _, cb := range c.callbacksAll {
+		if cb.lifecycle {
+			cb.fn(ev)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package sse
+
+import (
+	"context"
+
+	"golang.org/x/exp/slog"
+)
+
+// log logs a message about the connection, if the Client has a Logger.
+// The connection's ID is added to the attributes.
+func (c *Connection) log(ctx context.Context, level slog.Level, msg string, args ...any) {
+	if c.client.Logger == nil {
+		return
+	}
+
+	c.client.Logger.Log(ctx, level, msg, append([]any{"connection", c.id}, args...)...)
+}
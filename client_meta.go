@@ -0,0 +1,22 @@
+package sse
+
+import "time"
+
+// EventMeta is the information about how an event was received, recorded when the Client's
+// RecordEventMeta option is set.
+type EventMeta struct {
+	// The event as it was received, including its comments and the blank line which ends it.
+	Raw string
+	// The time at which the client started parsing the event.
+	ReceivedAt time.Time
+}
+
+// Meta returns the event's metadata. It returns false if the event's client doesn't record
+// metadata or if the event wasn't received from a server, as is the case for lifecycle events.
+func (e Event) Meta() (EventMeta, bool) {
+	if e.meta == nil {
+		return EventMeta{}, false
+	}
+
+	return *e.meta, true
+}
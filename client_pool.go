@@ -0,0 +1,74 @@
+package sse
+
+import (
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// maxPooledEventData is the capacity over which the data buffers are not returned to the pool,
+// so a few very large events don't make the pool retain a lot of memory.
+const maxPooledEventData = 64 * 1024
+
+var eventDataPool = sync.Pool{
+	New: func() any {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+func getEventData() *[]byte {
+	return eventDataPool.Get().(*[]byte) //nolint:forcetypeassert // The pool contains only byte slices.
+}
+
+func putEventData(b *[]byte) {
+	if cap(*b) > maxPooledEventData {
+		return
+	}
+
+	*b = (*b)[:0]
+	eventDataPool.Put(b)
+}
+
+// dispatchPooled dispatches the event with the given pooled data buffer, if it is not nil, as its data.
+// The buffer is returned to the pool after the callbacks return.
+func dispatchPooled(dispatch func(Event), ev Event, data *[]byte) {
+	if data == nil {
+		dispatch(ev)
+		return
+	}
+
+	ev.Data = *(*string)(unsafe.Pointer(data))
+	dispatch(ev)
+	putEventData(data)
+}
+
+// retain returns the event such that it can be used after the callbacks return,
+// which requires copying its data if the data is pooled.
+func (c *Connection) retain(e Event) Event {
+	if c.client.PooledEventData {
+		return e.Clone()
+	}
+	return e
+}
+
+// cloneString returns a copy of s which doesn't share its memory.
+func cloneString(s string) string {
+	if s == "" {
+		return ""
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	b.WriteString(s)
+
+	return b.String()
+}
+
+// Clone returns a copy of the event which doesn't share memory with the connection's buffers.
+// Use it to retain the events received by connections of clients with the PooledEventData option
+// set, or their data, after the callbacks return.
+func (e Event) Clone() Event {
+	e.Data = cloneString(e.Data)
+	return e
+}
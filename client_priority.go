@@ -0,0 +1,76 @@
+package sse
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultLowPriorityBuffer is the number of events the PriorityLow callbacks may fall behind
+// by for a Client which doesn't set LowPriorityBuffer.
+const DefaultLowPriorityBuffer = 256
+
+// lowPriorityQueue runs the PriorityLow callbacks in their own goroutine, so they don't delay
+// the delivery of the next events to the other callbacks.
+type lowPriorityQueue struct {
+	jobs chan func()
+	done chan struct{}
+}
+
+// startLowPriority starts the goroutine which runs the PriorityLow callbacks until the returned
+// function is called, which waits for the queued callbacks to be run.
+func (c *Connection) startLowPriority(ctx context.Context) (stop func()) {
+	size := c.client.LowPriorityBuffer
+	if size <= 0 {
+		size = DefaultLowPriorityBuffer
+	}
+
+	q := &lowPriorityQueue{jobs: make(chan func(), size), done: make(chan struct{})}
+	c.low = q
+
+	go func() {
+		defer close(q.done)
+
+		for job := range q.jobs {
+			c.runLowPriority(ctx, job)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(q.jobs)
+			<-q.done
+			c.low = nil
+		})
+	}
+}
+
+func (c *Connection) runLowPriority(ctx context.Context, job func()) {
+	if c.client.ErrorReporter != nil {
+		defer reportPanic(ctx, c.client.ErrorReporter)
+	}
+
+	job()
+}
+
+// dispatchLowPriority queues the event for the given PriorityLow callbacks. If the queue is full,
+// it waits for the callbacks to catch up. Outside Connect, the callbacks are called directly.
+// The queued events are retained, as their data may be pooled and reused once dispatch returns.
+func (c *Connection) dispatchLowPriority(cbs []callback, ev Event) {
+	if c.low != nil {
+		ev = c.retain(ev)
+	}
+
+	job := func() {
+		for _, cb := range cbs {
+			c.call(cb, ev)
+		}
+	}
+
+	if c.low == nil {
+		job()
+		return
+	}
+
+	c.low.jobs <- job
+}
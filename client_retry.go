@@ -0,0 +1,68 @@
+package sse
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// RetryCallback is a function that is used to receive the reconnection delays set by the server.
+type RetryCallback func(delay time.Duration)
+
+// OnRetryValue subscribes the given callback to the changes of the reconnection delay, which the
+// server makes using the retry field. It is called with the new delay, after it is applied to the
+// connection's backoff, in the same goroutine and order as the event callbacks, so the same
+// restrictions apply to it. Invalid or non-positive retry values, which are ignored, are not reported.
+// Remove the callback by calling the returned function.
+func (c *Connection) OnRetryValue(cb RetryCallback) EventCallbackRemover {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.callbackID
+	c.retries[id] = cb
+	c.callbackID++
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.retries, id)
+	}
+}
+
+func (c *Connection) dispatchRetry(delay time.Duration) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.client.ErrorReporter != nil {
+		defer reportPanic(c.request.Context(), c.client.ErrorReporter)
+	}
+
+	for _, cb := range c.retries {
+		cb(delay)
+	}
+}
+
+// DefaultShouldRetry is a Client.ShouldRetry classifier which follows the spec: requests which fail,
+// for example because of network errors, and responses with the status 429 Too Many Requests or
+// 503 Service Unavailable are retried, while all the other rejected responses fail the connection.
+//
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html#sse-processing-model.
+func DefaultShouldRetry(_ error, resp *http.Response) bool {
+	if resp == nil {
+		return true
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// retryable makes the error of a failed connection attempt permanent, if the ShouldRetry
+// classifier says so.
+func (c *Client) retryable(err *ConnectionError, resp *http.Response) error {
+	if c.ShouldRetry != nil && !c.ShouldRetry(err.Err, resp) {
+		return backoff.Permanent(err)
+	}
+
+	return err
+}
@@ -0,0 +1,56 @@
+package sse
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// retryAfterBackOff waits for the duration the server asked for using the Retry-After header,
+// if there is one, instead of the duration of the wrapped backoff.
+type retryAfterBackOff struct {
+	backoff.BackOff
+	// The duration to wait before the next reconnection attempt. It is used only once.
+	after time.Duration
+}
+
+func (b *retryAfterBackOff) NextBackOff() time.Duration {
+	next := b.BackOff.NextBackOff()
+	if next != backoff.Stop && b.after > 0 {
+		next = b.after
+	}
+	b.after = 0
+
+	return next
+}
+
+// retryAfter returns how long the server asked clients to wait before reconnecting, if the response's
+// status is 429 Too Many Requests or 503 Service Unavailable and it has a valid Retry-After header.
+// Otherwise, it returns 0.
+func retryAfter(res *http.Response) time.Duration {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+
+	header := res.Header.Get("Retry-After")
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if d := time.Until(date); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
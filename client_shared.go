@@ -0,0 +1,296 @@
+package sse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SharedClient creates connections that share a single upstream stream when their requests are identical.
+// This is useful for proxies and gateways, which would otherwise open duplicate connections to the same server.
+//
+// Two requests are identical if they have the same method, URL and values for the KeyHeaders.
+// The first connection for which Connect is called opens the upstream stream; the connections
+// made afterwards for an identical request receive the most recent events of the stream and then
+// all the new events, as if they were connected to the server directly. The upstream is closed
+// when all the connections that use it are done.
+//
+// The connections created by a SharedClient are regular Connections – subscribe to them and connect
+// them as usual. They retry according to the upstream Client's configuration, if the shared stream fails.
+// Events are dispatched to the connections in order; a connection with slow callbacks doesn't slow down
+// the others, but its events are buffered in memory until they are consumed.
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type SharedClient struct {
+	// The client used to connect to the upstream and to configure the shared connections.
+	// Defaults to DefaultClient.
+	Client *Client
+	// The headers, other than the method and URL, whose values must be equal for requests
+	// to be considered identical.
+	KeyHeaders []string
+	// The number of recent events sent to connections which join an already open upstream.
+	// If it is 0, connections which join later receive only the new events.
+	ReplayCount int
+
+	streams map[string]*sharedStream
+	mu      sync.Mutex
+}
+
+// NewConnection creates a connection which shares the upstream stream with all the other connections
+// created by this SharedClient for identical requests. See the SharedClient documentation for more info.
+func (s *SharedClient) NewConnection(r *http.Request) *Connection {
+	client := DefaultClient
+	if s.Client != nil {
+		client = s.Client
+	}
+
+	c := *client
+	c.HTTPClient = &http.Client{Transport: sharedTransport{s}}
+
+	return c.NewConnection(r)
+}
+
+func (s *SharedClient) key(r *http.Request) string {
+	sb := &strings.Builder{}
+	sb.WriteString(r.Method)
+	sb.WriteByte(' ')
+	sb.WriteString(r.URL.String())
+
+	for _, h := range s.KeyHeaders {
+		sb.WriteByte('\n')
+		sb.WriteString(http.CanonicalHeaderKey(h))
+		sb.WriteByte(':')
+		sb.WriteString(strings.Join(r.Header.Values(h), ","))
+	}
+
+	return sb.String()
+}
+
+func (s *SharedClient) join(r *http.Request, b *sharedBody) *sharedStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.streams == nil {
+		s.streams = map[string]*sharedStream{}
+	}
+
+	key := s.key(r)
+	stream := s.streams[key]
+
+	if stream == nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		stream = &sharedStream{
+			key:       key,
+			cancel:    cancel,
+			consumers: map[*sharedBody]struct{}{},
+		}
+		s.streams[key] = stream
+
+		upstream := r.Clone(ctx)
+		upstream.Header.Del("Last-Event-ID")
+
+		go s.run(stream, upstream)
+	}
+
+	for _, e := range stream.recent {
+		b.write(e)
+	}
+	stream.consumers[b] = struct{}{}
+
+	return stream
+}
+
+func (s *SharedClient) leave(stream *sharedStream, b *sharedBody) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(stream.consumers, b)
+	if len(stream.consumers) == 0 {
+		// Remove the stream right away, so upcoming connections don't join a closing stream.
+		s.remove(stream)
+		stream.cancel()
+	}
+}
+
+func (s *SharedClient) remove(stream *sharedStream) {
+	if s.streams[stream.key] == stream {
+		delete(s.streams, stream.key)
+	}
+}
+
+func (s *SharedClient) run(stream *sharedStream, r *http.Request) {
+	client := DefaultClient
+	if s.Client != nil {
+		client = s.Client
+	}
+
+	conn := client.NewConnection(r)
+	conn.SubscribeToAll(func(e Event) {
+		encoded := encodeEvent(e)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.ReplayCount > 0 {
+			if len(stream.recent) == s.ReplayCount {
+				stream.recent = stream.recent[1:]
+			}
+			stream.recent = append(stream.recent, encoded)
+		}
+
+		for b := range stream.consumers {
+			b.write(encoded)
+		}
+	})
+
+	err := conn.Connect()
+	if errors.Is(err, r.Context().Err()) {
+		err = io.EOF
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(stream)
+
+	for b := range stream.consumers {
+		b.closeWithError(err)
+	}
+}
+
+// encodeEvent encodes a received event so that when it is parsed again
+// the result is the same event, with the same last event ID.
+func encodeEvent(e Event) string {
+	sb := &strings.Builder{}
+	sb.WriteString("id: ")
+	sb.WriteString(e.LastEventID)
+	sb.WriteByte('\n')
+
+	if e.Stream != "" {
+		sb.WriteString("stream: ")
+		sb.WriteString(e.Stream)
+		sb.WriteByte('\n')
+	}
+
+	if e.TraceID != "" {
+		sb.WriteString("trace: ")
+		sb.WriteString(e.TraceID)
+		sb.WriteByte('\n')
+	}
+
+	if e.Type != "" {
+		sb.WriteString("event: ")
+		sb.WriteString(e.Type)
+		sb.WriteByte('\n')
+	}
+
+	for _, line := range strings.Split(e.Data, "\n") {
+		sb.WriteString("data: ")
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	sb.WriteByte('\n')
+
+	return sb.String()
+}
+
+type sharedStream struct {
+	key       string
+	cancel    context.CancelFunc
+	consumers map[*sharedBody]struct{}
+	recent    []string
+}
+
+type sharedTransport struct {
+	s *SharedClient
+}
+
+func (t sharedTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := r.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	b := newSharedBody()
+	stream := t.s.join(r, b)
+
+	go func() {
+		select {
+		case <-r.Context().Done():
+			b.closeWithError(r.Context().Err())
+		case <-b.done:
+		}
+
+		t.s.leave(stream, b)
+	}()
+
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{headerContentType: headerContentTypeValue},
+		Body:       b,
+		Request:    r,
+	}, nil
+}
+
+// sharedBody is a response body to which the shared stream writes without blocking.
+type sharedBody struct {
+	err  error
+	done chan struct{}
+	cond *sync.Cond
+	buf  bytes.Buffer
+}
+
+func newSharedBody() *sharedBody {
+	return &sharedBody{done: make(chan struct{}), cond: sync.NewCond(&sync.Mutex{})}
+}
+
+func (b *sharedBody) write(s string) {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	if b.err == nil {
+		b.buf.WriteString(s)
+		b.cond.Signal()
+	}
+}
+
+func (b *sharedBody) closeWithError(err error) {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	if b.err == nil {
+		b.err = err
+		close(b.done)
+		b.cond.Broadcast()
+	}
+}
+
+func (b *sharedBody) Read(p []byte) (int, error) {
+	b.cond.L.Lock()
+	defer b.cond.L.Unlock()
+
+	for b.buf.Len() == 0 && b.err == nil {
+		b.cond.Wait()
+	}
+
+	if b.buf.Len() > 0 {
+		return b.buf.Read(p)
+	}
+
+	return 0, b.err
+}
+
+func (b *sharedBody) Close() error {
+	b.closeWithError(errSharedBodyClosed)
+	return nil
+}
+
+var errSharedBodyClosed = errors.New("go-sse.client: shared response body closed")
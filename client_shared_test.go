@@ -0,0 +1,94 @@
+package sse_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestSharedClient(t *testing.T) {
+	var hits atomic.Int32
+	next := make(chan struct{})
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+
+		for i := 1; ; i++ {
+			select {
+			case <-next:
+				fmt.Fprintf(w, "id: %d\nevent: count\ndata: %d\n\n", i, i)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	s := &sse.SharedClient{
+		Client:      &sse.Client{HTTPClient: ts.Client()},
+		ReplayCount: 1,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	received := map[int][]sse.Event{}
+
+	// connect returns a channel which receives a value for each event the connection receives.
+	connect := func(i int) <-chan struct{} {
+		conn := s.NewConnection(reqCtx(t, ctx, "", ts.URL, nil))
+		ch := make(chan struct{}, 2)
+
+		conn.SubscribeToAll(func(e sse.Event) {
+			mu.Lock()
+			received[i] = append(received[i], e)
+			mu.Unlock()
+
+			ch <- struct{}{}
+		})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = conn.Connect()
+		}()
+
+		return ch
+	}
+
+	first := connect(0)
+	next <- struct{}{}
+	<-first
+
+	second := connect(1)
+	// The second connection receives the first event from the replay.
+	<-second
+
+	next <- struct{}{}
+	<-first
+	<-second
+
+	cancel()
+	wg.Wait()
+
+	expected := []sse.Event{
+		{LastEventID: "1", Type: "count", Data: "1"},
+		{LastEventID: "2", Type: "count", Data: "2"},
+	}
+
+	require.Equal(t, int32(1), hits.Load(), "upstream should be requested once")
+	require.Equal(t, expected, received[0], "invalid events for the first connection")
+	require.Equal(t, expected, received[1], "invalid events for the second connection")
+}
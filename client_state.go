@@ -0,0 +1,70 @@
+package sse
+
+// ConnectionState is the state of a Connection. Retrieve it using the Connection's State method.
+type ConnectionState string
+
+// The states of a Connection.
+const (
+	// Connect is not running. This is the state of new connections and of the ones for which
+	// Connect returned.
+	StateClosed ConnectionState = "closed"
+	// A connection attempt is being made.
+	StateConnecting ConnectionState = "connecting"
+	// The server accepted the connection and the events are being received.
+	StateOpen ConnectionState = "open"
+	// A connection attempt failed and the connection waits before reconnecting.
+	StateRetrying ConnectionState = "retrying"
+)
+
+// StateCallback is a function that is used to receive the state changes of a Connection.
+type StateCallback func(ConnectionState)
+
+// State returns the connection's current state. It is safe to call it concurrently.
+func (c *Connection) State() ConnectionState {
+	c.lifecycleMu.Lock()
+	defer c.lifecycleMu.Unlock()
+
+	return c.state
+}
+
+// OnStateChange subscribes the given callback to the state changes of the connection. It is called
+// with the new state in the goroutine which called Connect, in the same order as the event callbacks,
+// so the same restrictions apply to it. Remove the callback by calling the returned function.
+func (c *Connection) OnStateChange(cb StateCallback) EventCallbackRemover {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := c.callbackID
+	c.states[id] = cb
+	c.callbackID++
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.states, id)
+	}
+}
+
+// setState changes the connection's state and notifies the callbacks, if the state is different.
+func (c *Connection) setState(s ConnectionState) {
+	c.lifecycleMu.Lock()
+	changed := c.state != s
+	c.state = s
+	c.lifecycleMu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.client.ErrorReporter != nil {
+		defer reportPanic(c.request.Context(), c.client.ErrorReporter)
+	}
+
+	for _, cb := range c.states {
+		cb(s)
+	}
+}
@@ -0,0 +1,274 @@
+package sse
+
+import (
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// ConnectionStats holds statistics about a Connection. Retrieve them using the Connection's Stats method.
+type ConnectionStats struct {
+	// The distribution of the time spent disconnected, measured from the first failure
+	// until a response from the server passes validation again.
+	Downtime Histogram
+	// The following distributions describe where the time is spent for each received event.
+	// They help pinpoint whether lag comes from the network, the parser or your own callbacks.
+	// They are recorded only if the Client's ProfileEvents option is set.
+	//
+	// The distribution of the time spent waiting for the event's data to be read from the network.
+	// This includes the time the server didn't send anything.
+	Network Histogram
+	// The distribution of the time spent parsing the event, after its data was read.
+	Parse Histogram
+	// The distribution of the time spent dispatching the event to the callbacks.
+	Callbacks Histogram
+
+	// The number of callbacks currently subscribed. If it keeps growing,
+	// callbacks are subscribed without ever being removed.
+	ActiveSubscriptions int
+	// The number of callbacks subscribed and removed since the connection was created,
+	// which describe the subscription churn.
+	Subscribed, Unsubscribed uint64
+	// The statistics of each subscribed callback, in the order they were subscribed.
+	Subscriptions []SubscriptionStats
+
+	// The distribution of the time the heartbeats took to arrive from the server, corrected with the
+	// ClockOffset. It is recorded only if the server sends heartbeats. See HeartbeatEventType.
+	Transit Histogram
+	// The estimated offset of the server's clock from the client's: a positive offset means the server's
+	// clock is ahead. It is estimated only if the Client's EchoURL is set. See Connection.LocalTime.
+	ClockOffset time.Duration
+	// The duration of the round trip to the echo endpoint the ClockOffset was estimated from.
+	// The offset is accurate within half of it. It is 0 if the offset wasn't estimated yet.
+	ClockRoundTrip time.Duration
+}
+
+// SubscriptionStats holds statistics about a callback subscribed to a Connection.
+type SubscriptionStats struct {
+	// When the callback was subscribed. Old subscriptions which are not expected to live long are leaks.
+	Since time.Time
+	// The type of the events the callback is subscribed to. It is empty if All is set.
+	EventType string
+	// The distribution of the time the callback takes to process an event. Its count is the number
+	// of events the callback received. It is recorded only if the Client's ProfileEvents option is set.
+	Latency Histogram
+	// Whether the callback is subscribed to all events.
+	All bool
+}
+
+// Histogram is a snapshot of a distribution of durations.
+// Its buckets have exponentially increasing upper bounds: each bound is double the previous one.
+type Histogram struct {
+	// The inclusive upper bounds of the buckets. Values bigger than the last bound
+	// are counted in an additional bucket.
+	Bounds []time.Duration
+	// The number of observations in each bucket. It has one more element than Bounds.
+	Counts []uint64
+	// The total number of observations.
+	Count uint64
+	// The sum of all observed durations.
+	Sum time.Duration
+}
+
+const histogramBounds = 21
+
+// histogram is an exponential histogram whose first bucket holds durations up to base.
+// The zero value is not usable, create it with a base first.
+type histogram struct {
+	counts [histogramBounds + 1]uint64
+	count  uint64
+	sum    time.Duration
+	base   time.Duration
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	if d > h.base {
+		// ceil(log2(d / base))
+		i = bits.Len64(uint64((d - 1) / h.base))
+		if i > histogramBounds {
+			i = histogramBounds
+		}
+	}
+
+	h.counts[i]++
+	h.count++
+	h.sum += d
+}
+
+func (h *histogram) snapshot() Histogram {
+	bounds := make([]time.Duration, histogramBounds)
+	for i := range bounds {
+		bounds[i] = h.base << i
+	}
+
+	return Histogram{
+		Bounds: bounds,
+		Counts: append([]uint64(nil), h.counts[:]...),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+}
+
+// connectionStats holds the internal state of ConnectionStats.
+type connectionStats struct {
+	downtime     histogram
+	network      histogram
+	parse        histogram
+	callbacks    histogram
+	subscribed   uint64
+	unsubscribed uint64
+	clock        clockSync
+}
+
+// subscriptionStats holds the internal state of SubscriptionStats. It is guarded by the connection's statsMu.
+type subscriptionStats struct {
+	since     time.Time
+	eventType string
+	latency   histogram
+	id        int
+	all       bool
+}
+
+func newConnectionStats() connectionStats {
+	return connectionStats{
+		downtime:  histogram{base: time.Millisecond},
+		network:   histogram{base: time.Microsecond},
+		parse:     histogram{base: time.Microsecond},
+		callbacks: histogram{base: time.Microsecond},
+		clock:     clockSync{transit: histogram{base: time.Millisecond}},
+	}
+}
+
+// Stats returns a snapshot of the connection's statistics. It is safe to call concurrently with Connect.
+func (c *Connection) Stats() ConnectionStats {
+	// The subscriptions are collected before locking the stats, as dispatch
+	// locks the stats while holding the callbacks' lock.
+	subs := c.subscriptionStats()
+
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	st := ConnectionStats{
+		Downtime:            c.stats.downtime.snapshot(),
+		Network:             c.stats.network.snapshot(),
+		Parse:               c.stats.parse.snapshot(),
+		Callbacks:           c.stats.callbacks.snapshot(),
+		ActiveSubscriptions: int(c.stats.subscribed - c.stats.unsubscribed),
+		Subscribed:          c.stats.subscribed,
+		Unsubscribed:        c.stats.unsubscribed,
+		Subscriptions:       make([]SubscriptionStats, 0, len(subs)),
+		Transit:             c.stats.clock.transit.snapshot(),
+	}
+
+	if est, ok := c.stats.clock.estimate(); ok {
+		st.ClockOffset, st.ClockRoundTrip = est.offset, est.roundTrip
+	}
+
+	for _, s := range subs {
+		st.Subscriptions = append(st.Subscriptions, SubscriptionStats{
+			Since:     s.since,
+			EventType: s.eventType,
+			Latency:   s.latency.snapshot(),
+			All:       s.all,
+		})
+	}
+
+	return st
+}
+
+func (c *Connection) subscriptionStats() []*subscriptionStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var subs []*subscriptionStats
+	for _, cbs := range c.callbacks {
+		for _, cb := range cbs {
+			subs = append(subs, cb.stats)
+		}
+	}
+	for _, cb := range c.callbacksAll {
+		subs = append(subs, cb.stats)
+	}
+
+	sort.Slice(subs, func(i, j int) bool { return subs[i].id < subs[j].id })
+
+	return subs
+}
+
+// subscribed records a new subscription. The callbacks must be locked.
+func (c *Connection) subscribed(id int, eventType string, all bool) *subscriptionStats {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.stats.subscribed++
+
+	return &subscriptionStats{
+		since:     time.Now(),
+		eventType: eventType,
+		latency:   histogram{base: time.Microsecond},
+		id:        id,
+		all:       all,
+	}
+}
+
+// unsubscribed records a removed subscription. The callbacks must be locked.
+func (c *Connection) unsubscribed() {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.stats.unsubscribed++
+}
+
+// call calls the callback with the event, measuring its latency if events are profiled.
+func (c *Connection) call(cb callback, ev Event) {
+	if !c.client.ProfileEvents || cb.stats == nil {
+		cb.fn(ev)
+		return
+	}
+
+	start := time.Now()
+	cb.fn(ev)
+	d := time.Since(start)
+
+	c.statsMu.Lock()
+	cb.stats.latency.observe(d)
+	c.statsMu.Unlock()
+}
+
+func (c *Connection) observeDowntime(d time.Duration) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	c.stats.downtime.observe(d)
+}
+
+// eventProfiler measures the phases of receiving an event. It is used only from the goroutine Connect is called in.
+type eventProfiler struct {
+	start   time.Time
+	network time.Duration
+}
+
+// reset starts measuring the next event.
+func (p *eventProfiler) reset() {
+	p.start = time.Now()
+	p.network = 0
+}
+
+func (c *Connection) dispatchProfiled(ev Event) {
+	p := &c.profiler
+	received := time.Now()
+	c.dispatch(ev)
+	dispatched := time.Now()
+
+	network := p.network
+	parse := received.Sub(p.start) - network
+
+	c.statsMu.Lock()
+	c.stats.network.observe(network)
+	c.stats.parse.observe(parse)
+	c.stats.callbacks.observe(dispatched.Sub(received))
+	c.statsMu.Unlock()
+
+	p.reset()
+}
@@ -0,0 +1,172 @@
+package sse_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestConnection_Stats_downtime(t *testing.T) {
+	attempt := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempt++
+		if attempt != 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = io.WriteString(w, "data: hello\n\n")
+	}))
+	defer ts.Close()
+
+	c := &sse.Client{
+		HTTPClient:              ts.Client(),
+		MaxRetries:              3,
+		DefaultReconnectionTime: time.Millisecond,
+	}
+	conn := c.NewConnection(req(t, "", ts.URL, nil))
+
+	stats := conn.Stats()
+	require.Zero(t, stats.Downtime.Count, "no downtime should be recorded before connecting")
+	require.Len(t, stats.Downtime.Counts, len(stats.Downtime.Bounds)+1, "invalid bucket count")
+
+	err := conn.Connect()
+	require.True(t, errors.As(err, new(*sse.ConnectionError)), "unexpected Connect error")
+
+	stats = conn.Stats()
+	require.Equal(t, uint64(1), stats.Downtime.Count, "only the first reconnection should be recorded")
+	require.Positive(t, stats.Downtime.Sum, "downtime should be recorded")
+
+	var total uint64
+	for i, n := range stats.Downtime.Counts {
+		total += n
+		if n != 0 && i < len(stats.Downtime.Bounds) {
+			require.LessOrEqual(t, stats.Downtime.Sum, stats.Downtime.Bounds[i], "observation in wrong bucket")
+		}
+	}
+	require.Equal(t, stats.Downtime.Count, total, "bucket counts don't add up")
+	require.Equal(t, time.Millisecond, stats.Downtime.Bounds[0], "invalid first bound")
+	require.Equal(t, 2*time.Millisecond, stats.Downtime.Bounds[1], "bounds should grow exponentially")
+}
+
+func TestConnection_Stats_profile(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "data: a\n\ndata: b\n\ndata: c\n\n")
+	}))
+	defer ts.Close()
+
+	for _, profile := range []bool{false, true} {
+		c := &sse.Client{
+			HTTPClient:        ts.Client(),
+			ResponseValidator: sse.NoopValidator,
+			ProfileEvents:     profile,
+		}
+		conn := c.NewConnection(req(t, "", ts.URL, nil))
+		conn.SubscribeToAll(func(sse.Event) { time.Sleep(time.Millisecond) })
+
+		require.ErrorIs(t, conn.Connect(), io.EOF, "unexpected Connect error")
+
+		stats := conn.Stats()
+		if !profile {
+			require.Zero(t, stats.Callbacks.Count, "events should not be profiled by default")
+			continue
+		}
+
+		require.Equal(t, uint64(3), stats.Network.Count, "invalid network observations")
+		require.Equal(t, uint64(3), stats.Parse.Count, "invalid parse observations")
+		require.Equal(t, uint64(3), stats.Callbacks.Count, "invalid callback observations")
+		require.GreaterOrEqual(t, stats.Callbacks.Sum, 3*time.Millisecond, "callback time not measured")
+		require.Less(t, stats.Parse.Sum, stats.Callbacks.Sum, "callback time measured as parse time")
+	}
+}
+
+func TestConnection_Stats_subscriptions(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "event: a\ndata: 1\n\nevent: b\ndata: 2\n\n")
+	}))
+	defer ts.Close()
+
+	c := &sse.Client{
+		HTTPClient:        ts.Client(),
+		ResponseValidator: sse.NoopValidator,
+		ProfileEvents:     true,
+	}
+	conn := c.NewConnection(req(t, "", ts.URL, nil))
+
+	before := time.Now()
+	removeA := conn.SubscribeEvent("a", func(sse.Event) {})
+	conn.SubscribeToAll(func(sse.Event) {})
+	removeB := conn.SubscribeEvent("b", func(sse.Event) {})
+	removeB()
+	removeB()
+
+	require.ErrorIs(t, conn.Connect(), io.EOF, "unexpected Connect error")
+
+	stats := conn.Stats()
+	require.Equal(t, 2, stats.ActiveSubscriptions, "invalid active subscriptions")
+	require.Equal(t, uint64(3), stats.Subscribed, "invalid subscription count")
+	require.Equal(t, uint64(1), stats.Unsubscribed, "removing twice should count once")
+	require.Len(t, stats.Subscriptions, 2, "invalid subscriptions")
+
+	a, all := stats.Subscriptions[0], stats.Subscriptions[1]
+	require.Equal(t, "a", a.EventType, "subscriptions should be ordered")
+	require.False(t, a.All, "subscription to a should not be to all events")
+	require.False(t, a.Since.Before(before), "invalid subscription time")
+	require.Equal(t, uint64(1), a.Latency.Count, "subscription to a should receive one event")
+	require.True(t, all.All, "second subscription should be to all events")
+	require.Equal(t, uint64(2), all.Latency.Count, "subscription to all should receive both events")
+
+	removeA()
+	stats = conn.Stats()
+	require.Equal(t, 1, stats.ActiveSubscriptions, "invalid active subscriptions after removal")
+	require.Equal(t, uint64(2), stats.Unsubscribed, "invalid unsubscription count")
+}
+
+func TestConnection_Stats_clock(t *testing.T) {
+	const ahead = time.Hour
+
+	serverTime := func() string { return time.Now().Add(ahead).Format(time.RFC3339Nano) }
+
+	synced := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `{"time":"`+serverTime()+`"}`)
+	})
+	mux.HandleFunc("/events", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "event: heartbeat\ndata: {\"time\":\""+serverTime()+"\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-synced
+		_, _ = io.WriteString(w, "event: heartbeat\ndata: {\"time\":\""+serverTime()+"\"}\n\n")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c := &sse.Client{
+		HTTPClient:        ts.Client(),
+		ResponseValidator: sse.NoopValidator,
+		EchoURL:           ts.URL + "/echo",
+	}
+	conn := c.NewConnection(req(t, "", ts.URL+"/events", nil))
+
+	done := make(chan error, 1)
+	go func() { done <- conn.Connect() }()
+
+	// The round trip to the echo endpoint is done in the background.
+	require.Eventually(t, func() bool { return conn.Stats().ClockRoundTrip > 0 }, time.Second*5, time.Millisecond, "clock offset not estimated")
+	close(synced)
+	require.ErrorIs(t, <-done, io.EOF, "unexpected Connect error")
+
+	stats := conn.Stats()
+	require.InDelta(t, ahead, stats.ClockOffset, float64(time.Second), "invalid clock offset")
+	require.Equal(t, uint64(2), stats.Transit.Count, "transit not recorded for each heartbeat")
+
+	local := time.Now()
+	require.WithinDuration(t, local, conn.LocalTime(local.Add(ahead)), time.Second, "server time not corrected")
+}
@@ -0,0 +1,53 @@
+package sse
+
+// Stream is a logical stream of a connection. Servers and gateways can multiplex multiple
+// logical streams over a single connection, so clients stay under the browsers' connection limits,
+// by marking each event with a stream field:
+//
+//	stream: prices
+//	data: {"BTC": 42000}
+//
+// Events without a stream field are part of the unnamed stream "". Stream fields are not part
+// of the spec, so browsers ignore them and dispatch the events as usual.
+//
+// The callbacks subscribed directly to a Connection receive the events of all streams – use
+// the Event's Stream field to distinguish them. The callbacks subscribed to a Stream receive only
+// the events of that stream. They are called in the same goroutine and order as the connection's
+// callbacks, so the same restrictions apply to them.
+type Stream struct {
+	conn *Connection
+	name string
+}
+
+// Stream returns the logical stream with the given name. See the Stream type for more info.
+func (c *Connection) Stream(name string) Stream {
+	return Stream{conn: c, name: name}
+}
+
+// Name returns the stream's name.
+func (s Stream) Name() string {
+	return s.name
+}
+
+// SubscribeMessages subscribes the given callback to all events of the stream without type.
+// Remove the callback by calling the returned function.
+func (s Stream) SubscribeMessages(cb EventCallback) EventCallbackRemover {
+	return s.SubscribeEvent("", cb)
+}
+
+// SubscribeEvent subscribes the given callback to all events of the stream with the provided type.
+// Remove the callback by calling the returned function.
+func (s Stream) SubscribeEvent(typ string, cb EventCallback) EventCallbackRemover {
+	return s.conn.addSubscriber(typ, s.callback(cb))
+}
+
+// SubscribeToAll subscribes the given callback to all events of the stream, with or without type.
+// Remove the callback by calling the returned function.
+func (s Stream) SubscribeToAll(cb EventCallback) EventCallbackRemover {
+	return s.conn.addSubscriberToAll(s.callback(cb))
+}
+
+func (s Stream) callback(cb EventCallback) callback {
+	name := s.name
+	return callback{fn: cb, stream: &name, priority: PriorityNormal}
+}
@@ -0,0 +1,44 @@
+package sse
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// ErrReadTimeout is wrapped by the errors of the connection attempts which failed
+// because nothing was received for the Client's ReadTimeout.
+var ErrReadTimeout = errors.New("go-sse.client: nothing received within the read timeout")
+
+// timeoutReader restarts the read timeout each time something is read.
+type timeoutReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (t timeoutReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		t.timer.Reset(t.timeout)
+	}
+
+	return n, err
+}
+
+// withReadTimeout returns a reader which calls cancel if nothing is read for the ReadTimeout, a function
+// which reports whether that happened and one which stops the timeout.
+func (c *Client) withReadTimeout(r io.Reader, cancel func()) (_ io.Reader, timedOut func() bool, stop func()) {
+	if c.ReadTimeout <= 0 {
+		return r, func() bool { return false }, func() {}
+	}
+
+	var expired atomic.Bool
+	t := time.AfterFunc(c.ReadTimeout, func() {
+		expired.Store(true)
+		cancel()
+	})
+
+	return timeoutReader{r: r, timer: t, timeout: c.ReadTimeout}, expired.Load, func() { t.Stop() }
+}
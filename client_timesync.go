@@ -0,0 +1,116 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// clockSamples is the number of recent round trips to the echo endpoint used to estimate the clock offset.
+const clockSamples = 8
+
+type clockSample struct {
+	offset, roundTrip time.Duration
+}
+
+// clockSync holds the state of the clock synchronization. It is guarded by the connection's statsMu.
+type clockSync struct {
+	samples [clockSamples]clockSample
+	count   int
+	next    int
+	transit histogram
+}
+
+// estimate returns the offset of the sample with the shortest round trip, which is the most accurate.
+func (s *clockSync) estimate() (clockSample, bool) {
+	if s.count == 0 {
+		return clockSample{}, false
+	}
+
+	best := s.samples[0]
+	for _, sample := range s.samples[1:s.count] {
+		if sample.roundTrip < best.roundTrip {
+			best = sample
+		}
+	}
+
+	return best, true
+}
+
+func (s *clockSync) add(sample clockSample) {
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % clockSamples
+	if s.count < clockSamples {
+		s.count++
+	}
+}
+
+// LocalTime converts a time read from the server's clock, such as an event's timestamp, to the
+// client's clock, using the offset estimated with the Client's EchoURL. The time is returned
+// unchanged if no offset was estimated yet.
+func (c *Connection) LocalTime(serverTime time.Time) time.Time {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+
+	est, _ := c.stats.clock.estimate()
+
+	return serverTime.Add(-est.offset)
+}
+
+// observeHeartbeat records the transit latency of a heartbeat received at the given time and,
+// if an echo endpoint is configured and no round trip is in flight, starts measuring the clock offset.
+func (c *Connection) observeHeartbeat(data string, received time.Time) {
+	var hb heartbeat
+	if err := json.Unmarshal([]byte(strings.TrimSuffix(data, "\n")), &hb); err != nil || hb.Time.IsZero() {
+		return
+	}
+
+	c.statsMu.Lock()
+	est, _ := c.stats.clock.estimate()
+	transit := received.Sub(hb.Time.Add(-est.offset))
+	if transit < 0 {
+		transit = 0
+	}
+	c.stats.clock.transit.observe(transit)
+	c.statsMu.Unlock()
+
+	if c.client.EchoURL != "" && c.echoing.CompareAndSwap(false, true) {
+		// The round trip is stopped together with the connection.
+		ctx := c.request.Context()
+		go func() {
+			defer c.echoing.Store(false)
+			c.echo(ctx)
+		}()
+	}
+}
+
+// echo measures a round trip to the echo endpoint and records the estimated clock offset.
+func (c *Connection) echo(ctx context.Context) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.client.EchoURL, http.NoBody)
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	res, err := c.client.HTTPClient.Do(req)
+	if err != nil {
+		return
+	}
+	defer res.Body.Close()
+
+	var hb heartbeat
+	if err := json.NewDecoder(res.Body).Decode(&hb); err != nil || hb.Time.IsZero() {
+		return
+	}
+	end := time.Now()
+
+	roundTrip := end.Sub(start)
+	// The server's time is assumed to be read halfway through the round trip.
+	offset := hb.Time.Sub(start.Add(roundTrip / 2))
+
+	c.statsMu.Lock()
+	c.stats.clock.add(clockSample{offset: offset, roundTrip: roundTrip})
+	c.statsMu.Unlock()
+}
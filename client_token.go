@@ -0,0 +1,147 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// TokenSource provides the tokens with which connections authenticate. The token is sent
+// as a bearer token in the Authorization header on each connection attempt.
+type TokenSource interface {
+	// Token returns the token to use for the next connection attempt.
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc is a function which implements TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token calls the function.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// TokenRefreshEventType is the type of the events with which servers push new tokens to clients.
+// The event's data is the new token. Pushing short-lived tokens lets servers keep long-lived
+// authenticated streams open, without clients having to refresh the tokens on their side.
+const TokenRefreshEventType = "token-refresh"
+
+// A TokenRefresher is a TokenSource which accepts tokens pushed by servers. If the Client's TokenSource
+// is a TokenRefresher, the tokens received in events with the type TokenRefreshEventType are given to it
+// and used on the subsequent reconnection attempts. These events are not dispatched to callbacks.
+type TokenRefresher interface {
+	TokenSource
+	// RefreshToken is called with the token received from the server.
+	RefreshToken(token string)
+}
+
+// A TokenRejecter is a TokenSource which is told when the server rejects its tokens. If the Client's
+// TokenSource is a TokenRejecter, the token of each connection attempt whose response has the status
+// 401 Unauthorized or 403 Forbidden is given to it, so it can provide another one on the next attempt.
+type TokenRejecter interface {
+	TokenSource
+	// RejectToken is called with the token rejected by the server.
+	RejectToken(token string)
+}
+
+// RefreshableTokenSource is a TokenRefresher which returns the most recent token pushed by the server,
+// or the token from its Source, until a token is pushed. If the server rejects the pushed token,
+// for example because it expired while the client was disconnected, the Source is used again
+// until another token is pushed. The zero value is ready to use. It is safe for concurrent use.
+type RefreshableTokenSource struct {
+	// The source of the tokens used before a token is pushed by the server.
+	// If it is nil, no token is returned until the server pushes one.
+	Source TokenSource
+
+	token string
+	mu    sync.Mutex
+}
+
+// Token returns the most recent token pushed by the server, or the token from the Source.
+func (r *RefreshableTokenSource) Token(ctx context.Context) (string, error) {
+	r.mu.Lock()
+	token := r.token
+	r.mu.Unlock()
+
+	if token != "" || r.Source == nil {
+		return token, nil
+	}
+
+	return r.Source.Token(ctx)
+}
+
+// RefreshToken sets the token returned by subsequent calls to Token.
+func (r *RefreshableTokenSource) RefreshToken(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.token = token
+}
+
+// RejectToken discards the pushed token, if it is the rejected one, so that the token
+// from the Source is used instead.
+func (r *RefreshableTokenSource) RejectToken(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.token == token {
+		r.token = ""
+	}
+}
+
+func (c *Connection) setToken() error {
+	if c.client.TokenSource == nil {
+		return nil
+	}
+
+	token, err := c.client.TokenSource.Token(c.request.Context())
+	if err != nil {
+		return err
+	}
+
+	c.token = token
+	if token == "" {
+		c.request.Header.Del("Authorization")
+	} else {
+		c.request.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return nil
+}
+
+// rejectToken tells the TokenSource, if possible, that the server rejected the token
+// sent with the last connection attempt.
+func (c *Connection) rejectToken(res *http.Response) {
+	if res.StatusCode != http.StatusUnauthorized && res.StatusCode != http.StatusForbidden {
+		return
+	}
+
+	if r, ok := c.client.TokenSource.(TokenRejecter); ok && c.token != "" {
+		r.RejectToken(c.token)
+	}
+}
+
+// refreshToken gives the token from the event to the TokenSource, if possible.
+// It returns true if the event shouldn't be dispatched.
+func (c *Connection) refreshToken(ev Event) bool {
+	if ev.Type != TokenRefreshEventType {
+		return false
+	}
+
+	r, ok := c.client.TokenSource.(TokenRefresher)
+	if !ok {
+		return false
+	}
+
+	// The event's data has a trailing newline.
+	token := ev.Data
+	if l := len(token); l > 0 {
+		token = token[:l-1]
+	}
+	if token != "" {
+		// The data may be pooled, so the token is copied to be retained.
+		r.RefreshToken(cloneString(token))
+	}
+
+	return true
+}
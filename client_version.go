@@ -0,0 +1,41 @@
+package sse
+
+import "sync"
+
+// SubscribeEventVersions subscribes the given callback to the given versions of an event type,
+// for example to "order.created.v1" and "order.created.v2" with the type "order.created" and the
+// versions "v1" and "v2". Use SplitEventVersion on the received event's type to find out its version.
+//
+// A server which publishes a message under multiple versions sends them with the same ID
+// (see Server.EventVersions), so the callback receives only the first version of each message
+// it handles: consecutive events of these versions with the same LastEventID are considered
+// versions of the same message. If no event has an ID, all of them are received.
+// Remove the callback by calling the returned function.
+func (c *Connection) SubscribeEventVersions(typ string, versions []string, cb EventCallback) EventCallbackRemover {
+	var (
+		mu     sync.Mutex
+		lastID string
+	)
+
+	once := func(e Event) {
+		mu.Lock()
+		duplicate := e.LastEventID != "" && e.LastEventID == lastID
+		lastID = e.LastEventID
+		mu.Unlock()
+
+		if !duplicate {
+			cb(e)
+		}
+	}
+
+	removers := make([]EventCallbackRemover, 0, len(versions))
+	for _, v := range versions {
+		removers = append(removers, c.SubscribeEvent(typ+"."+v, once))
+	}
+
+	return func() {
+		for _, remove := range removers {
+			remove()
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CloseEventType is the type of the final message the Server sends to the sessions it closes, if
+// Server.CloseEvents is enabled. Its data is a JSON object with the reason the session was closed and a human-readable message:
+//
+//	{"reason":"server-shutdown","message":"The server is shutting down."}
+//
+// Clients return the reason in a *ClosedError – see Connection.Connect.
+const CloseEventType = "close"
+
+// CloseReason describes why a session was closed, so disconnects can be told apart on both ends.
+type CloseReason string
+
+// The reasons for which sessions are closed.
+const (
+	// The client closed the connection, or released the session using a beacon.
+	CloseClientCancel CloseReason = "client-cancel"
+	// The server is shutting down, or its provider ended the session.
+	CloseServerShutdown CloseReason = "server-shutdown"
+	// The session was inactive for too long. The Server doesn't close sessions for this reason
+	// by itself – use it with Server.CloseSessions.
+	CloseIdleTimeout CloseReason = "idle-timeout"
+	// The client didn't keep up with the stream. See Server.WriteTimeout.
+	CloseSlowConsumer CloseReason = "slow-consumer"
+	// The client's authorization was revoked. The Server doesn't close sessions for this reason
+	// by itself – use it with Server.CloseSessions.
+	CloseAuthRevoked CloseReason = "auth-revoked"
+	// The session exceeded its quota. See Server.Quota.
+	CloseQuota CloseReason = "quota"
+	// The session reached its maximum age. See Server.MaxSessionAge.
+	CloseMaxAge CloseReason = "max-age"
+	// All the session's topics were retired. See Server.RetireTopic.
+	CloseRetired CloseReason = "retired"
+	// An error occurred while sending messages to the session.
+	CloseError CloseReason = "error"
+)
+
+var closeMessages = map[CloseReason]string{
+	CloseServerShutdown: "The server is shutting down.",
+	CloseIdleTimeout:    "The stream was inactive for too long.",
+	CloseAuthRevoked:    "The authorization for this stream was revoked.",
+	CloseQuota:          "The quota for this stream was exceeded.",
+	CloseMaxAge:         "The stream reached its maximum age. Reconnect to continue receiving events.",
+	CloseRetired:        "The topics of this stream were retired.",
+	CloseError:          "An error occurred while sending events.",
+}
+
+// A ClosedError is the error with which a connection is lost after the server sent a message
+// with the type CloseEventType, which tells why the server closed it.
+type ClosedError struct {
+	// Why the server closed the connection.
+	Reason CloseReason `json:"reason"`
+	// A human-readable description of the reason.
+	Message string `json:"message,omitempty"`
+}
+
+func (e *ClosedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("closed by server: %s", e.Reason)
+	}
+	return fmt.Sprintf("closed by server: %s: %s", e.Reason, e.Message)
+}
+
+func closeMessage(reason CloseReason) *Message {
+	data, _ := json.Marshal(ClosedError{Reason: reason, Message: closeMessages[reason]})
+
+	m := &Message{Type: Type(CloseEventType)}
+	m.AppendData(string(data))
+
+	return m
+}
+
+// observeClose records the reason the server sent before closing the connection.
+// It is called from the goroutine which reads the stream.
+func (c *Connection) observeClose(data string) {
+	// Event data has a trailing newline here, which JSON ignores.
+	closed := &ClosedError{}
+	if err := json.Unmarshal([]byte(data), closed); err != nil || closed.Reason == "" {
+		return
+	}
+
+	c.closedBy = closed
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+const diffUsage = "Usage: sse diff [--window duration] [--header 'Name: value'] [--hook plugin.so] URL1 URL2\n"
+
+type headerFlags http.Header
+
+func (h headerFlags) String() string { return "" }
+
+func (h headerFlags) Set(v string) error {
+	name, value, ok := strings.Cut(v, ":")
+	if !ok {
+		return fmt.Errorf("invalid header %q, expected 'Name: value'", v)
+	}
+
+	http.Header(h).Add(strings.TrimSpace(name), strings.TrimSpace(value))
+
+	return nil
+}
+
+func runDiff(args []string) error {
+	fset := flag.NewFlagSet("diff", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprint(fset.Output(), diffUsage+"\nSubscribes to both streams and reports the events, identified by their IDs, which are missing\nfrom the second stream, which are extra in it, which arrive in a different order or whose content\ndiffers. The exit status is non-zero if the streams diverge.\n\n")
+		fset.PrintDefaults()
+	}
+	window := fset.Duration("window", time.Minute, "For how long both streams are received")
+	headers := headerFlags{}
+	fset.Var(headers, "header", "A header sent to both servers, e.g. 'Authorization: Bearer token'. Can be repeated")
+	var hookPaths hookFlags
+	fset.Var(&hookPaths, "hook", "A Go plugin exporting an event hook named Hook, which filters or transforms the events\nof both streams before they are compared. Can be repeated")
+
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+	if fset.NArg() != 2 {
+		fmt.Fprint(os.Stderr, diffUsage)
+		return errUsage
+	}
+
+	hooks, err := hookPaths.load()
+	if err != nil {
+		return fmt.Errorf("load hook: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *window)
+	defer cancel()
+
+	urls := [2]string{fset.Arg(0), fset.Arg(1)}
+	var (
+		streams [2][]diffEvent
+		errs    [2]error
+		wg      sync.WaitGroup
+	)
+	for i := range urls {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			streams[i], errs[i] = receive(ctx, urls[i], http.Header(headers), hooks)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("%s: %w", urls[i], err)
+		}
+	}
+
+	report := diffStreams(streams[0], streams[1])
+	report.print(os.Stdout, urls)
+	if report.diverges() {
+		return errors.New("the streams diverge")
+	}
+
+	return nil
+}
+
+type diffEvent struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// receive collects the events of the stream until the context is done.
+func receive(ctx context.Context, url string, header http.Header, hooks []sse.EventHook) ([]diffEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	client := &sse.Client{
+		EventHooks: hooks,
+		MaxRetries: -1,
+		OnRetry: func(err error, d time.Duration) {
+			fmt.Fprintf(os.Stderr, "sse: %s: %v, reconnecting in %s\n", url, err, d)
+		},
+	}
+
+	var (
+		events []diffEvent
+		lastID string
+	)
+	conn := client.NewConnection(req)
+	conn.SubscribeToAll(func(e sse.Event) {
+		// Events without an ID field have the ID of the previous event.
+		id := e.LastEventID
+		if id == lastID {
+			id = ""
+		}
+		lastID = e.LastEventID
+
+		events = append(events, diffEvent{ID: id, Type: e.Type, Data: e.Data})
+	})
+
+	if err := conn.Connect(); err != nil && !errors.Is(err, ctx.Err()) {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+type diffReport struct {
+	Missing    []string
+	Extra      []string
+	OutOfOrder []string
+	Changed    []string
+	// The number of events without ID, which can't be compared, received from each stream.
+	Unidentified [2]int
+}
+
+func (r diffReport) diverges() bool {
+	return len(r.Missing)+len(r.Extra)+len(r.OutOfOrder)+len(r.Changed) > 0
+}
+
+func (r diffReport) print(w io.Writer, urls [2]string) {
+	section := func(title string, ids []string) {
+		if len(ids) > 0 {
+			fmt.Fprintf(w, "%s (%d): %s\n", title, len(ids), strings.Join(ids, ", "))
+		}
+	}
+
+	section("Missing from "+urls[1], r.Missing)
+	section("Extra in "+urls[1], r.Extra)
+	section("Out of order", r.OutOfOrder)
+	section("Different type or data", r.Changed)
+
+	for i, n := range r.Unidentified {
+		if n > 0 {
+			fmt.Fprintf(w, "Events without ID from %s, not compared: %d\n", urls[i], n)
+		}
+	}
+
+	if !r.diverges() {
+		fmt.Fprintln(w, "The streams are identical.")
+	}
+}
+
+// diffStreams compares the events received from two streams by their IDs. The events of the second stream
+// which are out of order are the ones not part of the longest sequence of events received in the same order
+// as in the first stream.
+func diffStreams(a, b []diffEvent) diffReport {
+	var r diffReport
+
+	index := func(events []diffEvent, unidentified *int) (map[string]int, []string) {
+		positions := map[string]int{}
+		var ids []string
+		for _, e := range events {
+			if e.ID == "" {
+				*unidentified++
+				continue
+			}
+			if _, ok := positions[e.ID]; !ok {
+				positions[e.ID] = len(ids)
+				ids = append(ids, e.ID)
+			}
+		}
+		return positions, ids
+	}
+
+	posA, idsA := index(a, &r.Unidentified[0])
+	posB, idsB := index(b, &r.Unidentified[1])
+
+	byID := func(events []diffEvent) map[string]diffEvent {
+		m := map[string]diffEvent{}
+		for _, e := range events {
+			if _, ok := m[e.ID]; !ok && e.ID != "" {
+				m[e.ID] = e
+			}
+		}
+		return m
+	}
+	eventsA, eventsB := byID(a), byID(b)
+
+	for _, id := range idsA {
+		if _, ok := posB[id]; !ok {
+			r.Missing = append(r.Missing, id)
+		} else if eventsA[id] != eventsB[id] {
+			r.Changed = append(r.Changed, id)
+		}
+	}
+
+	// The positions in the first stream of the common events, in the order of the second stream.
+	var common []int
+	var commonIDs []string
+	for _, id := range idsB {
+		if p, ok := posA[id]; ok {
+			common = append(common, p)
+			commonIDs = append(commonIDs, id)
+		} else {
+			r.Extra = append(r.Extra, id)
+		}
+	}
+
+	inOrder := longestIncreasing(common)
+	for i, id := range commonIDs {
+		if !inOrder[i] {
+			r.OutOfOrder = append(r.OutOfOrder, id)
+		}
+	}
+
+	return r
+}
+
+// longestIncreasing marks the elements which are part of a longest strictly increasing subsequence.
+func longestIncreasing(s []int) []bool {
+	// tails[k] is the index of the smallest tail of an increasing subsequence of length k+1.
+	var tails []int
+	prev := make([]int, len(s))
+
+	for i, v := range s {
+		k := sort.Search(len(tails), func(j int) bool { return s[tails[j]] >= v })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+
+	marked := make([]bool, len(s))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			marked[i] = true
+		}
+	}
+
+	return marked
+}
@@ -0,0 +1,79 @@
+// This is a chat server: messages sent to /send are broadcast to everyone listening on /events.
+// The last 100 messages are replayed to users who reconnect, so nothing is lost on flaky networks.
+// Each user can send 30 messages a minute, and /metrics reports how much everyone sent and why
+// the sessions were closed.
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+//go:embed index.html
+var page []byte
+
+func main() {
+	s := &sse.Server{
+		Provider: &sse.Joe{
+			ReplayProvider: &sse.FiniteReplayProvider{Count: 100, AutoIDs: true},
+		},
+		PublishQuota:       func(string) int64 { return 30 },
+		PublishQuotaPeriod: time.Minute,
+		// Users on a bad network are disconnected instead of delaying the messages of everyone else.
+		WriteTimeout: time.Second * 5,
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/events", s)
+	mux.HandleFunc("/send", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "use POST", http.StatusMethodNotAllowed)
+			return
+		}
+
+		name, text := strings.TrimSpace(r.FormValue("name")), strings.TrimSpace(r.FormValue("message"))
+		if name == "" || text == "" {
+			http.Error(w, "name and message are required", http.StatusBadRequest)
+			return
+		}
+
+		m := &sse.Message{Type: sse.Type("chat")}
+		m.AppendData(name + ": " + text)
+
+		// The messages are attributed to their sender, who is limited by the PublishQuota.
+		if err := s.PublishContext(sse.ContextWithPublisher(r.Context(), name), m); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, sse.ErrPublishQuotaExceeded) {
+				status = http.StatusTooManyRequests
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"publishers":    s.PublisherStats(),
+			"close_reasons": s.CloseReasons(),
+			"slow_sessions": s.SlowSessions(),
+		})
+	})
+	mux.Handle("/health", s.HealthHandler())
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	})
+
+	srv := &http.Server{Addr: ":8080", Handler: mux, ReadHeaderTimeout: time.Second * 10}
+	log.Println("chat running on http://localhost:8080")
+	log.Fatalln(srv.ListenAndServe())
+}
@@ -0,0 +1,65 @@
+// This is a live dashboard: metrics are sampled every second and pushed to all the viewers.
+// Samples from the last minute are replayed to viewers that reconnect, so their charts have no gaps.
+// The dashboard's own health is reported at /metrics: how many samples wait to be sent and why
+// the viewers' sessions were closed.
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+//go:embed index.html
+var page []byte
+
+var metrics = []string{"cpu", "memory", "requests"}
+
+func main() {
+	joe := &sse.Joe{
+		ReplayProvider:   &sse.ValidReplayProvider{TTL: time.Minute, AutoIDs: true},
+		ReplayGCInterval: time.Second * 10,
+	}
+	s := &sse.Server{
+		Provider: joe,
+		// Viewers on a bad network are disconnected instead of delaying the samples of everyone else.
+		WriteTimeout: time.Second * 5,
+	}
+
+	go func() {
+		for range time.Tick(time.Second) {
+			for _, metric := range metrics {
+				m := &sse.Message{Type: sse.Type(metric)}
+				m.AppendData(strconv.Itoa(rand.Intn(100)))
+
+				_ = s.Publish(m)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle("/events", s)
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"queue_depth":   joe.QueueDepth(sse.DefaultTopic),
+			"close_reasons": s.CloseReasons(),
+			"slow_sessions": s.SlowSessions(),
+		})
+	})
+	mux.Handle("/health", s.HealthHandler())
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	})
+
+	srv := &http.Server{Addr: ":8080", Handler: mux, ReadHeaderTimeout: time.Second * 10}
+	log.Println("dashboard running on http://localhost:8080")
+	log.Fatalln(srv.ListenAndServe())
+}
@@ -0,0 +1,131 @@
+// This is a proxy in front of a language model, which streams the generated tokens to the caller
+// as soon as they are produced, like the popular LLM APIs do. The model's API streams the tokens
+// as server-sent events, which the proxy receives with a client and forwards. Each request gets
+// its own stream, so the session is used directly instead of publishing through a provider.
+// /metrics reports the completions served and the latencies of the last one's upstream connection.
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+//go:embed index.html
+var page []byte
+
+// model is a stand-in for the model's API: it echoes the prompt back, word by word.
+// Point modelURL to your model of choice instead.
+func model(w http.ResponseWriter, r *http.Request) {
+	sess, err := sse.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	for _, word := range strings.Fields("You asked: " + r.URL.Query().Get("prompt")) {
+		time.Sleep(time.Millisecond * 150)
+
+		m := &sse.Message{}
+		m.AppendData(word + " ")
+		if sess.Send(m) != nil || sess.Flush() != nil {
+			return
+		}
+	}
+
+	done := &sse.Message{Type: sse.Type("done")}
+	done.AppendData("[DONE]")
+
+	_ = sess.Send(done)
+	_ = sess.Flush()
+}
+
+const modelURL = "http://localhost:8080/model"
+
+type proxy struct {
+	client *sse.Client
+
+	mu          sync.Mutex
+	completions int
+	last        sse.ConnectionStats
+}
+
+func (p *proxy) complete(w http.ResponseWriter, r *http.Request) {
+	prompt := r.URL.Query().Get("prompt")
+	if prompt == "" {
+		http.Error(w, "prompt is required", http.StatusBadRequest)
+		return
+	}
+
+	sess, err := sse.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	upstream, err := http.NewRequestWithContext(r.Context(), http.MethodGet, modelURL+"?"+url.Values{"prompt": {prompt}}.Encode(), http.NoBody)
+	if err != nil {
+		log.Println("completion failed:", err)
+		return
+	}
+
+	conn := p.client.NewConnection(upstream)
+	conn.SubscribeToAll(func(e sse.Event) {
+		m := &sse.Message{}
+		if e.Type != "" {
+			m.Type = sse.Type(e.Type)
+		}
+		m.AppendData(e.Data)
+
+		_ = sess.Send(m)
+		_ = sess.Flush()
+	})
+
+	// The model ends the stream after it is done, and the client doesn't reconnect.
+	if err := conn.Connect(); !errors.Is(err, io.EOF) && !errors.Is(err, context.Canceled) {
+		log.Println("completion failed:", err)
+	}
+
+	p.mu.Lock()
+	p.completions++
+	p.last = conn.Stats()
+	p.mu.Unlock()
+}
+
+func (p *proxy) metrics(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"completions": p.completions,
+		"upstream":    p.last,
+	})
+}
+
+func main() {
+	p := &proxy{client: sse.DefaultClient}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/model", model)
+	mux.HandleFunc("/complete", p.complete)
+	mux.HandleFunc("/metrics", p.metrics)
+	mux.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(page)
+	})
+
+	srv := &http.Server{Addr: ":8080", Handler: mux, ReadHeaderTimeout: time.Second * 10}
+	log.Println("llm-proxy running on http://localhost:8080")
+	log.Fatalln(srv.ListenAndServe())
+}
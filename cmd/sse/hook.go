@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"plugin"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+// hookFlags are the paths of the Go plugins from which the event hooks are loaded.
+type hookFlags []string
+
+func (h *hookFlags) String() string { return "" }
+
+func (h *hookFlags) Set(v string) error {
+	*h = append(*h, v)
+	return nil
+}
+
+// load loads the event hooks from the plugins, in order.
+func (h hookFlags) load() ([]sse.EventHook, error) {
+	hooks := make([]sse.EventHook, 0, len(h))
+	for _, path := range h {
+		hook, err := loadHook(path)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, nil
+}
+
+// loadHook loads the event hook exported by a Go plugin as Hook: either a variable which implements
+// sse.EventHook, or a function with the signature of sse.EventHookFunc. The plugin must be built with
+// the same versions of Go and go-sse as this tool:
+//
+//	go build -buildmode=plugin -o hook.so ./hook
+func loadHook(path string) (sse.EventHook, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	sym, err := p.Lookup("Hook")
+	if err != nil {
+		return nil, err
+	}
+
+	switch h := sym.(type) {
+	case *sse.EventHook:
+		return *h, nil
+	case func(sse.Event) (sse.Event, bool):
+		return sse.EventHookFunc(h), nil
+	case sse.EventHook:
+		return h, nil
+	default:
+		return nil, fmt.Errorf("%s: Hook has type %T, which is not an event hook", path, sym)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// The examples are regular programs, so they are built and vetted together
+// with the rest of the module. They are copied as they are when scaffolding.
+//
+//go:embed examples
+var examples embed.FS
+
+// exampleTypes maps the names accepted by the --type flag to the directories of the examples.
+var exampleTypes = map[string]string{
+	"chat":      "chat",
+	"dashboard": "dashboard",
+	"llm-proxy": "llmproxy",
+}
+
+func exampleTypeNames() string {
+	names := make([]string, 0, len(exampleTypes))
+	for name := range exampleTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return strings.Join(names, "|")
+}
+
+func runInit(args []string) error {
+	if len(args) == 0 || args[0] != "example" {
+		fmt.Fprintf(os.Stderr, "Usage: sse init example --type %s [--dir path]\n", exampleTypeNames())
+		return errUsage
+	}
+
+	fset := flag.NewFlagSet("init example", flag.ContinueOnError)
+	typ := fset.String("type", "", "The kind of example to create: "+exampleTypeNames())
+	dir := fset.String("dir", "", "The directory in which the example is created. Defaults to the example's type.")
+	force := fset.Bool("force", false, "Overwrite existing files")
+
+	if err := fset.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return errUsage
+	}
+
+	src, ok := exampleTypes[*typ]
+	if !ok {
+		return fmt.Errorf("unknown example type %q, expected one of %s", *typ, exampleTypeNames())
+	}
+
+	if *dir == "" {
+		*dir = *typ
+	}
+
+	if err := scaffold(*dir, "examples/"+src, *force); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stdout, "Created the %s example in %s. Run it with:\n\n\tcd %s\n\tgo mod init example\n\t%s\n\tgo run .\n", *typ, *dir, *dir, dependencyCommand())
+
+	return nil
+}
+
+// modulePath is the path of the module the examples depend on.
+const modulePath = "github.com/tmaxmax/go-sse"
+
+// dependencyCommand returns the command which makes an example depend on the version of go-sse
+// this tool was installed at, or on the latest version if it was built from a checkout.
+func dependencyCommand() string {
+	version := "latest"
+	if info, ok := debug.ReadBuildInfo(); ok && strings.HasPrefix(info.Main.Version, "v") && !strings.HasSuffix(info.Main.Version, "+dirty") {
+		version = info.Main.Version
+	}
+
+	return fmt.Sprintf("go get %s@%s", modulePath, version)
+}
+
+func scaffold(dst, src string, force bool) error {
+	return fs.WalkDir(examples, src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, filepath.FromSlash(strings.TrimPrefix(path, src)))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+
+		if !force {
+			if _, err := os.Stat(target); err == nil {
+				return fmt.Errorf("file %s already exists, use --force to overwrite it", target)
+			}
+		}
+
+		content, err := examples.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		return os.WriteFile(target, content, 0o644) //nolint:gosec // The examples aren't secret.
+	})
+}
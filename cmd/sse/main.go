@@ -0,0 +1,58 @@
+// Command sse is a collection of tools for working with server-sent events.
+//
+// Usage:
+//
+//	sse <command> [arguments]
+//
+// The commands are:
+//
+//	init example    scaffold a runnable example application
+//	diff            compare the events of two streams
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const usage = `Usage: sse <command> [arguments]
+
+The commands are:
+
+	init example    scaffold a runnable example application
+	diff            compare the events of two streams
+
+Run "sse <command> -h" for more information about a command.
+`
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "sse:", err)
+		}
+		os.Exit(2)
+	}
+}
+
+var errUsage = errors.New("invalid usage")
+
+func run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "init":
+		return runInit(args[1:])
+	case "diff":
+		return runDiff(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "sse: unknown command %q\n\n%s", args[0], usage)
+		return errUsage
+	}
+}
@@ -0,0 +1,61 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// A Codec encodes and decodes the JSON payloads of events. Set the Client's Codec to decode payloads
+// with a faster JSON library than encoding/json, such as jsoniter, segmentio/encoding or easyjson,
+// when decoding dominates the CPU usage of consumers of high-rate streams. See Event.DecodePayload.
+// Publishers encode payloads with a Codec using Message.AppendJSON.
+//
+// To avoid copying it, the data given to Unmarshal shares its memory with the event's data,
+// so Unmarshal must neither modify nor retain it. Codecs must be safe for concurrent use.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the Codec which uses encoding/json. It is used when no other Codec is set.
+type JSONCodec struct{}
+
+// Marshal calls json.Marshal.
+func (JSONCodec) Marshal(v any) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal calls json.Unmarshal.
+func (JSONCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// AppendJSON appends the given value encoded as JSON to the message's data, using the given Codec,
+// or JSONCodec if it is nil. The payload can be decoded by clients using Event.DecodePayload:
+//
+//	m := &sse.Message{Type: sse.Type("order")}
+//	if err := m.AppendJSON(order, nil); err != nil {
+//		// handle error
+//	}
+//	_ = s.Publish(m)
+func (e *Message) AppendJSON(v any, codec Codec) error {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	e.AppendData(string(data))
+
+	return nil
+}
+
+// stringBytes returns the bytes of the string without copying them. They must not be modified.
+func stringBytes(s string) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer((*reflect.StringHeader)(unsafe.Pointer(&s)).Data)), len(s))
+}
+
+// The buffers used to convert MessagePack payloads to JSON.
+var payloadBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
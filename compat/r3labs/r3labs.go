@@ -0,0 +1,334 @@
+// Package r3labs mirrors the client API of github.com/r3labs/sse/v2 on top of go-sse, so consumers
+// can be migrated incrementally: replace the import path, keep the code which uses the client,
+// and rewrite it to use go-sse directly afterwards, one consumer at a time.
+//
+// The Client's fields, options and methods behave like their counterparts, with these differences:
+// the events' Retry and Comment are always empty, the ID of an event is the last event ID received,
+// as in go-sse, and the retry values sent by the server are ignored, as r3labs/sse does.
+package r3labs
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/tmaxmax/go-sse"
+)
+
+// Event is an event received from the server.
+type Event struct {
+	// The last event ID received. See sse.Event.LastEventID.
+	ID []byte
+	// The event's data. It is decoded if the Client's EncodingBase64 is set.
+	Data []byte
+	// The event's type.
+	Event []byte
+	// Always empty: retry values are handled by the underlying go-sse connection.
+	Retry []byte
+	// Always empty: comments are not dispatched by go-sse.
+	Comment []byte
+}
+
+// ConnCallback is called when the Client connects to or disconnects from the server.
+type ConnCallback func(c *Client)
+
+// ResponseValidator checks the server's response before events are read from it. If it returns
+// an error, the connection is reattempted.
+type ResponseValidator func(c *Client, resp *http.Response) error
+
+// Client subscribes to the event streams of a server. Create it using NewClient.
+type Client struct {
+	// The strategy which determines how long to wait before each reconnection attempt.
+	// Defaults to an exponential backoff, created for each subscription.
+	ReconnectStrategy backoff.BackOff
+	// Called whenever a reconnection attempt starts.
+	ReconnectNotify backoff.Notify
+	// Checks the server's responses. Defaults to checking that the status code is 200 OK.
+	ResponseValidator ResponseValidator
+	// The HTTP client used. Defaults to http.DefaultClient.
+	Connection *http.Client
+	// The headers sent with each request.
+	Headers map[string]string
+	// The URL of the server.
+	URL string
+	// The ID of the last event received, as a []byte, which is sent to the server on reconnections
+	// and by subsequent subscriptions.
+	LastEventID atomic.Value
+	// EncodingBase64 makes the client decode the events' data from base64.
+	EncodingBase64 bool
+
+	mu           sync.Mutex
+	subscribed   map[chan *Event]context.CancelFunc
+	connectedcb  ConnCallback
+	disconnectcb ConnCallback
+}
+
+// NewClient creates a client for the server at the given URL, configured using the given options.
+func NewClient(url string, opts ...func(c *Client)) *Client {
+	c := &Client{
+		URL:        url,
+		Connection: http.DefaultClient,
+		Headers:    map[string]string{},
+		subscribed: map[chan *Event]context.CancelFunc{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Subscribe subscribes the handler to the given stream and blocks until the connection ends.
+// The stream is sent in the "stream" query parameter. If it is empty, no parameter is sent.
+func (c *Client) Subscribe(stream string, handler func(msg *Event)) error {
+	return c.SubscribeWithContext(context.Background(), stream, handler)
+}
+
+// SubscribeWithContext is the same as Subscribe, but it returns nil when the given context is done.
+func (c *Client) SubscribeWithContext(ctx context.Context, stream string, handler func(msg *Event)) error {
+	conn, err := c.connection(ctx, stream)
+	if err != nil {
+		return err
+	}
+
+	conn.SubscribeToAll(func(e sse.Event) {
+		if ev, ok := c.event(e); ok {
+			handler(ev)
+		}
+	})
+
+	if err := conn.Connect(); err != nil && ctx.Err() == nil {
+		return err
+	}
+
+	return nil
+}
+
+// SubscribeRaw is the same as Subscribe, without a stream.
+func (c *Client) SubscribeRaw(handler func(msg *Event)) error {
+	return c.Subscribe("", handler)
+}
+
+// SubscribeRawWithContext is the same as SubscribeWithContext, without a stream.
+func (c *Client) SubscribeRawWithContext(ctx context.Context, handler func(msg *Event)) error {
+	return c.SubscribeWithContext(ctx, "", handler)
+}
+
+// SubscribeChan sends the events of the given stream to the channel. It returns once the client
+// connected to the server, or with the error with which the connection failed.
+// The events are sent until Unsubscribe is called with the channel, which is not closed.
+func (c *Client) SubscribeChan(stream string, ch chan *Event) error {
+	return c.SubscribeChanWithContext(context.Background(), stream, ch)
+}
+
+// SubscribeChanWithContext is the same as SubscribeChan, but the events are sent only until the given context is done.
+func (c *Client) SubscribeChanWithContext(ctx context.Context, stream string, ch chan *Event) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	conn, err := c.connection(ctx, stream)
+	if err != nil {
+		cancel()
+		return err
+	}
+
+	c.mu.Lock()
+	if c.subscribed == nil {
+		c.subscribed = map[chan *Event]context.CancelFunc{}
+	}
+	c.subscribed[ch] = cancel
+	c.mu.Unlock()
+
+	started := make(chan error, 1)
+	var once sync.Once
+	signal := func(err error) { once.Do(func() { started <- err }) }
+
+	conn.SubscribeToAllWithLifecycle(func(e sse.Event) {
+		if e.Lifecycle == sse.LifecycleConnected {
+			signal(nil)
+			return
+		}
+
+		if ev, ok := c.event(e); ok {
+			select {
+			case ch <- ev:
+			case <-ctx.Done():
+			}
+		}
+	})
+
+	go func() {
+		err := conn.Connect()
+		if err == nil || ctx.Err() != nil {
+			err = errors.New("go-sse.r3labs: subscription ended before connecting")
+		}
+		signal(err)
+		c.Unsubscribe(ch)
+	}()
+
+	if err := <-started; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SubscribeChanRaw is the same as SubscribeChan, without a stream.
+func (c *Client) SubscribeChanRaw(ch chan *Event) error {
+	return c.SubscribeChan("", ch)
+}
+
+// SubscribeChanRawWithContext is the same as SubscribeChanWithContext, without a stream.
+func (c *Client) SubscribeChanRawWithContext(ctx context.Context, ch chan *Event) error {
+	return c.SubscribeChanWithContext(ctx, "", ch)
+}
+
+// Unsubscribe stops sending events to the given channel and closes its connection.
+func (c *Client) Unsubscribe(ch chan *Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.subscribed[ch]; ok {
+		cancel()
+		delete(c.subscribed, ch)
+	}
+}
+
+// OnConnect sets the function called when the client connects to the server.
+func (c *Client) OnConnect(fn ConnCallback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.connectedcb = fn
+}
+
+// OnDisconnect sets the function called when the client's connection to the server is lost.
+func (c *Client) OnDisconnect(fn ConnCallback) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.disconnectcb = fn
+}
+
+// connection creates the go-sse connection of a subscription.
+func (c *Client) connection(ctx context.Context, stream string) (*sse.Connection, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	if stream != "" {
+		query := req.URL.Query()
+		query.Set("stream", stream)
+		req.URL.RawQuery = query.Encode()
+	}
+	for name, value := range c.Headers {
+		req.Header.Set(name, value)
+	}
+
+	client := &sse.Client{
+		HTTPClient:        c.Connection,
+		OnRetry:           c.ReconnectNotify,
+		ResponseValidator: c.validate,
+		IgnoreServerRetry: true,
+		Backoff:           c.backoff,
+	}
+
+	conn := client.NewConnection(req)
+	if id, _ := c.LastEventID.Load().([]byte); len(id) > 0 {
+		conn.StartFromEventID(string(id))
+	}
+
+	connected := false
+	conn.SubscribeToAllWithLifecycle(func(e sse.Event) {
+		switch e.Lifecycle {
+		case sse.LifecycleConnected:
+			connected = true
+			c.notify(true)
+		case sse.LifecycleReconnecting, sse.LifecycleClosed:
+			if connected {
+				connected = false
+				c.notify(false)
+			}
+		case sse.LifecycleGapDetected:
+		default:
+			if e.LastEventID != "" {
+				c.LastEventID.Store([]byte(e.LastEventID))
+			}
+		}
+	})
+
+	return conn, nil
+}
+
+// notify calls the OnConnect or the OnDisconnect callback.
+func (c *Client) notify(connected bool) {
+	c.mu.Lock()
+	cb := c.disconnectcb
+	if connected {
+		cb = c.connectedcb
+	}
+	c.mu.Unlock()
+
+	if cb != nil {
+		cb(c)
+	}
+}
+
+func (c *Client) event(e sse.Event) (*Event, bool) {
+	if e.Lifecycle != "" {
+		return nil, false
+	}
+
+	ev := &Event{ID: []byte(e.LastEventID), Event: []byte(e.Type), Data: []byte(e.Data)}
+	if c.EncodingBase64 {
+		data := make([]byte, base64.StdEncoding.DecodedLen(len(ev.Data)))
+		n, err := base64.StdEncoding.Decode(data, ev.Data)
+		if err != nil {
+			return nil, false
+		}
+		ev.Data = data[:n]
+	}
+
+	return ev, true
+}
+
+// temporaryError makes the go-sse connection retry the errors of the ResponseValidator.
+type temporaryError struct{ error }
+
+func (temporaryError) Temporary() bool { return true }
+
+func (e temporaryError) Unwrap() error { return e.error }
+
+func (c *Client) validate(res *http.Response) error {
+	var err error
+	if c.ResponseValidator != nil {
+		err = c.ResponseValidator(c, res)
+	} else if res.StatusCode != http.StatusOK {
+		err = fmt.Errorf("could not connect to stream: %s", http.StatusText(res.StatusCode))
+	}
+
+	if err != nil {
+		return temporaryError{err}
+	}
+
+	return nil
+}
+
+// strategy adapts the ReconnectStrategy to go-sse.
+type strategy struct{ backoff.BackOff }
+
+func (strategy) SetRetry(time.Duration) {}
+
+func (c *Client) backoff() sse.BackoffStrategy {
+	if c.ReconnectStrategy != nil {
+		return strategy{c.ReconnectStrategy}
+	}
+
+	return strategy{backoff.NewExponentialBackOff()}
+}
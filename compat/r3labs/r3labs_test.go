@@ -0,0 +1,99 @@
+package r3labs_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse/compat/r3labs"
+)
+
+func TestClient_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	var streams []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		streams = append(streams, r.URL.Query().Get("stream"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = fmt.Fprintf(w, "id: 1\nevent: greeting\ndata: %s\n\n", base64.StdEncoding.EncodeToString([]byte("hello")))
+	}))
+	defer ts.Close()
+
+	c := r3labs.NewClient(ts.URL, func(c *r3labs.Client) { c.EncodingBase64 = true })
+	connects, disconnects := 0, 0
+	c.OnConnect(func(*r3labs.Client) { connects++ })
+	c.OnDisconnect(func(*r3labs.Client) { disconnects++ })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var events []*r3labs.Event
+	err := c.SubscribeWithContext(ctx, "messages", func(e *r3labs.Event) {
+		events = append(events, e)
+		cancel()
+	})
+	require.NoError(t, err, "subscription should end without error when the context is done")
+
+	require.Equal(t, []*r3labs.Event{{ID: []byte("1"), Event: []byte("greeting"), Data: []byte("hello")}}, events)
+	require.Equal(t, []string{"messages"}, streams, "stream should be sent as a query parameter")
+	require.Equal(t, []byte("1"), c.LastEventID.Load(), "last event ID should be stored")
+	require.Equal(t, 1, connects, "unexpected number of connects")
+	require.Equal(t, 1, disconnects, "unexpected number of disconnects")
+}
+
+func TestClient_SubscribeChan(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("stream") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, "data: %d\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond * 5):
+			}
+		}
+	}))
+	defer ts.Close()
+
+	c := r3labs.NewClient(ts.URL)
+
+	ch := make(chan *r3labs.Event)
+	require.NoError(t, c.SubscribeChanRaw(ch), "unexpected subscription error")
+	require.Equal(t, []byte("0"), (<-ch).Data, "unexpected first event")
+	require.Equal(t, []byte("1"), (<-ch).Data, "unexpected second event")
+
+	c.Unsubscribe(ch)
+	select {
+	case <-ch:
+	case <-time.After(time.Millisecond * 50):
+	}
+	select {
+	case e := <-ch:
+		t.Fatalf("unexpected event after unsubscribing: %s", e.Data)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	c.ReconnectStrategy = &backoffStop{}
+	require.Error(t, c.SubscribeChan("missing", make(chan *r3labs.Event)), "failed connection should be returned")
+}
+
+type backoffStop struct{}
+
+func (backoffStop) NextBackOff() time.Duration { return -1 }
+func (backoffStop) Reset()                     {}
@@ -0,0 +1,141 @@
+package sse
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DictionaryCompressor compresses event payloads using a preset dictionary, which is shared
+// out-of-band by the server and its clients. This is effective for high volumes of small, similar
+// events – JSON objects with the same keys, for example – which otherwise don't compress well on their own.
+//
+// Payloads are compressed using DEFLATE and then base64 encoded, as event streams must be text.
+// The server compresses the payloads using AppendData or Compress, and the clients decompress them using
+// Decompress with the same dictionary. The dictionary should contain strings that are likely to appear
+// in the payloads, with the most common ones at the end.
+//
+// The payloads are raw DEFLATE streams, not Zstandard frames, so the clients must decompress them
+// with a DictionaryCompressor or another DEFLATE implementation which supports preset dictionaries.
+// The dictionary isn't trained, it is chosen by hand. For Zstandard compression with trained
+// dictionaries see the github.com/tmaxmax/go-sse/ssezstd module.
+//
+// A DictionaryCompressor is safe for concurrent use. It must not be copied after first use.
+type DictionaryCompressor struct {
+	writers sync.Pool
+	// The preset dictionary. It must be the same on the server and on the clients,
+	// and it must not be modified after the compressor is used.
+	Dictionary []byte
+	// The DEFLATE compression level, as defined by the compress/flate package.
+	// If it is nil, flate.BestCompression is used – event payloads are usually small,
+	// so the best compression is cheap.
+	Level *int
+
+	uncompressed atomic.Uint64
+	compressed   atomic.Uint64
+}
+
+// CompressionStats holds the number of bytes that were processed by a compressor.
+type CompressionStats struct {
+	// The total size of the payloads, before compression.
+	Uncompressed uint64
+	// The total size of the payloads, after compression and encoding.
+	Compressed uint64
+}
+
+// Ratio returns the compression ratio – how many times smaller the compressed payloads are.
+// It returns 0 if nothing was compressed yet.
+func (c CompressionStats) Ratio() float64 {
+	if c.Compressed == 0 {
+		return 0
+	}
+	return float64(c.Uncompressed) / float64(c.Compressed)
+}
+
+// Compress compresses the payload and returns its textual representation.
+// The error is non-nil only if the compression level is invalid.
+func (d *DictionaryCompressor) Compress(payload string) (string, error) {
+	w, err := d.getWriter()
+	if err != nil {
+		return "", err
+	}
+	defer d.writers.Put(w)
+
+	buf := &bytes.Buffer{}
+	w.w.Reset(buf)
+	// Writes to a bytes.Buffer never fail.
+	_, _ = writeString(w.w, payload)
+	_ = w.w.Close()
+
+	encoded := base64.RawStdEncoding.EncodeToString(buf.Bytes())
+
+	d.uncompressed.Add(uint64(len(payload)))
+	d.compressed.Add(uint64(len(encoded)))
+
+	return encoded, nil
+}
+
+// AppendData compresses the payload and appends it to the message as a data field.
+func (d *DictionaryCompressor) AppendData(m *Message, payload string) error {
+	compressed, err := d.Compress(payload)
+	if err != nil {
+		return err
+	}
+
+	m.AppendData(compressed)
+
+	return nil
+}
+
+// Decompress decodes and decompresses a payload created by Compress. Use it on the client
+// with the data of the received events.
+func (d *DictionaryCompressor) Decompress(data string) (string, error) {
+	compressed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	r := flate.NewReaderDict(bytes.NewReader(compressed), d.Dictionary)
+	defer r.Close()
+
+	sb := &strings.Builder{}
+	if _, err := io.Copy(sb, r); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// Stats returns the statistics of the payloads compressed so far.
+func (d *DictionaryCompressor) Stats() CompressionStats {
+	return CompressionStats{
+		Uncompressed: d.uncompressed.Load(),
+		Compressed:   d.compressed.Load(),
+	}
+}
+
+type flateWriter struct {
+	w *flate.Writer
+}
+
+func (d *DictionaryCompressor) getWriter() (*flateWriter, error) {
+	if w, ok := d.writers.Get().(*flateWriter); ok {
+		return w, nil
+	}
+
+	level := flate.BestCompression
+	if d.Level != nil {
+		level = *d.Level
+	}
+
+	w, err := flate.NewWriterDict(nil, level, d.Dictionary)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flateWriter{w}, nil
+}
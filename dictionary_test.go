@@ -0,0 +1,56 @@
+package sse_test
+
+import (
+	"compress/flate"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestDictionaryCompressor(t *testing.T) {
+	t.Parallel()
+
+	dict := []byte(`{"user":"","action":"","timestamp":`)
+	server := &sse.DictionaryCompressor{Dictionary: dict}
+	client := &sse.DictionaryCompressor{Dictionary: dict}
+
+	payload := `{"user":"john","action":"login","timestamp":1700000000}`
+
+	m := &sse.Message{}
+	require.NoError(t, server.AppendData(m, payload), "unexpected compression error")
+
+	ev := toEv(t, m.String())
+	require.False(t, strings.ContainsAny(ev.Data, "\r\n"), "compressed payload must be a single line")
+
+	decompressed, err := client.Decompress(ev.Data)
+	require.NoError(t, err, "unexpected decompression error")
+	require.Equal(t, payload, decompressed, "payload altered")
+
+	stats := server.Stats()
+	require.Equal(t, uint64(len(payload)), stats.Uncompressed, "invalid uncompressed size")
+	require.Equal(t, uint64(len(ev.Data)), stats.Compressed, "invalid compressed size")
+
+	withoutDict, err := (&sse.DictionaryCompressor{}).Compress(payload)
+	require.NoError(t, err, "unexpected compression error")
+	require.Less(t, len(ev.Data), len(withoutDict), "dictionary should improve compression")
+	require.InDelta(t, float64(len(payload))/float64(len(ev.Data)), stats.Ratio(), 1e-9, "invalid ratio")
+	require.Zero(t, client.Stats().Ratio(), "nothing was compressed by the client")
+
+	_, err = (&sse.DictionaryCompressor{Dictionary: []byte("other")}).Decompress(ev.Data)
+	require.Error(t, err, "decompressing with another dictionary should fail")
+
+	invalid := 42
+	_, err = (&sse.DictionaryCompressor{Level: &invalid}).Compress(payload)
+	require.Error(t, err, "invalid level should fail")
+
+	none := flate.NoCompression
+	stored, err := (&sse.DictionaryCompressor{Dictionary: dict, Level: &none}).Compress(payload)
+	require.NoError(t, err, "unexpected compression error")
+	require.Greater(t, len(stored), len(ev.Data), "no compression should be used")
+
+	decompressed, err = client.Decompress(stored)
+	require.NoError(t, err, "unexpected decompression error")
+	require.Equal(t, payload, decompressed, "payload altered")
+}
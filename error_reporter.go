@@ -0,0 +1,63 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// An ErrorReporter receives the errors which the library can't return to you, such as recovered
+// panics, failed writes to sessions and connection errors which are retried. Use it to make these
+// incidents surface in your error tracking service. Both the Client and the Server accept one.
+//
+// ReportError must be safe for concurrent use. The context is the one of the request
+// during which the error occurred.
+type ErrorReporter interface {
+	ReportError(ctx context.Context, err error)
+}
+
+// ErrorReporterFunc is a function that implements the ErrorReporter interface.
+type ErrorReporterFunc func(ctx context.Context, err error)
+
+// ReportError calls the function itself.
+func (f ErrorReporterFunc) ReportError(ctx context.Context, err error) {
+	f(ctx, err)
+}
+
+// CaptureErrors creates an ErrorReporter from a function which captures an error, such as the ones
+// provided by the clients of error tracking services. The value it returns is discarded.
+// For example, with Sentry:
+//
+//	reporter := sse.CaptureErrors(sentry.CaptureException)
+func CaptureErrors[T any](capture func(error) T) ErrorReporter {
+	return ErrorReporterFunc(func(_ context.Context, err error) {
+		capture(err)
+	})
+}
+
+// PanicError is the error reported to an ErrorReporter when a panic is recovered.
+type PanicError struct {
+	// The value the panic was called with.
+	Value any
+	// The stack trace of the goroutine which panicked.
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap returns the value the panic was called with, if it is an error.
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// reportPanic reports a panic, if there is one, and then panics again with the same value,
+// so the behavior is not changed. It must be deferred directly.
+func reportPanic(ctx context.Context, reporter ErrorReporter) {
+	if r := recover(); r != nil {
+		reporter.ReportError(ctx, &PanicError{Value: r, Stack: debug.Stack()})
+		panic(r)
+	}
+}
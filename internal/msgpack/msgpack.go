@@ -0,0 +1,292 @@
+// Package msgpack implements the subset of MessagePack needed to represent JSON values.
+package msgpack
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// Append appends the MessagePack encoding of v to b. The value must be a JSON value,
+// as decoded by encoding/json into an interface{}: nil, bool, float64, json.Number,
+// string, []any or map[string]any. Integers are also accepted.
+// Map keys are encoded in sorted order, so the output is deterministic.
+func Append(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0), nil
+	case bool:
+		if v {
+			return append(b, 0xc3), nil
+		}
+		return append(b, 0xc2), nil
+	case int:
+		return appendInt(b, int64(v)), nil
+	case int64:
+		return appendInt(b, v), nil
+	case float64:
+		if i := int64(v); float64(i) == v && v >= math.MinInt64 && v <= math.MaxInt64 {
+			return appendInt(b, i), nil
+		}
+		return appendFloat(b, v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendInt(b, i), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return appendFloat(b, f), nil
+	case string:
+		return appendString(b, v), nil
+	case []any:
+		b = appendLength(b, len(v), 0x90, 0xdc)
+		for _, e := range v {
+			var err error
+			if b, err = Append(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = appendLength(b, len(v), 0x80, 0xde)
+		for _, k := range keys {
+			b = appendString(b, k)
+
+			var err error
+			if b, err = Append(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type %T", v)
+	}
+}
+
+func appendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8 && i <= math.MaxInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16 && i <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(f))
+}
+
+func appendString(b []byte, s string) []byte {
+	switch l := len(s); {
+	case l <= 31:
+		b = append(b, 0xa0|byte(l))
+	case l <= math.MaxUint8:
+		b = append(b, 0xd9, byte(l))
+	case l <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(l))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(l))
+	}
+
+	return append(b, s...)
+}
+
+// appendLength appends the header of an array or map with the given fix type and 16-bit type.
+// The 32-bit type always follows the 16-bit one.
+func appendLength(b []byte, l int, fix, typ16 byte) []byte {
+	switch {
+	case l <= 15:
+		return append(b, fix|byte(l))
+	case l <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, typ16), uint16(l))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, typ16+1), uint32(l))
+	}
+}
+
+// ErrShortInput is returned when the input ends before the value is complete.
+var ErrShortInput = errors.New("msgpack: unexpected end of input")
+
+// Decode decodes a single MessagePack value into the same types encoding/json uses
+// for an interface{}, except that integers are decoded as int64.
+// Binary values are decoded as strings, and extension values are not supported.
+func Decode(b []byte) (any, error) {
+	v, rest, err := decode(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes", len(rest))
+	}
+
+	return v, nil
+}
+
+func decode(b []byte) (v any, rest []byte, err error) {
+	if len(b) == 0 {
+		return nil, nil, ErrShortInput
+	}
+
+	t, b := b[0], b[1:]
+
+	switch {
+	case t <= 0x7f:
+		return int64(t), b, nil
+	case t >= 0xe0:
+		return int64(int8(t)), b, nil
+	case t&0xe0 == 0xa0:
+		return decodeString(b, int(t&0x1f))
+	case t&0xf0 == 0x90:
+		return decodeArray(b, int(t&0x0f))
+	case t&0xf0 == 0x80:
+		return decodeMap(b, int(t&0x0f))
+	}
+
+	switch t {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2:
+		return false, b, nil
+	case 0xc3:
+		return true, b, nil
+	case 0xca:
+		n, b, err := uintN(b, 4)
+		return float64(math.Float32frombits(uint32(n))), b, err
+	case 0xcb:
+		n, b, err := uintN(b, 8)
+		return math.Float64frombits(n), b, err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, b, err := uintN(b, 1<<(t-0xcc))
+		if err == nil && n > math.MaxInt64 {
+			return float64(n), b, nil
+		}
+		return int64(n), b, err
+	case 0xd0:
+		n, b, err := uintN(b, 1)
+		return int64(int8(n)), b, err
+	case 0xd1:
+		n, b, err := uintN(b, 2)
+		return int64(int16(n)), b, err
+	case 0xd2:
+		n, b, err := uintN(b, 4)
+		return int64(int32(n)), b, err
+	case 0xd3:
+		n, b, err := uintN(b, 8)
+		return int64(n), b, err
+	case 0xc4, 0xc5, 0xc6, 0xd9, 0xda, 0xdb:
+		size := 1
+		switch t {
+		case 0xc5, 0xda:
+			size = 2
+		case 0xc6, 0xdb:
+			size = 4
+		}
+		n, b, err := uintN(b, size)
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeString(b, int(n))
+	case 0xdc, 0xdd:
+		n, b, err := uintN(b, 2<<(t-0xdc))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeArray(b, int(n))
+	case 0xde, 0xdf:
+		n, b, err := uintN(b, 2<<(t-0xde))
+		if err != nil {
+			return nil, nil, err
+		}
+		return decodeMap(b, int(n))
+	default:
+		return nil, nil, fmt.Errorf("msgpack: unsupported type 0x%x", t)
+	}
+}
+
+func uintN(b []byte, size int) (uint64, []byte, error) {
+	if len(b) < size {
+		return 0, nil, ErrShortInput
+	}
+
+	var n uint64
+	for _, c := range b[:size] {
+		n = n<<8 | uint64(c)
+	}
+
+	return n, b[size:], nil
+}
+
+func decodeString(b []byte, l int) (any, []byte, error) {
+	if len(b) < l {
+		return nil, nil, ErrShortInput
+	}
+
+	return string(b[:l]), b[l:], nil
+}
+
+func decodeArray(b []byte, l int) (any, []byte, error) {
+	if l > len(b) {
+		// Each element takes at least a byte.
+		return nil, nil, ErrShortInput
+	}
+
+	arr := make([]any, l)
+	for i := range arr {
+		var err error
+		if arr[i], b, err = decode(b); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return arr, b, nil
+}
+
+func decodeMap(b []byte, l int) (any, []byte, error) {
+	if 2*l > len(b) {
+		return nil, nil, ErrShortInput
+	}
+
+	m := make(map[string]any, l)
+	for i := 0; i < l; i++ {
+		k, rest, err := decode(b)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var key string
+		switch k := k.(type) {
+		case string:
+			key = k
+		case int64:
+			key = strconv.FormatInt(k, 10)
+		default:
+			return nil, nil, fmt.Errorf("msgpack: unsupported map key type %T", k)
+		}
+
+		if m[key], b, err = decode(rest); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return m, b, nil
+}
@@ -0,0 +1,55 @@
+package msgpack_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmaxmax/go-sse/internal/msgpack"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	const input = `{"nil":null,"bool":[true,false],"ints":[0,127,-32,-33,200,-200,70000,-70000,5000000000],"float":1.5,"str":"` +
+		`a string that is longer than thirty-one bytes","nested":{"empty":{},"list":[]}}`
+
+	dec := json.NewDecoder(strings.NewReader(input))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := msgpack.Append(nil, v)
+	if err != nil {
+		t.Fatalf("unexpected encoding error: %v", err)
+	}
+
+	decoded, err := msgpack.Decode(b)
+	if err != nil {
+		t.Fatalf("unexpected decoding error: %v", err)
+	}
+
+	expected := map[string]any{
+		"nil":    nil,
+		"bool":   []any{true, false},
+		"ints":   []any{int64(0), int64(127), int64(-32), int64(-33), int64(200), int64(-200), int64(70000), int64(-70000), int64(5000000000)},
+		"float":  1.5,
+		"str":    "a string that is longer than thirty-one bytes",
+		"nested": map[string]any{"empty": map[string]any{}, "list": []any{}},
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Fatalf("invalid decoded value:\nreceived: %#v\nexpected: %#v", decoded, expected)
+	}
+
+	if _, err := msgpack.Decode(b[:len(b)-1]); !errors.Is(err, msgpack.ErrShortInput) {
+		t.Fatalf("invalid error for truncated input: %v", err)
+	}
+	if _, err := msgpack.Append(nil, struct{}{}); err == nil {
+		t.Fatal("expected error for unsupported type")
+	}
+}
@@ -20,12 +20,18 @@ const (
 	FieldNameEvent = FieldName("event")
 	FieldNameRetry = FieldName("retry")
 	FieldNameID    = FieldName("id")
+	// FieldNameStream is not defined by the spec. It is used
+	// to multiplex multiple logical streams over one connection.
+	FieldNameStream = FieldName("stream")
+	// FieldNameTrace is not defined by the spec. It carries
+	// the trace ID of an event, for end-to-end correlation.
+	FieldNameTrace = FieldName("trace")
 	// FieldNameComment is a sentinel value that indicates
 	// comment fields. It is not a valid field name that should
 	// be written to a SSE stream.
 	FieldNameComment = FieldName(":")
 
-	maxFieldNameLength = 5
+	maxFieldNameLength = 6
 )
 
 func getFieldName(b string) (FieldName, bool) {
@@ -38,6 +44,10 @@ func getFieldName(b string) (FieldName, bool) {
 		return FieldNameRetry, true
 	case FieldNameID:
 		return FieldNameID, true
+	case FieldNameStream:
+		return FieldNameStream, true
+	case FieldNameTrace:
+		return FieldNameTrace, true
 	default:
 		return "", false
 	}
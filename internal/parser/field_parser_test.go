@@ -43,6 +43,24 @@ func TestFieldParser(t *testing.T) {
 				{},
 			},
 		},
+		{
+			name: "Stream field",
+			data: "stream: prices\ndata: 1\nstreams: ignored\n\n",
+			expected: []parser.Field{
+				{Name: parser.FieldNameStream, Value: "prices"},
+				newDataField(t, "1"),
+				{},
+			},
+		},
+		{
+			name: "Trace field",
+			data: "trace: 4bf92f3577b34da6\ndata: 1\n\n",
+			expected: []parser.Field{
+				{Name: parser.FieldNameTrace, Value: "4bf92f3577b34da6"},
+				newDataField(t, "1"),
+				{},
+			},
+		},
 		{
 			name: "Normal data but no newline at the end",
 			data: ":comment\r: another comment\ndata: whatever",
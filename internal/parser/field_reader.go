@@ -0,0 +1,102 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unsafe"
+)
+
+// FieldReader extracts fields from a reader as they arrive. Unlike Parser, it doesn't wait
+// for an event to end before returning its fields, and it returns the values of data fields
+// in chunks, so data lines longer than its buffer are never entirely in memory. The other
+// lines must fit in the maximum size given, otherwise Next returns bufio.ErrTooLong.
+// Blank lines are returned as fields without name, as Parser does, and the UTF-8 BOM is removed.
+type FieldReader struct {
+	lines  *LineReader
+	fields FieldParser
+	line   []byte
+	max    int
+	inData bool
+}
+
+// Next reads the next field. If a data line is longer than what was received so far,
+// the data field is returned with the part of its value received and more is true.
+// The rest of the value is returned by the following calls, in fields with the name
+// FieldNameData, until more is false.
+//
+// The values of data fields are valid only until the next call to Next.
+// The values of the other fields own their memory.
+func (r *FieldReader) Next(f *Field) (more bool, err error) {
+	for {
+		chunk, end, err := r.lines.Next()
+		if err != nil {
+			return false, err
+		}
+
+		if r.inData {
+			r.inData = !end
+			*f = Field{Name: FieldNameData, Value: bytesToString(chunk)}
+			return r.inData, nil
+		}
+
+		r.line = append(r.line, chunk...)
+		if !end {
+			if value, ok := dataValue(r.line); ok {
+				r.line, r.inData = r.line[:0], true
+				*f = Field{Name: FieldNameData, Value: bytesToString(value)}
+				return true, nil
+			}
+			if len(r.line) > r.max {
+				return false, bufio.ErrTooLong
+			}
+			continue
+		}
+
+		line := r.line
+		r.line = r.line[:0]
+		if !r.fields.scanSegment(bytesToString(line), f) {
+			continue
+		}
+		if f.Name != FieldNameData {
+			if len(line) > r.max {
+				return false, bufio.ErrTooLong
+			}
+			f.Value = strings.Clone(f.Value)
+		}
+
+		return false, nil
+	}
+}
+
+// KeepComments configures the FieldReader to parse/ignore comment fields.
+// By default comment fields are ignored.
+func (r *FieldReader) KeepComments(shouldKeep bool) {
+	r.fields.KeepComments(shouldKeep)
+}
+
+// NewFieldReader returns a FieldReader which reads from r using a buffer of the given size.
+// The lines which aren't data lines can be at most max bytes long.
+func NewFieldReader(r io.Reader, size, max int) *FieldReader {
+	return &FieldReader{lines: NewLineReader(r, size), max: max}
+}
+
+// dataValue returns the value received so far of an incomplete data line. It returns false if the
+// line is not a data line or it's not known yet whether the value starts with the optional space.
+func dataValue(line []byte) ([]byte, bool) {
+	const prefix = "data:"
+	if len(line) <= len(prefix) || string(line[:len(prefix)]) != prefix {
+		return nil, false
+	}
+
+	v := line[len(prefix):]
+	if v[0] == ' ' {
+		v = v[1:]
+	}
+
+	return v, true
+}
+
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
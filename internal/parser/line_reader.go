@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// LineReader reads the lines of the input in chunks, so lines longer than its buffer can be
+// processed without having them entirely in memory. The UTF-8 BOM is removed, if it exists.
+type LineReader struct {
+	r          *bufio.Reader
+	crPending  bool
+	bomRemoved bool
+}
+
+// Next returns the next chunk of the current line, without the newline sequence, and whether
+// the line ends with it. The chunk is valid only until the next call to Next. Lines which are
+// not terminated at the end of input are returned without end, after which Next returns io.EOF.
+func (l *LineReader) Next() (chunk []byte, end bool, err error) {
+	if l.crPending {
+		// The previous line ended with '\r', so a '\n' which follows is part of its newline sequence.
+		l.crPending = false
+		if b, err := l.r.Peek(1); err == nil && b[0] == '\n' {
+			_, _ = l.r.Discard(1)
+		}
+	}
+
+	b, err := l.r.Peek(1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !l.bomRemoved {
+		l.bomRemoved = true
+		// Only peek further if the input may start with the BOM, so short lines aren't delayed.
+		const bom = "\xEF\xBB\xBF"
+		if b[0] == bom[0] {
+			if b, err := l.r.Peek(len(bom)); err == nil && string(b) == bom {
+				_, _ = l.r.Discard(len(bom))
+			}
+		}
+	}
+
+	data, _ := l.r.Peek(l.r.Buffered())
+	i := bytes.IndexAny(data, "\r\n")
+	if i == -1 {
+		_, _ = l.r.Discard(len(data))
+		return data, false, nil
+	}
+
+	n := i + 1
+	if data[i] == '\r' {
+		if i+1 == len(data) {
+			l.crPending = true
+		} else if data[i+1] == '\n' {
+			n++
+		}
+	}
+	_, _ = l.r.Discard(n)
+
+	return data[:i], true, nil
+}
+
+// NewLineReader returns a LineReader which reads from r using a buffer of the given size.
+func NewLineReader(r io.Reader, size int) *LineReader {
+	return &LineReader{r: bufio.NewReaderSize(r, size)}
+}
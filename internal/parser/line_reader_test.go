@@ -0,0 +1,141 @@
+package parser_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tmaxmax/go-sse/internal/parser"
+)
+
+func TestLineReader(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 40)
+	input := "\xEF\xBB\xBFdata: a\r\ndata: b\r\rid: 1\n" + long + "\nunterminated"
+
+	// The smallest buffer possible, so the long line is returned in chunks.
+	r := parser.NewLineReader(strings.NewReader(input), 16)
+
+	var (
+		lines []string
+		line  string
+	)
+	for {
+		chunk, end, err := r.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			break
+		}
+
+		line += string(chunk)
+		if end {
+			lines = append(lines, line)
+			line = ""
+		}
+	}
+
+	expected := []string{"data: a", "data: b", "", "id: 1", long}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("invalid lines:\nreceived: %q\nexpected: %q", lines, expected)
+	}
+	if line != "unterminated" {
+		t.Fatalf("unterminated line not returned: %q", line)
+	}
+}
+
+func TestFieldReader(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("a", 40)
+	input := "\xEF\xBB\xBFevent: e\ndata: " + long + "\n: comment\ndata\n\nunknown: field\nid: 1\n\ndata: unterminated"
+
+	type field struct {
+		Field  parser.Field
+		Chunks int
+		More   bool
+	}
+
+	// The smallest buffer possible, so the long data line is returned in chunks.
+	r := parser.NewFieldReader(strings.NewReader(input), 16, 20)
+	r.KeepComments(true)
+
+	var (
+		fields []field
+		more   bool
+	)
+	for {
+		var (
+			f   parser.Field
+			err error
+		)
+		cont := more
+		more, err = r.Next(&f)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			break
+		}
+
+		if cont {
+			last := &fields[len(fields)-1]
+			if f.Name != parser.FieldNameData {
+				t.Fatalf("data value continued in field %+v", f)
+			}
+			// Data values are valid only until the next call, so they are copied.
+			last.Field.Value += f.Value
+			last.Chunks++
+			last.More = more
+			continue
+		}
+
+		f.Value = strings.Clone(f.Value)
+		fields = append(fields, field{Field: f, Chunks: 1, More: more})
+	}
+
+	if l := fields[1].Chunks; l < 2 {
+		t.Fatalf("long data line should be returned in chunks, got %d", l)
+	}
+	fields[1].Chunks = 0
+	fields[len(fields)-1].Chunks = 0
+
+	expected := []field{
+		{Field: newEventField(t, "e"), Chunks: 1},
+		{Field: newDataField(t, long)},
+		{Field: newField(t, parser.FieldNameComment, "comment"), Chunks: 1},
+		{Field: newDataField(t, ""), Chunks: 1},
+		{Chunks: 1},
+		{Field: newIDField(t, "1"), Chunks: 1},
+		{Chunks: 1},
+		{Field: newDataField(t, "unterminated"), More: true},
+	}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Fatalf("invalid fields:\nreceived: %+v\nexpected: %+v", fields, expected)
+	}
+}
+
+func TestFieldReader_tooLong(t *testing.T) {
+	t.Parallel()
+
+	r := parser.NewFieldReader(strings.NewReader("data: "+strings.Repeat("a", 40)+"\nid: "+strings.Repeat("1", 40)+"\n"), 16, 20)
+
+	var (
+		f   parser.Field
+		err error
+	)
+	for err == nil {
+		_, err = r.Next(&f)
+	}
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Fatalf("long non-data lines should be rejected, got %v", err)
+	}
+	if f.Name != parser.FieldNameData {
+		t.Fatalf("long data lines should be accepted, got %+v", f)
+	}
+}
@@ -29,7 +29,11 @@ func splitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 		}
 	}
 
-	if l := len(data); advance == l && !atEOF {
+	if l := len(data); advance == l && start == l && atEOF {
+		// Only blank lines are left, for example the '\n' of a "\r\n" sequence which wasn't yet
+		// received when the event before was split. They don't make an event, so they are skipped.
+		return advance, nil, nil
+	} else if advance == l && !atEOF {
 		// We have reached the end of the buffer but have not yet seen two consecutive
 		// newline sequences, so we request more data.
 		return 0, nil, nil
@@ -52,10 +56,25 @@ func splitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 type Parser struct {
 	inputScanner *bufio.Scanner
 	fieldScanner *FieldParser
+	err          error
+	raw          string
+	reuse        bool
+	utf8         UTF8Mode
 }
 
 // Next parses a single field from the reader. It returns false when there are no more fields to parse.
 func (r *Parser) Next(f *Field) bool {
+	if r.err != nil || !r.next(f) {
+		return false
+	}
+	if r.err = CheckUTF8(f, r.utf8); r.err != nil {
+		return false
+	}
+
+	return true
+}
+
+func (r *Parser) next(f *Field) bool {
 	if !r.fieldScanner.Next(f) {
 		if !r.inputScanner.Scan() {
 			// Do this to signal EOF, which bufio.Scanner suppresses.
@@ -76,7 +95,13 @@ func (r *Parser) Next(f *Field) bool {
 		// to allocate new memory and copy each field value. This way, not only the caller doesn't
 		// have to worry about allocations and ownership, but also bigger and less frequent allocations
 		// are made, compared to the previous usage – allocations are now made per event, not per field value.
-		r.fieldScanner.Reset(r.inputScanner.Text())
+		if r.reuse {
+			b := r.inputScanner.Bytes()
+			r.raw = *(*string)(unsafe.Pointer(&b))
+		} else {
+			r.raw = r.inputScanner.Text()
+		}
+		r.fieldScanner.Reset(r.raw)
 
 		return r.fieldScanner.Next(f)
 	}
@@ -87,6 +112,9 @@ func (r *Parser) Next(f *Field) bool {
 // Err returns the last read error. At the end of input
 // it will always be equal to io.EOF.
 func (r *Parser) Err() error {
+	if r.err != nil {
+		return r.err
+	}
 	if err := r.fieldScanner.Err(); err != nil {
 		return err
 	}
@@ -98,6 +126,32 @@ func (r *Parser) Err() error {
 	return r.inputScanner.Err()
 }
 
+// Raw returns the input from which the fields of the current event are parsed, which includes
+// the blank line that ends the event, if there is one. Leading blank lines are not included.
+func (r *Parser) Raw() string {
+	return r.raw
+}
+
+// KeepComments configures the Parser to parse/ignore comment fields.
+// By default comment fields are ignored.
+func (r *Parser) KeepComments(shouldKeep bool) {
+	r.fieldScanner.KeepComments(shouldKeep)
+}
+
+// ReuseBuffer configures the Parser to return fields which share memory with its buffer,
+// instead of allocating a copy of each event. The fields' values, and Raw, are then valid
+// only until the fields of the next event are parsed. By default the fields own their memory.
+func (r *Parser) ReuseBuffer(reuse bool) {
+	r.reuse = reuse
+}
+
+// UTF8 configures what the Parser does with field values which aren't valid UTF-8.
+// By default they are left untouched. If the mode is UTF8Reject, parsing stops at the
+// first invalid value and Err returns ErrInvalidUTF8.
+func (r *Parser) UTF8(mode UTF8Mode) {
+	r.utf8 = mode
+}
+
 // Buffer sets the buffer used to scan the input.
 // For more information, see the documentation on bufio.Scanner.Buffer.
 // Do not call this after parsing has started – the method will panic!
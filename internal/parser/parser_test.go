@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/tmaxmax/go-sse/internal/parser"
 )
@@ -158,3 +159,111 @@ func BenchmarkParser(b *testing.B) {
 
 	_ = f
 }
+
+func TestParser_ReuseBuffer(t *testing.T) {
+	t.Parallel()
+
+	p := parser.New(strings.NewReader("data: first\n\ndata: second\nid: 2\n\n"))
+	p.ReuseBuffer(true)
+
+	var (
+		f        parser.Field
+		received []parser.Field
+	)
+	for p.Next(&f) {
+		// The values are valid only until the next event is parsed, so they are copied.
+		f.Value = string([]byte(f.Value))
+		received = append(received, f)
+	}
+
+	expected := []parser.Field{
+		newDataField(t, "first"),
+		{},
+		newDataField(t, "second"),
+		newIDField(t, "2"),
+		{},
+	}
+	if !reflect.DeepEqual(received, expected) {
+		t.Fatalf("invalid fields:\nreceived: %#v\nexpected: %#v", received, expected)
+	}
+}
+
+func TestParser_UTF8(t *testing.T) {
+	t.Parallel()
+
+	const input = "\xEF\xBB\xBFdata: a\xffb\nid: 1\n\n"
+
+	tests := []struct {
+		mode     parser.UTF8Mode
+		expected []parser.Field
+		err      error
+	}{
+		{
+			mode:     parser.UTF8PassThrough,
+			expected: []parser.Field{newDataField(t, "a\xffb"), newIDField(t, "1"), {}},
+			err:      io.EOF,
+		},
+		{
+			mode:     parser.UTF8Replace,
+			expected: []parser.Field{newDataField(t, "a\uFFFDb"), newIDField(t, "1"), {}},
+			err:      io.EOF,
+		},
+		{
+			mode: parser.UTF8Reject,
+			err:  parser.ErrInvalidUTF8,
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(strings.NewReader(input))
+		p.UTF8(tt.mode)
+
+		var (
+			f        parser.Field
+			received []parser.Field
+		)
+		for p.Next(&f) {
+			received = append(received, f)
+		}
+
+		if !reflect.DeepEqual(received, tt.expected) {
+			t.Fatalf("invalid fields for mode %d:\nreceived: %#v\nexpected: %#v", tt.mode, received, tt.expected)
+		}
+		if err := p.Err(); !errors.Is(err, tt.err) {
+			t.Fatalf("invalid error for mode %d: received %v, expected %v", tt.mode, err, tt.err)
+		}
+	}
+}
+
+func TestParser_LineEndings(t *testing.T) {
+	t.Parallel()
+
+	expected := []parser.Field{newDataField(t, "a"), newDataField(t, "b"), {}, newIDField(t, "1"), {}}
+
+	for _, input := range []string{
+		"data: a\ndata: b\n\nid: 1\n\n",
+		"data: a\r\ndata: b\r\n\r\nid: 1\r\n\r\n",
+		"data: a\rdata: b\r\rid: 1\r\r",
+		"\r\ndata: a\r\ndata: b\n\r\r\nid: 1\r\n\n",
+	} {
+		// Reading one byte at a time splits the input between the '\r' and the '\n' of each "\r\n" sequence.
+		for _, r := range []io.Reader{strings.NewReader(input), iotest.OneByteReader(strings.NewReader(input))} {
+			p := parser.New(r)
+
+			var (
+				f        parser.Field
+				received []parser.Field
+			)
+			for p.Next(&f) {
+				received = append(received, f)
+			}
+
+			if !reflect.DeepEqual(received, expected) {
+				t.Fatalf("invalid fields for %q:\nreceived: %#v\nexpected: %#v", input, received, expected)
+			}
+			if err := p.Err(); err != io.EOF { //nolint:errorlint // Parser returns io.EOF unwrapped
+				t.Fatalf("invalid error for %q: %v", input, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// UTF8Mode determines what the parsers do with field values which aren't valid UTF-8.
+// The spec requires streams to be UTF-8 and invalid sequences to be replaced.
+type UTF8Mode int
+
+const (
+	// UTF8PassThrough leaves the values untouched. It is the default mode.
+	UTF8PassThrough UTF8Mode = iota
+	// UTF8Replace replaces invalid sequences with the Unicode replacement character (U+FFFD).
+	UTF8Replace
+	// UTF8Reject makes parsing fail with ErrInvalidUTF8.
+	UTF8Reject
+)
+
+// ErrInvalidUTF8 is returned when a field value isn't valid UTF-8 and the mode is UTF8Reject.
+var ErrInvalidUTF8 = errors.New("invalid UTF-8")
+
+// CheckUTF8 applies the given mode to the field's value.
+func CheckUTF8(f *Field, mode UTF8Mode) error {
+	if mode == UTF8PassThrough || utf8.ValidString(f.Value) {
+		return nil
+	}
+
+	if mode == UTF8Reject {
+		return ErrInvalidUTF8
+	}
+
+	f.Value = strings.ToValidUTF8(f.Value, string(utf8.RuneError))
+
+	return nil
+}
@@ -78,6 +78,19 @@ type ReplayProviderWithGC interface {
 	GC() error
 }
 
+// ReplayProviderWithSince is a ReplayProvider that can also replay the events published since
+// a given time. This allows clients which don't have an event ID, for example on their first
+// connection, to still request the recent events – see Subscription.ReplaySince.
+//
+// Providers must check if replay providers implement this interface, so they can call ReplaySince
+// for the subscriptions which have a ReplaySince time but no LastEventID.
+type ReplayProviderWithSince interface {
+	ReplayProvider
+	// ReplaySince sends to a new subscriber all the valid events received by the provider
+	// at or after the given time. The same requirements as for Replay apply.
+	ReplaySince(subscription Subscription, since time.Time) error
+}
+
 type (
 	subscriber   chan<- error
 	subscription struct {
@@ -89,6 +102,11 @@ type (
 		message *Message
 		topics  []string
 	}
+
+	replaySwap struct {
+		provider ReplayProvider
+		overlap  time.Duration
+	}
 )
 
 // Joe is a basic server provider that synchronously executes operations by queueing them in channels.
@@ -109,6 +127,8 @@ type Joe struct {
 	message        chan messageWithTopics
 	subscription   chan subscription
 	unsubscription chan subscriber
+	replaySwap     chan replaySwap
+	replayIter     chan replayIterRequest
 	done           chan struct{}
 	closed         chan struct{}
 	subscribers    map[subscriber]Subscription
@@ -118,7 +138,18 @@ type Joe struct {
 	// An optional interval at which Joe triggers a cleanup of expired messages, if the replay provider supports it.
 	// See the desired provider's documentation to determine if periodic cleanup is necessary.
 	ReplayGCInterval time.Duration
-
+	// An optional reporter for the panics of the replay provider, which are recovered.
+	// If it is nil, the panics are logged using the standard library's log package.
+	ErrorReporter ErrorReporter
+	// HighWaterMarks optionally limits, for each topic, the number of published messages which Joe
+	// hasn't finished sending yet. When a topic reaches its mark, Publish behaves according to the
+	// CongestionPolicy. Topics without a positive mark are not limited.
+	HighWaterMarks map[string]int
+	// CongestionPolicy determines what Publish does for congested topics. By default,
+	// it returns ErrTopicCongested.
+	CongestionPolicy CongestionPolicy
+
+	queues   topicQueues
 	initDone sync.Once
 }
 
@@ -156,6 +187,9 @@ func (j *Joe) Subscribe(ctx context.Context, sub Subscription) error {
 // to more than one topic that receive the given Message. Every client
 // receives each unique message once, regardless of how many topics it
 // is subscribed to or to how many topics the message is published.
+//
+// If any of the topics has reached its high-water mark, Publish either
+// returns ErrTopicCongested or waits, depending on the CongestionPolicy.
 func (j *Joe) Publish(msg *Message, topics []string) error {
 	if len(topics) == 0 {
 		return ErrNoTopic
@@ -163,12 +197,17 @@ func (j *Joe) Publish(msg *Message, topics []string) error {
 
 	j.init()
 
+	if err := j.enqueue(topics); err != nil {
+		return err
+	}
+
 	// Waiting on done ensures Publish doesn't block the caller goroutine
 	// when Joe is stopped and implements the required Provider behavior.
 	select {
 	case j.message <- messageWithTopics{message: msg, topics: topics}:
 		return nil
 	case <-j.done:
+		j.queues.dequeue(topics)
 		return ErrProviderClosed
 	}
 }
@@ -197,19 +236,62 @@ func (j *Joe) Shutdown(ctx context.Context) (err error) {
 	return
 }
 
+// Healthy returns ErrProviderClosed if Joe is stopped, and nil otherwise.
+func (j *Joe) Healthy() error {
+	j.init()
+
+	select {
+	case <-j.done:
+		return ErrProviderClosed
+	case <-j.closed:
+		return ErrProviderClosed
+	default:
+		return nil
+	}
+}
+
+// SetReplayProvider replaces Joe's replay provider at runtime, for example to migrate from an in-memory
+// provider to one backed by a database without downtime. For the given overlap duration, new messages
+// are put into both the previous and the new replay provider, and subscriptions for which the new
+// provider replays nothing are replayed from the previous one, so the replay window isn't lost.
+// After the overlap, the previous provider is not used anymore.
+//
+// During the overlap, the messages returned by the new provider's Put are sent to the subscribers.
+// Make sure the previous provider keeps those messages' IDs – for example, it must not set IDs automatically.
+// If the provider is nil, Joe stops replaying messages after the overlap.
+func (j *Joe) SetReplayProvider(provider ReplayProvider, overlap time.Duration) error {
+	j.init()
+
+	if provider == nil {
+		provider = noopReplayProvider{}
+	}
+
+	select {
+	case j.replaySwap <- replaySwap{provider: provider, overlap: overlap}:
+		return nil
+	case <-j.done:
+		return ErrProviderClosed
+	}
+}
+
 func (j *Joe) removeSubscriber(sub subscriber) {
 	delete(j.subscribers, sub)
 	close(sub)
 }
 
-func (j *Joe) start(replay ReplayProvider, gcFn func() error, gcSignal <-chan time.Time, stopGCSignal func()) {
+func (j *Joe) start(replay ReplayProvider) {
 	defer close(j.closed)
 	// defer closing all subscribers instead of closing them when done is closed
 	// so in case of a panic subscribers won't block the request goroutines forever.
 	defer j.closeSubscribers()
-	defer stopGCSignal()
+
+	gcFn, gcSignal, stopGCSignal := j.replayGC(replay)
+	defer func() { stopGCSignal() }()
 
 	canReplay := true
+	// The previous replay provider and the end of its overlap, after a replay provider swap.
+	var previous ReplayProvider
+	var previousEnd <-chan time.Time
 
 	for {
 		select {
@@ -218,6 +300,9 @@ func (j *Joe) start(replay ReplayProvider, gcFn func() error, gcSignal <-chan ti
 			if canReplay {
 				toDispatch = j.tryPut(msg, replay, &canReplay)
 			}
+			if previous != nil {
+				_ = j.tryPut(messageWithTopics{message: toDispatch, topics: msg.topics}, previous, new(bool))
+			}
 
 			for done, sub := range j.subscribers {
 				if topicsIntersect(sub.Topics, msg.topics) {
@@ -232,10 +317,12 @@ func (j *Joe) start(replay ReplayProvider, gcFn func() error, gcSignal <-chan ti
 					}
 				}
 			}
+
+			j.queues.dequeue(msg.topics)
 		case sub := <-j.subscription:
 			var err error
 			if canReplay {
-				err = j.tryReplay(sub.Subscription, replay, &canReplay)
+				err = j.tryReplayThrough(sub.Subscription, replay, previous, &canReplay)
 			}
 
 			if err != nil {
@@ -246,6 +333,20 @@ func (j *Joe) start(replay ReplayProvider, gcFn func() error, gcSignal <-chan ti
 			}
 		case sub := <-j.unsubscription:
 			j.removeSubscriber(sub)
+		case swap := <-j.replaySwap:
+			if canReplay {
+				previous = replay
+				previousEnd = time.After(swap.overlap)
+			}
+
+			replay, canReplay = swap.provider, true
+
+			stopGCSignal()
+			gcFn, gcSignal, stopGCSignal = j.replayGC(replay)
+		case req := <-j.replayIter:
+			req.result <- j.tryIter(req, replay)
+		case <-previousEnd:
+			previous, previousEnd = nil, nil
 		case <-gcSignal:
 			if err := gcFn(); err != nil {
 				stopGCSignal()
@@ -256,42 +357,108 @@ func (j *Joe) start(replay ReplayProvider, gcFn func() error, gcSignal <-chan ti
 	}
 }
 
+// replayGC returns the GC function of the replay provider and the channel of the ticker
+// which signals when to call it. The ticker doesn't tick if the provider doesn't require GC.
+func (j *Joe) replayGC(replay ReplayProvider) (gcFn func() error, ticks <-chan time.Time, stop func()) {
+	provider, hasGC := replay.(ReplayProviderWithGC)
+	if !hasGC {
+		return nil, nil, func() {}
+	}
+
+	gcFn = func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = ErrReplayFailed
+				j.reportPanic(r)
+			}
+		}()
+		err = provider.GC()
+		return
+	}
+
+	ticks, stop = ticker(j.ReplayGCInterval)
+
+	return gcFn, ticks, stop
+}
+
+// tryReplayThrough replays the subscription using the current replay provider and,
+// if it replayed nothing, using the previous provider, if there is one.
+func (j *Joe) tryReplayThrough(sub Subscription, replay, previous ReplayProvider, canReplay *bool) error {
+	if previous == nil {
+		return j.tryReplay(sub, replay, canReplay)
+	}
+
+	counter := &countingWriter{MessageWriter: sub.Client}
+	sub.Client = counter
+
+	if err := j.tryReplay(sub, replay, canReplay); err != nil || counter.sent > 0 {
+		return err
+	}
+
+	return j.tryReplay(sub, previous, new(bool))
+}
+
+type countingWriter struct {
+	MessageWriter
+	sent int
+}
+
+func (c *countingWriter) Send(m *Message) error {
+	c.sent++
+	return c.MessageWriter.Send(m)
+}
+
 func (j *Joe) closeSubscribers() {
 	for done := range j.subscribers {
 		j.removeSubscriber(done)
 	}
 }
 
-func (*Joe) tryReplay(sub Subscription, replay ReplayProvider, canReplay *bool) (err error) {
+func (j *Joe) tryReplay(sub Subscription, replay ReplayProvider, canReplay *bool) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			*canReplay = false
 			err = ErrReplayFailed
-			log.Printf("panic: %v\n%s", r, debug.Stack())
+			j.reportPanic(r)
 		}
 	}()
 
-	err = replay.Replay(sub)
+	if r, ok := replay.(ReplayProviderWithSince); ok && !sub.LastEventID.IsSet() && !sub.ReplaySince.IsZero() {
+		err = r.ReplaySince(sub, sub.ReplaySince)
+	} else {
+		err = replay.Replay(sub)
+	}
 
 	return
 }
 
-func (*Joe) tryPut(msg messageWithTopics, replay ReplayProvider, canReplay *bool) *Message {
+func (j *Joe) tryPut(msg messageWithTopics, replay ReplayProvider, canReplay *bool) *Message {
 	defer func() {
 		if r := recover(); r != nil {
 			*canReplay = false
-			log.Printf("panic: %v\n%s", r, debug.Stack())
+			j.reportPanic(r)
 		}
 	}()
 
 	return replay.Put(msg.message, msg.topics)
 }
 
+func (j *Joe) reportPanic(r any) {
+	if j.ErrorReporter != nil {
+		j.ErrorReporter.ReportError(context.Background(), &PanicError{Value: r, Stack: debug.Stack()})
+		return
+	}
+
+	log.Printf("panic: %v\n%s", r, debug.Stack())
+}
+
 func (j *Joe) init() {
 	j.initDone.Do(func() {
 		j.message = make(chan messageWithTopics)
 		j.subscription = make(chan subscription)
 		j.unsubscription = make(chan subscriber)
+		j.replaySwap = make(chan replaySwap)
+		j.replayIter = make(chan replayIterRequest)
 		j.done = make(chan struct{})
 		j.closed = make(chan struct{})
 		j.subscribers = map[subscriber]Subscription{}
@@ -301,28 +468,7 @@ func (j *Joe) init() {
 			replay = noopReplayProvider{}
 		}
 
-		var gcFn func() error
-		replayGCInterval := j.ReplayGCInterval
-
-		provider, hasGC := replay.(ReplayProviderWithGC)
-		if hasGC {
-			gcFn = func() (err error) {
-				defer func() {
-					if r := recover(); r != nil {
-						err = ErrReplayFailed
-						log.Printf("panic: %v\n%s", r, debug.Stack())
-					}
-				}()
-				err = provider.GC()
-				return
-			}
-		} else {
-			replayGCInterval = 0
-		}
-
-		gc, stopGCTicker := ticker(replayGCInterval)
-
-		go j.start(replay, gcFn, gc, stopGCTicker)
+		go j.start(replay)
 	})
 }
 
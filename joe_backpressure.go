@@ -0,0 +1,104 @@
+package sse
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrTopicCongested is returned by Joe's Publish when a topic the message is published to has
+// reached its high-water mark and the CongestionPolicy is CongestionReject. The error returned
+// wraps ErrTopicCongested and contains the congested topic – use errors.Is to check for it.
+var ErrTopicCongested = errors.New("go-sse.server: topic congested")
+
+// CongestionPolicy determines what Joe's Publish does when a topic has reached its high-water mark.
+type CongestionPolicy int
+
+const (
+	// CongestionReject makes Publish return ErrTopicCongested, so producers can shed load.
+	CongestionReject CongestionPolicy = iota
+	// CongestionBlock makes Publish wait until the topic's queue depth is under the high-water mark.
+	CongestionBlock
+)
+
+// QueueDepth returns the number of messages published to the given topic which Joe
+// hasn't finished sending to the subscribers yet.
+func (j *Joe) QueueDepth(topic string) int {
+	return j.queues.depth(topic)
+}
+
+// enqueue waits until none of the given topics is congested, then counts the message
+// in their queue depths. It must be paired with a call to queues.dequeue.
+func (j *Joe) enqueue(topics []string) error {
+	for {
+		congested, drained := j.queues.enqueue(topics, j.HighWaterMarks)
+		if drained == nil {
+			return nil
+		}
+		if j.CongestionPolicy != CongestionBlock {
+			return fmt.Errorf("%w: %q", ErrTopicCongested, congested)
+		}
+
+		select {
+		case <-drained:
+		case <-j.done:
+			return ErrProviderClosed
+		}
+	}
+}
+
+type topicQueues struct {
+	depths map[string]int
+	// drained is closed and replaced each time a message is dequeued.
+	drained chan struct{}
+	mu      sync.Mutex
+}
+
+// enqueue counts the message in the queue depths of the given topics. If any of the topics
+// has reached its high-water mark, nothing is counted and the congested topic is returned,
+// together with a channel which is closed when a message is dequeued.
+func (q *topicQueues) enqueue(topics []string, marks map[string]int) (congested string, drained <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range topics {
+		if mark, ok := marks[t]; ok && mark > 0 && q.depths[t] >= mark {
+			if q.drained == nil {
+				q.drained = make(chan struct{})
+			}
+			return t, q.drained
+		}
+	}
+
+	if q.depths == nil {
+		q.depths = map[string]int{}
+	}
+	for _, t := range topics {
+		q.depths[t]++
+	}
+
+	return "", nil
+}
+
+func (q *topicQueues) dequeue(topics []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, t := range topics {
+		if q.depths[t]--; q.depths[t] <= 0 {
+			delete(q.depths, t)
+		}
+	}
+
+	if q.drained != nil {
+		close(q.drained)
+		q.drained = nil
+	}
+}
+
+func (q *topicQueues) depth(topic string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.depths[topic]
+}
@@ -225,6 +225,30 @@ data: world
 	require.Equal(t, expected, msgs[0].String()+msgs[1].String(), "unexpected data received")
 }
 
+func TestJoe_Subscribe_pattern(t *testing.T) {
+	t.Parallel()
+
+	j := &sse.Joe{}
+	defer j.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ctx, cancel := newMockContext(t)
+	defer cancel()
+
+	sub := subscribe(t, j, ctx, "orders.*")
+	<-ctx.waitingOnDone
+
+	_ = j.Publish(msg(t, "created", ""), []string{"orders.created"})
+	_ = j.Publish(msg(t, "user", ""), []string{"users.created"})
+	_ = j.Publish(msg(t, "added", ""), []string{"orders.item.added"})
+
+	_ = j.Shutdown(context.Background())
+
+	msgs := <-sub
+
+	require.Len(t, msgs, 2, "only the messages of the matching topics should be received")
+	require.Equal(t, "data: created\n\ndata: added\n\n", msgs[0].String()+msgs[1].String(), "unexpected data received")
+}
+
 func TestJoe_errors(t *testing.T) {
 	t.Parallel()
 
@@ -314,3 +338,134 @@ func TestJoe_ReplayPanic(t *testing.T) {
 
 	require.NoError(t, j.Shutdown(context.Background()))
 }
+
+func TestJoe_ErrorReporter(t *testing.T) {
+	t.Parallel()
+
+	var reported []error
+	j := &sse.Joe{
+		ReplayProvider: &mockReplayProvider{shouldPanic: "replay"},
+		ErrorReporter: sse.ErrorReporterFunc(func(_ context.Context, err error) {
+			reported = append(reported, err)
+		}),
+	}
+	defer j.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	err := j.Subscribe(context.Background(), sse.Subscription{})
+	require.ErrorIs(t, err, sse.ErrReplayFailed, "wrong error returned")
+	require.Len(t, reported, 1, "panic wasn't reported")
+
+	var panicErr *sse.PanicError
+	require.ErrorAs(t, reported[0], &panicErr, "invalid reported error")
+	require.Equal(t, "panicked", panicErr.Value, "invalid panic value")
+}
+
+func TestJoe_SetReplayProvider(t *testing.T) {
+	t.Parallel()
+
+	previous := &sse.FiniteReplayProvider{Count: 10}
+	j := &sse.Joe{ReplayProvider: previous}
+	defer j.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	require.NoError(t, j.Publish(msg(t, "a", "1"), []string{sse.DefaultTopic}))
+	require.NoError(t, j.Publish(msg(t, "b", "2"), []string{sse.DefaultTopic}))
+
+	current := &sse.FiniteReplayProvider{Count: 10}
+	require.NoError(t, j.SetReplayProvider(current, time.Hour), "unexpected SetReplayProvider error")
+	require.NoError(t, j.Publish(msg(t, "c", "3"), []string{sse.DefaultTopic}))
+
+	replayed := func(lastEventID string) []string {
+		ctx, cancel := context.WithCancel(context.Background())
+		var msgs []string
+		err := j.Subscribe(ctx, sse.Subscription{
+			Client: mockClient(func(m *sse.Message) error {
+				if m == nil {
+					cancel()
+				} else {
+					msgs = append(msgs, m.String())
+				}
+				return nil
+			}),
+			LastEventID: sse.ID(lastEventID),
+			Topics:      []string{sse.DefaultTopic},
+		})
+		require.NoError(t, err, "unexpected Subscribe error")
+		return msgs
+	}
+
+	require.Equal(t, []string{"id: 2\ndata: b\n\n", "id: 3\ndata: c\n\n"}, replayed("1"), "previous provider should be read through")
+	require.NoError(t, j.Publish(msg(t, "d", "4"), []string{sse.DefaultTopic}))
+	require.Equal(t, []string{"id: 4\ndata: d\n\n"}, replayed("3"), "new provider should be used")
+
+	require.NoError(t, j.Shutdown(context.Background()))
+	require.ErrorIs(t, j.SetReplayProvider(nil, 0), sse.ErrProviderClosed, "invalid error after shutdown")
+}
+
+func TestJoe_HighWaterMarks(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []sse.CongestionPolicy{sse.CongestionReject, sse.CongestionBlock} {
+		j := &sse.Joe{HighWaterMarks: map[string]int{sse.DefaultTopic: 1}, CongestionPolicy: policy}
+
+		received, release := make(chan struct{}), make(chan struct{})
+		c := mockClient(func(m *sse.Message) error {
+			if m != nil {
+				received <- struct{}{}
+				<-release
+			}
+			return nil
+		})
+
+		ctx, cancel := newMockContext(t)
+		go func() { _ = j.Subscribe(ctx, sse.Subscription{Client: c, Topics: []string{sse.DefaultTopic}}) }()
+		<-ctx.waitingOnDone
+
+		require.NoError(t, j.Publish(msg(t, "hello", ""), []string{sse.DefaultTopic}))
+		<-received
+		require.Equal(t, 1, j.QueueDepth(sse.DefaultTopic), "invalid queue depth")
+
+		if policy == sse.CongestionReject {
+			require.ErrorIs(t, j.Publish(msg(t, "world", ""), []string{sse.DefaultTopic}), sse.ErrTopicCongested)
+			close(release)
+		} else {
+			published := make(chan error, 1)
+			go func() { published <- j.Publish(msg(t, "world", ""), []string{sse.DefaultTopic}) }()
+
+			select {
+			case <-published:
+				t.Fatal("publish should block while the topic is congested")
+			case <-time.After(10 * time.Millisecond):
+			}
+
+			close(release)
+			require.NoError(t, <-published)
+			<-received
+		}
+
+		cancel()
+		require.NoError(t, j.Shutdown(context.Background()))
+	}
+}
+
+func TestJoe_ReplayIter(t *testing.T) {
+	t.Parallel()
+
+	j := &sse.Joe{ReplayProvider: &sse.FiniteReplayProvider{Count: 10, AutoIDs: true}}
+	defer j.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	require.NoError(t, j.Publish(msg(t, "a", ""), []string{"orders"}), "unexpected publish error")
+	require.NoError(t, j.Publish(msg(t, "b", ""), []string{"other"}), "unexpected publish error")
+
+	it, err := j.ReplayIter("orders", sse.EventID{})
+	require.NoError(t, err, "unexpected ReplayIter error")
+	require.True(t, it.Next(), "published message not iterated")
+	require.Equal(t, "id: 0\ndata: a\n\n", it.Message().String(), "invalid message")
+	require.False(t, it.Next(), "only messages of the topic should be iterated")
+
+	_, err = (&sse.Joe{}).ReplayIter("orders", sse.EventID{})
+	require.ErrorIs(t, err, sse.ErrReplayIterUnsupported, "default replay provider can't be iterated")
+
+	require.NoError(t, j.Shutdown(context.Background()), "unexpected shutdown error")
+	_, err = j.ReplayIter("orders", sse.EventID{})
+	require.ErrorIs(t, err, sse.ErrProviderClosed, "closed Joe should return an error")
+}
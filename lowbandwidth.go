@@ -0,0 +1,69 @@
+package sse
+
+import (
+	"strconv"
+	"time"
+)
+
+// The capabilities advertised by clients in LowBandwidthMode, in addition to CapabilityEncoding.
+// CapabilityConflation has no value and asks the server to conflate the messages sent to the
+// client, for example by sending only the latest value of a frequently updated entity.
+// CapabilityKeepAlive has as value the interval, in seconds, at which the client can tolerate
+// receiving keep-alive comments, so servers can keep idle connections quieter.
+const (
+	CapabilityConflation = "conflation"
+	CapabilityKeepAlive  = "keep-alive"
+)
+
+// DefaultLowBandwidthKeepAlive is the keep-alive interval advertised by LowBandwidthMode by default.
+const DefaultLowBandwidthKeepAlive = time.Minute
+
+// LowBandwidthMode bundles the capabilities which reduce the traffic to constrained clients,
+// such as mobile or IoT devices. Servers which support the capabilities convention adapt the
+// messages sent to these clients; the others ignore them. Use it as the Client's LowBandwidth
+// field, and LowBandwidthModeFrom on the server to find out what the client asked for.
+type LowBandwidthMode struct {
+	// The payload encoding requested. Defaults to EncodingMsgpack, which is smaller than JSON.
+	// See PayloadEncoder.
+	Encoding string
+	// The interval at which the client can tolerate receiving keep-alive comments.
+	// Defaults to DefaultLowBandwidthKeepAlive.
+	KeepAlive time.Duration
+	// Whether the client opts into receiving conflated messages.
+	Conflation bool
+}
+
+// Capabilities returns the capabilities which describe the mode.
+func (m LowBandwidthMode) Capabilities() Capabilities {
+	c := Capabilities{
+		CapabilityEncoding:  m.Encoding,
+		CapabilityKeepAlive: strconv.FormatInt(int64(m.KeepAlive/time.Second), 10),
+	}
+	if c[CapabilityEncoding] == "" {
+		c[CapabilityEncoding] = EncodingMsgpack
+	}
+	if m.KeepAlive < time.Second {
+		c[CapabilityKeepAlive] = strconv.FormatInt(int64(DefaultLowBandwidthKeepAlive/time.Second), 10)
+	}
+	if m.Conflation {
+		c[CapabilityConflation] = ""
+	}
+
+	return c
+}
+
+// LowBandwidthModeFrom returns the low bandwidth mode advertised with the given capabilities,
+// for example those of a Session. The boolean is false if the client didn't advertise a keep-alive
+// interval, which all clients in low bandwidth mode do. The encoding is empty if it wasn't advertised.
+func LowBandwidthModeFrom(c Capabilities) (LowBandwidthMode, bool) {
+	secs, err := strconv.ParseInt(c.Get(CapabilityKeepAlive), 10, 64)
+	if err != nil || secs <= 0 {
+		return LowBandwidthMode{}, false
+	}
+
+	return LowBandwidthMode{
+		Encoding:   c.Get(CapabilityEncoding),
+		KeepAlive:  time.Duration(secs) * time.Second,
+		Conflation: c.Has(CapabilityConflation),
+	}, true
+}
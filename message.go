@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"text/template"
 	"time"
 	"unicode/utf8"
 	"unsafe"
@@ -25,6 +26,7 @@ var (
 	fieldBytesEvent   = []byte(parser.FieldNameEvent + ": ")
 	fieldBytesRetry   = []byte(parser.FieldNameRetry + ": ")
 	fieldBytesID      = []byte(parser.FieldNameID + ": ")
+	fieldBytesTrace   = []byte(parser.FieldNameTrace + ": ")
 	fieldBytesComment = []byte(": ")
 )
 
@@ -60,6 +62,16 @@ type Message struct {
 	ID    EventID
 	Type  EventType
 	Retry time.Duration
+	// The identity of the message's publisher, for example a user ID. It is not sent to clients.
+	// Sessions subscribed with NoEcho don't receive the messages whose Origin is their Identity.
+	Origin string
+	// The ID of the trace the message is part of, sent using the non-standard "trace" field,
+	// so clients can correlate the event with the publisher's trace. It is not sent if it
+	// spans multiple lines. See Server.PublishContext.
+	TraceID string
+
+	// The template the data is rendered with for each session. See TemplateMessage.
+	template *template.Template
 }
 
 func (e *Message) appendText(isComment bool, chunks ...string) {
@@ -148,6 +160,24 @@ func (e *Message) writeType(w io.Writer) (int64, error) {
 	return e.writeMessageField(w, e.Type.messageField, fieldBytesEvent)
 }
 
+func (e *Message) writeTrace(w io.Writer) (int64, error) {
+	if e.TraceID == "" || !isSingleLine(e.TraceID) {
+		return 0, nil
+	}
+
+	n, err := w.Write(fieldBytesTrace)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := writeString(w, e.TraceID)
+	n += m
+	if err != nil {
+		return int64(n), err
+	}
+	m, err = w.Write(newline)
+	return int64(n + m), err
+}
+
 func (e *Message) writeRetry(w io.Writer) (int64, error) {
 	millis := e.Retry.Milliseconds()
 	if millis <= 0 {
@@ -189,6 +219,11 @@ func (e *Message) WriteTo(w io.Writer) (int64, error) {
 	if err != nil {
 		return n, err
 	}
+	m, err = e.writeTrace(w)
+	n += m
+	if err != nil {
+		return n, err
+	}
 	m, err = e.writeRetry(w)
 	n += m
 	if err != nil {
@@ -269,6 +304,8 @@ func (e *Message) reset() {
 	e.Type = EventType{}
 	e.ID = EventID{}
 	e.Retry = 0
+	e.TraceID = ""
+	e.template = nil
 }
 
 // UnmarshalText extracts the first event found in the given byte slice into the
@@ -326,6 +363,9 @@ loop:
 
 			e.ID.value = f.Value
 			e.ID.set = true
+		case parser.FieldNameTrace:
+			e.TraceID = f.Value
+		case parser.FieldNameStream: // only used by clients
 		default: // event end
 			break loop
 		}
@@ -343,10 +383,13 @@ func (e *Message) Clone() *Message {
 	return &Message{
 		// The first AppendData will trigger a reallocation.
 		// Already appended chunks cannot be modified/removed, so this is safe.
-		chunks: e.chunks[:len(e.chunks):len(e.chunks)],
-		Retry:  e.Retry,
-		Type:   e.Type,
-		ID:     e.ID,
+		chunks:   e.chunks[:len(e.chunks):len(e.chunks)],
+		Retry:    e.Retry,
+		Type:     e.Type,
+		ID:       e.ID,
+		Origin:   e.Origin,
+		TraceID:  e.TraceID,
+		template: e.template,
 	}
 }
 
@@ -0,0 +1,50 @@
+package sse
+
+import "strings"
+
+// isPattern reports whether the given event type or topic is a pattern, that is, whether it contains a wildcard.
+func isPattern(s string) bool {
+	return strings.IndexByte(s, '*') != -1
+}
+
+// matchPattern reports whether s matches the pattern, where each '*' matches any sequence of
+// characters, including the empty one. For example, "orders.*" matches "orders.created" and
+// "orders.item.added", and "*.created" matches "orders.created". Patterns without wildcards
+// match only themselves.
+func matchPattern(pattern, s string) bool {
+	before, after, found := strings.Cut(pattern, "*")
+	if !found {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, before) {
+		return false
+	}
+	s = s[len(before):]
+
+	for {
+		var part string
+		part, after, found = strings.Cut(after, "*")
+		if !found {
+			// The last part must be at the end.
+			return len(s) >= len(part) && strings.HasSuffix(s, part)
+		}
+
+		// Matching the middle parts as early as possible leaves the most for the rest.
+		i := strings.Index(s, part)
+		if i == -1 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+}
+
+// removePattern removes the given pattern from the slice, if it is a pattern in it.
+func removePattern(patterns []string, pattern string) []string {
+	for i, p := range patterns {
+		if p == pattern {
+			return append(patterns[:i], patterns[i+1:]...)
+		}
+	}
+
+	return patterns
+}
@@ -0,0 +1,158 @@
+package sse
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/tmaxmax/go-sse/internal/msgpack"
+)
+
+// The capability and its values used to negotiate the encoding of event payloads.
+// Clients advertise the encoding they prefer, for example:
+//
+//	client := &sse.Client{Capabilities: sse.Capabilities{sse.CapabilityEncoding: sse.EncodingMsgpack}}
+//
+// Clients that don't advertise anything, such as browsers, receive JSON.
+const (
+	CapabilityEncoding = "encoding"
+
+	EncodingJSON    = "json"
+	EncodingMsgpack = "msgpack"
+)
+
+// msgpackPayloadPrefix marks data encoded as Base64 MessagePack. JSON values
+// and the Base64 alphabet don't contain it, so payloads can't be confused.
+const msgpackPayloadPrefix = "~"
+
+// PayloadEncoder adapts the encoding of JSON message data to each session's advertised
+// CapabilityEncoding. Messages are published once, with their data in JSON, and sessions
+// which prefer MessagePack receive the data encoded as Base64 MessagePack instead, which
+// saves bandwidth for numeric and deeply nested payloads. Comments and the other fields
+// are kept as they are. Messages whose data isn't valid JSON are sent unchanged.
+//
+// Set its OnSend method as the Server's OnSend callback:
+//
+//	enc := &sse.PayloadEncoder{}
+//	s := &sse.Server{OnSend: enc.OnSend}
+//
+// Clients decode the payloads with Event.DecodePayload, regardless of the encoding.
+// The zero value is ready to use. PayloadEncoder is safe for concurrent use.
+type PayloadEncoder struct {
+	mu sync.Mutex
+	// The last encoded message is cached, as it is usually sent to multiple sessions in a row.
+	last, lastEncoded *Message
+}
+
+// OnSend returns the message encoded for the given session. Its signature matches Server.OnSend.
+func (p *PayloadEncoder) OnSend(s *Session, m *Message) *Message {
+	if s.Capabilities.Get(CapabilityEncoding) != EncodingMsgpack {
+		return m
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.last != m {
+		p.last, p.lastEncoded = m, encodeMsgpackPayload(m)
+	}
+
+	return p.lastEncoded
+}
+
+func encodeMsgpackPayload(m *Message) *Message {
+	data, hasData := messageData(m)
+	if !hasData {
+		return m
+	}
+
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return m
+	}
+
+	b, err := msgpack.Append(nil, v)
+	if err != nil {
+		return m
+	}
+
+	return withData(m, msgpackPayloadPrefix+base64.StdEncoding.EncodeToString(b))
+}
+
+// messageData returns the message's data fields joined by newlines, as clients receive them,
+// and whether the message has any data.
+func messageData(m *Message) (string, bool) {
+	var data strings.Builder
+	hasData := false
+	for _, c := range m.chunks {
+		if c.isComment {
+			continue
+		}
+		if hasData {
+			data.WriteByte('\n')
+		}
+		data.WriteString(c.content)
+		hasData = true
+	}
+
+	return data.String(), hasData
+}
+
+// withData returns a copy of the message whose data is replaced with the given one.
+// The comments are kept.
+func withData(m *Message, data string) *Message {
+	replaced := m.Clone()
+	replaced.chunks = nil
+	for _, c := range m.chunks {
+		if c.isComment {
+			replaced.chunks = append(replaced.chunks, c)
+		}
+	}
+	replaced.AppendData(data)
+
+	return replaced
+}
+
+// DecodePayload decodes the event's data into v, as encoding/json.Unmarshal does, or using
+// the Codec of the connection's client, if it has one. The data can be either JSON or, if the
+// connection's client advertised EncodingMsgpack and the server uses a PayloadEncoder,
+// Base64 MessagePack.
+func (e Event) DecodePayload(v any) error {
+	codec := e.codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	if !strings.HasPrefix(e.Data, msgpackPayloadPrefix) {
+		return codec.Unmarshal(stringBytes(e.Data), v)
+	}
+
+	b, err := base64.StdEncoding.DecodeString(e.Data[len(msgpackPayloadPrefix):])
+	if err != nil {
+		return err
+	}
+
+	decoded, err := msgpack.Decode(b)
+	if err != nil {
+		return err
+	}
+
+	// The value is converted to JSON so v is filled in exactly as for JSON payloads,
+	// honoring its json struct tags and Unmarshaler implementations.
+	buf := payloadBuffers.Get().(*bytes.Buffer) //nolint:forcetypeassert // The pool only has buffers.
+	defer func() {
+		buf.Reset()
+		payloadBuffers.Put(buf)
+	}()
+
+	if err := json.NewEncoder(buf).Encode(decoded); err != nil {
+		return err
+	}
+
+	return codec.Unmarshal(buf.Bytes(), v)
+}
@@ -0,0 +1,114 @@
+package sse
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// TemplateMessage returns a copy of the message whose data is a text/template template, rendered
+// for each session by PayloadTemplates. This allows publishing a lightly personalized notification
+// once, instead of once for each user. Values interpolated into JSON payloads must be encoded using
+// the json template function, which quotes and escapes them:
+//
+//	m := &sse.Message{}
+//	m.AppendData(`{"text":{{printf "Hi %s, your order shipped!" .Name | json}}}`)
+//	tm, err := sse.TemplateMessage(m)
+//	// handle err
+//	s.Publish(tm, "orders")
+//
+// It returns an error if the template can't be parsed. Sessions of servers without PayloadTemplates
+// receive the template as is.
+func TemplateMessage(m *Message) (*Message, error) {
+	data, _ := messageData(m)
+
+	t, err := template.New("").Option("missingkey=error").Funcs(templateFuncs).Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	tm := m.Clone()
+	tm.template = t
+
+	return tm, nil
+}
+
+// templateFuncs are the functions available to the templates of TemplateMessage.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// PayloadTemplates renders the data of the messages created using TemplateMessage for each session,
+// against the session's metadata. The other messages are sent unchanged. Messages whose template
+// can't be rendered for a session are not sent to it.
+//
+// Set its OnSend method as the Server's OnSend callback:
+//
+//	tpl := &sse.PayloadTemplates{
+//		Data: func(s *sse.Session) any { return userFromContext(s.Req.Context()) },
+//		Key:  func(s *sse.Session) string { return userFromContext(s.Req.Context()).Locale },
+//	}
+//	s := &sse.Server{OnSend: tpl.OnSend}
+//
+// The rendered variants of the last message sent are cached by the sessions' Key, as a message
+// is usually sent to multiple sessions in a row. PayloadTemplates is safe for concurrent use.
+type PayloadTemplates struct {
+	// Data returns the value the templates are rendered against for the given session – for example,
+	// the user's name and locale. It is required.
+	Data func(*Session) any
+	// Key returns the key of the rendered variant for the given session. Sessions with the same key
+	// must render the same data, so a variant is rendered only once for all of them – for example,
+	// all the sessions of a user, or all the users with the same locale. If it is nil, the message
+	// is rendered for each session.
+	Key func(*Session) string
+
+	mu       sync.Mutex
+	last     *Message
+	variants map[string]*Message
+}
+
+// OnSend returns the message rendered for the given session. Its signature matches Server.OnSend.
+func (p *PayloadTemplates) OnSend(s *Session, m *Message) *Message {
+	if m.template == nil {
+		return m
+	}
+
+	if p.Key == nil {
+		return renderTemplate(m, p.Data(s))
+	}
+
+	key := p.Key(s)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.last != m {
+		p.last, p.variants = m, map[string]*Message{}
+	}
+
+	if rendered, ok := p.variants[key]; ok {
+		return rendered
+	}
+
+	rendered := renderTemplate(m, p.Data(s))
+	p.variants[key] = rendered
+
+	return rendered
+}
+
+// renderTemplate returns the message with the data rendered against the given value, or nil on error.
+func renderTemplate(m *Message, data any) *Message {
+	var sb strings.Builder
+	if err := m.template.Execute(&sb, data); err != nil {
+		return nil
+	}
+
+	rendered := withData(m, sb.String())
+	rendered.template = nil
+
+	return rendered
+}
@@ -0,0 +1,183 @@
+package sse_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestPayloadEncoder(t *testing.T) {
+	t.Parallel()
+
+	enc := &sse.PayloadEncoder{}
+	browser := &sse.Session{Capabilities: sse.Capabilities{}}
+	native := &sse.Session{Capabilities: sse.Capabilities{sse.CapabilityEncoding: sse.EncodingMsgpack}}
+
+	m := &sse.Message{Type: sse.Type("update")}
+	m.AppendComment("keep me")
+	m.AppendData(`{"id":42,"tags":["a","b"],"ratio":0.25}`)
+
+	require.Same(t, m, enc.OnSend(browser, m), "JSON sessions should receive the message as is")
+
+	encoded := enc.OnSend(native, m)
+	require.NotSame(t, m, encoded, "msgpack sessions should receive a different message")
+	require.Same(t, encoded, enc.OnSend(native, m), "encoded message should be cached")
+	require.Equal(t, "event: update\n: keep me\n", encoded.String()[:len("event: update\n: keep me\n")], "comments and fields should be kept")
+
+	type payload struct {
+		ID    int      `json:"id"`
+		Tags  []string `json:"tags"`
+		Ratio float64  `json:"ratio"`
+	}
+	expected := payload{ID: 42, Tags: []string{"a", "b"}, Ratio: 0.25}
+
+	for _, msg := range []*sse.Message{m, encoded} {
+		data := msg.String()
+		data = data[strings.Index(data, "data: ")+len("data: ") : len(data)-2]
+
+		var p payload
+		require.NoError(t, sse.Event{Data: data}.DecodePayload(&p), "unexpected decode error for %q", data)
+		require.Equal(t, expected, p, "invalid decoded payload for %q", data)
+	}
+
+	plain := &sse.Message{}
+	plain.AppendData("not json")
+	require.Same(t, plain, enc.OnSend(native, plain), "non-JSON data should be sent unchanged")
+}
+
+func TestPayloadTemplates(t *testing.T) {
+	t.Parallel()
+
+	type user struct{ Name, Locale string }
+	users := map[string]user{"ana": {"Ana", "ro"}, "bob": {"Bob", "en"}}
+	sess := func(name string) *sse.Session {
+		r := httptest.NewRequest(http.MethodGet, "/?user="+name, http.NoBody)
+		return &sse.Session{Req: r}
+	}
+
+	renders := 0
+	tpl := &sse.PayloadTemplates{
+		Data: func(s *sse.Session) any {
+			renders++
+			return users[s.Req.URL.Query().Get("user")]
+		},
+		Key: func(s *sse.Session) string { return s.Req.URL.Query().Get("user") },
+	}
+
+	m := &sse.Message{Type: sse.Type("notification")}
+	m.AppendComment("greeting")
+	m.AppendData("Hi {{.Name}}!", "Locale: {{.Locale}}")
+
+	tm, err := sse.TemplateMessage(m)
+	require.NoError(t, err, "unexpected TemplateMessage error")
+	require.Equal(t, m.String(), tm.String(), "template message should be sent as is without rendering")
+
+	require.Same(t, m, tpl.OnSend(sess("ana"), m), "plain messages should be sent unchanged")
+
+	ana := tpl.OnSend(sess("ana"), tm)
+	require.Equal(t, "event: notification\n: greeting\ndata: Hi Ana!\ndata: Locale: ro\n\n", ana.String(), "invalid rendered message")
+	require.Equal(t, "event: notification\n: greeting\ndata: Hi Bob!\ndata: Locale: en\n\n", tpl.OnSend(sess("bob"), tm).String(), "invalid rendered message")
+	require.Same(t, ana, tpl.OnSend(sess("ana"), tm), "rendered variant should be cached")
+	require.Equal(t, 2, renders, "each variant should be rendered once")
+
+	failing := &sse.PayloadTemplates{Data: func(*sse.Session) any { return map[string]string{} }}
+	require.Nil(t, failing.OnSend(sess("ana"), tm), "message should not be sent on render error")
+
+	jm := &sse.Message{}
+	jm.AppendData(`{"text":{{printf "Hi %s!" .Name | json}}}`)
+	jtm, err := sse.TemplateMessage(jm)
+	require.NoError(t, err, "unexpected TemplateMessage error")
+	quoting := &sse.PayloadTemplates{Data: func(*sse.Session) any { return user{Name: `"Ana" <3`} }}
+	require.Equal(t, "data: {\"text\":\"Hi \\\"Ana\\\" \\u003c3!\"}\n\n", quoting.OnSend(sess("ana"), jtm).String(), "interpolated values should be encoded as JSON")
+
+	_, err = sse.TemplateMessage(func() *sse.Message {
+		m := &sse.Message{}
+		m.AppendData("{{.Name")
+		return m
+	}())
+	require.Error(t, err, "invalid template should be rejected")
+}
+
+type countingCodec struct {
+	sse.JSONCodec
+	calls    atomic.Int32
+	marshals atomic.Int32
+}
+
+func (c *countingCodec) Marshal(v any) ([]byte, error) {
+	c.marshals.Add(1)
+	return c.JSONCodec.Marshal(v)
+}
+
+func (c *countingCodec) Unmarshal(data []byte, v any) error {
+	c.calls.Add(1)
+	return c.JSONCodec.Unmarshal(data, v)
+}
+
+func TestClient_Codec(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "data: {\"id\":42}\n\n")
+	}))
+	defer ts.Close()
+
+	codec := &countingCodec{}
+	c := &sse.Client{HTTPClient: ts.Client(), ResponseValidator: sse.NoopValidator, Codec: codec}
+	conn := c.NewConnection(req(t, "", ts.URL, nil))
+
+	var payload struct{ ID int }
+	conn.SubscribeMessages(func(e sse.Event) {
+		require.NoError(t, e.DecodePayload(&payload), "unexpected decode error")
+	})
+
+	require.ErrorIs(t, conn.Connect(), io.EOF, "unexpected Connect error")
+	require.Equal(t, 42, payload.ID, "invalid decoded payload")
+	require.Equal(t, int32(1), codec.calls.Load(), "client codec not used")
+}
+
+func TestMessage_AppendJSON(t *testing.T) {
+	t.Parallel()
+
+	type order struct {
+		ID   int    `json:"id"`
+		Note string `json:"note"`
+	}
+
+	codec := &countingCodec{}
+	m := &sse.Message{Type: sse.Type("order")}
+	require.NoError(t, m.AppendJSON(order{ID: 42, Note: "a\nb"}, codec), "unexpected encode error")
+	require.Equal(t, "event: order\ndata: {\"id\":42,\"note\":\"a\\nb\"}\n\n", m.String(), "invalid message")
+	require.Equal(t, int32(1), codec.marshals.Load(), "codec not used")
+
+	m = &sse.Message{}
+	require.NoError(t, m.AppendJSON(map[string]int{"id": 1}, nil), "unexpected encode error")
+	require.Equal(t, "data: {\"id\":1}\n\n", m.String(), "invalid message with the default codec")
+
+	require.Error(t, m.AppendJSON(func() {}, nil), "unencodable values should fail")
+
+	got, err := sse.DecodeJSON[order](sse.Event{Data: `{"id":42,"note":"a\nb"}`})
+	require.NoError(t, err, "unexpected decode error")
+	require.Equal(t, order{ID: 42, Note: "a\nb"}, got, "payload should round-trip")
+}
+
+func BenchmarkEvent_DecodePayload(b *testing.B) {
+	ev := sse.Event{Data: `{"id":42,"tags":["a","b"],"ratio":0.25}`}
+	var p struct {
+		ID    int
+		Tags  []string
+		Ratio float64
+	}
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		_ = ev.DecodePayload(&p)
+	}
+}
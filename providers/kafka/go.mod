@@ -0,0 +1,25 @@
+module github.com/tmaxmax/go-sse/providers/kafka
+
+go 1.21
+
+replace github.com/tmaxmax/go-sse => ../..
+
+require (
+	github.com/stretchr/testify v1.8.4
+	github.com/tmaxmax/go-sse v0.0.0-00010101000000-000000000000
+	github.com/twmb/franz-go v1.17.1
+	github.com/twmb/franz-go/pkg/kadm v1.13.0
+	github.com/twmb/franz-go/pkg/kfake v0.0.0-20241015013301-cea7aa5d8037
+)
+
+require (
+	github.com/cenkalti/backoff/v4 v4.2.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/klauspost/compress v1.17.8 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/twmb/franz-go/pkg/kmsg v1.8.0 // indirect
+	golang.org/x/crypto v0.23.0 // indirect
+	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
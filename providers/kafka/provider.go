@@ -0,0 +1,342 @@
+// Package kafka implements a go-sse Provider backed by a Kafka topic, for durable fan-out of events
+// to the clients of multiple server instances.
+//
+// Each server instance consumes all the partitions of the topic and sends the records, converted
+// to messages, to its sessions. The IDs of the messages hold the offsets of the records consumed
+// from each partition, so the sessions which reconnect to any instance are replayed the records
+// they missed from Kafka.
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// The headers of the records which the Provider and DecodeRecord understand.
+const (
+	// The content type of the record's value. If it is ContentTypeMessage, the value
+	// is the message's wire format; otherwise, it is the message's data.
+	HeaderContentType = "content-type"
+	// A topic to which the message is published. Records can have multiple topic headers.
+	// If they have none, the message is published to sse.DefaultTopic.
+	HeaderTopic = "sse-topic"
+	// The type of the message, for records whose value is the message's data.
+	HeaderType = "sse-event"
+	// The message's origin. See sse.Message.Origin.
+	HeaderOrigin = "sse-origin"
+)
+
+// The defaults of the Provider's replay limits. Each replay creates a Kafka client, so it is given more time
+// than a single request would need.
+const (
+	DefaultReplayLimit   = 1000
+	DefaultReplayTimeout = 2 * time.Second
+)
+
+// ContentTypeMessage is the content type of the records whose value is a message in the wire format,
+// such as the ones produced by Provider.Publish.
+const ContentTypeMessage = "text/event-stream"
+
+// Provider is a go-sse Provider which republishes the records of a Kafka topic as messages. Each server
+// instance consumes all the partitions of the topic, without a consumer group, starting with the records
+// produced after the Provider is first used, and sends them to its own sessions using a Joe. Partitions
+// added afterwards are not consumed.
+//
+// The ID of each message lists the offset of the record last sent from each partition, up to and including
+// the message's record – for example, "0:15,1:42". The sessions which reconnect with such a last event ID,
+// or with a replay time (see sse.Session.ReplaySince), are replayed the records after it, which may
+// include records delivered already, if the session subscribed while they were consumed.
+//
+// Records can be produced by any Kafka producer – see DecodeRecord for how they are converted to messages,
+// or use Decode to convert them yourself. The Provider connects to Kafka when it is first used; if that
+// fails, Subscribe and Publish return the error, and the Provider tries to connect again the next time
+// it is used. The Provider must not be copied after it is used.
+//
+// The sessions are replayed from the Joe's goroutine, which doesn't deliver messages in the meantime,
+// so each replay reads at most ReplayLimit records from each partition and waits for Kafka at most
+// ReplayTimeout.
+type Provider struct {
+	// The seed brokers of the Kafka cluster. Required.
+	Brokers []string
+	// The Kafka topic. Required.
+	Topic string
+	// Additional options for the Kafka clients created by the Provider, for example for TLS or SASL.
+	ClientOptions []kgo.Opt
+	// Decode converts the records to messages and the topics they are published to.
+	// Defaults to DecodeRecord. Records which fail to decode are skipped.
+	Decode func(*kgo.Record) (*sse.Message, []string, error)
+	// The maximum number of records replayed to a session from each partition, which are the latest
+	// ones it missed. Defaults to DefaultReplayLimit. If it is negative, the number of records is not limited.
+	ReplayLimit int64
+	// How long a replay waits for Kafka. Defaults to DefaultReplayTimeout.
+	ReplayTimeout time.Duration
+	// An optional reporter for the errors which can't be returned, such as the records which
+	// fail to decode, and for the panics recovered by the Joe provider used.
+	ErrorReporter sse.ErrorReporter
+
+	client  *kgo.Client
+	admin   *kadm.Client
+	joe     *sse.Joe
+	cancel  context.CancelFunc
+	stopped chan struct{}
+	done    chan struct{}
+	mu      sync.Mutex
+	stop    sync.Once
+}
+
+// Subscribe subscribes the session to the instance's Joe, replaying the records it missed from Kafka.
+func (p *Provider) Subscribe(ctx context.Context, sub sse.Subscription) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	return p.joe.Subscribe(ctx, sub)
+}
+
+// Publish produces a record with the message to the Kafka topic, from which all the server instances
+// consume it. The message's ID is ignored: the instances set it to the offset of the record.
+func (p *Provider) Publish(m *sse.Message, topics []string) error {
+	if len(topics) == 0 {
+		return sse.ErrNoTopic
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-p.done:
+		return sse.ErrProviderClosed
+	default:
+	}
+
+	unidentified := m.Clone()
+	unidentified.ID = sse.EventID{}
+	text, err := unidentified.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	r := &kgo.Record{Topic: p.Topic, Value: text}
+	r.Headers = append(r.Headers, kgo.RecordHeader{Key: HeaderContentType, Value: []byte(ContentTypeMessage)})
+	for _, topic := range topics {
+		r.Headers = append(r.Headers, kgo.RecordHeader{Key: HeaderTopic, Value: []byte(topic)})
+	}
+	if m.Origin != "" {
+		r.Headers = append(r.Headers, kgo.RecordHeader{Key: HeaderOrigin, Value: []byte(m.Origin)})
+	}
+
+	if err := p.client.ProduceSync(context.Background(), r).FirstErr(); err != nil {
+		return fmt.Errorf("go-sse.kafka: produce record: %w", err)
+	}
+
+	return nil
+}
+
+// Shutdown stops consuming the topic and closes the Kafka clients and the instance's sessions.
+// See sse.Joe.Shutdown for more information.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	p.stop.Do(func() {
+		close(p.done)
+		p.cancel()
+		<-p.stopped
+		p.client.Close()
+		p.admin.Close()
+	})
+
+	return p.joe.Shutdown(ctx)
+}
+
+// Healthy reports whether the instance is not shut down. It implements sse.HealthChecker.
+func (p *Provider) Healthy() error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	return p.joe.Healthy()
+}
+
+// DecodeRecord is the default conversion of records to messages. If the record's content type
+// is ContentTypeMessage, its value is parsed as a message in the wire format. Otherwise, the value
+// is the message's data and the message's type is the value of the HeaderType header, if valid.
+// The message is published to the topics in the HeaderTopic headers, or to sse.DefaultTopic
+// if there are none, and its Origin is the value of the HeaderOrigin header.
+func DecodeRecord(r *kgo.Record) (*sse.Message, []string, error) {
+	m := &sse.Message{}
+
+	var (
+		topics      []string
+		contentType string
+	)
+	for _, h := range r.Headers {
+		switch h.Key {
+		case HeaderContentType:
+			contentType = string(h.Value)
+		case HeaderTopic:
+			topics = append(topics, string(h.Value))
+		case HeaderType:
+			if typ, err := sse.NewType(string(h.Value)); err == nil {
+				m.Type = typ
+			}
+		case HeaderOrigin:
+			m.Origin = string(h.Value)
+		}
+	}
+
+	if contentType == ContentTypeMessage {
+		origin := m.Origin
+		if err := m.UnmarshalText(r.Value); err != nil {
+			return nil, nil, err
+		}
+		m.Origin = origin
+	} else {
+		m.AppendData(string(r.Value))
+	}
+
+	if len(topics) == 0 {
+		topics = []string{sse.DefaultTopic}
+	}
+
+	return m, topics, nil
+}
+
+func (p *Provider) start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The Provider is started once it has a Joe; until then, each use tries to start it.
+	if p.joe != nil {
+		return nil
+	}
+
+	return p.connect()
+}
+
+func (p *Provider) connect() error {
+	if len(p.Brokers) == 0 || p.Topic == "" {
+		return errors.New("go-sse.kafka: no brokers or topic")
+	}
+
+	opts := append([]kgo.Opt{kgo.SeedBrokers(p.Brokers...)}, p.ClientOptions...)
+
+	admin, err := kadm.NewOptClient(opts...)
+	if err != nil {
+		return fmt.Errorf("go-sse.kafka: create client: %w", err)
+	}
+	p.admin = admin
+
+	ctx := context.Background()
+
+	// The instance consumes the records produced after this point, and the ones before are replayed.
+	ends, err := admin.ListEndOffsets(ctx, p.Topic)
+	if err == nil {
+		err = ends.Error()
+	}
+	if err != nil {
+		admin.Close()
+		return fmt.Errorf("go-sse.kafka: list offsets: %w", err)
+	}
+
+	last := position{}
+	start := map[int32]kgo.Offset{}
+	ends.Each(func(o kadm.ListedOffset) {
+		last[o.Partition] = o.Offset - 1
+		start[o.Partition] = kgo.NewOffset().At(o.Offset)
+	})
+
+	client, err := kgo.NewClient(append(opts, kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{p.Topic: start}))...)
+	if err != nil {
+		admin.Close()
+		return fmt.Errorf("go-sse.kafka: create client: %w", err)
+	}
+	p.client = client
+
+	p.done = make(chan struct{})
+	p.joe = &sse.Joe{
+		ReplayProvider: &topicReplay{p: p, last: last},
+		ErrorReporter:  p.ErrorReporter,
+	}
+
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.stopped = make(chan struct{})
+	go p.consume(ctx)
+
+	return nil
+}
+
+// consume sends the records consumed from the topic to the instance's sessions.
+func (p *Provider) consume(ctx context.Context) {
+	defer close(p.stopped)
+
+	for {
+		fetches := p.client.PollFetches(ctx)
+		if ctx.Err() != nil || fetches.IsClientClosed() {
+			return
+		}
+
+		fetches.EachError(func(_ string, partition int32, err error) {
+			if !errors.Is(err, context.Canceled) {
+				p.report(fmt.Errorf("go-sse.kafka: fetch partition %d: %w", partition, err))
+			}
+		})
+
+		fetches.EachRecord(func(r *kgo.Record) {
+			m, topics, err := p.decode(r)
+			if err != nil {
+				p.report(err)
+				return
+			}
+
+			// The Joe's replay provider replaces the record's position with the message's ID.
+			m.ID = sse.ID(position{r.Partition: r.Offset}.String())
+
+			if err := p.joe.Publish(m, topics); err != nil && !errors.Is(err, sse.ErrProviderClosed) {
+				p.report(err)
+			}
+		})
+	}
+}
+
+func (p *Provider) decode(r *kgo.Record) (*sse.Message, []string, error) {
+	decode := p.Decode
+	if decode == nil {
+		decode = DecodeRecord
+	}
+
+	m, topics, err := decode(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("go-sse.kafka: invalid record %d:%d: %w", r.Partition, r.Offset, err)
+	}
+
+	return m, topics, nil
+}
+
+func (p *Provider) replayLimit() int64 {
+	if p.ReplayLimit == 0 {
+		return DefaultReplayLimit
+	}
+	return p.ReplayLimit
+}
+
+func (p *Provider) replayTimeout() time.Duration {
+	if p.ReplayTimeout <= 0 {
+		return DefaultReplayTimeout
+	}
+	return p.ReplayTimeout
+}
+
+func (p *Provider) report(err error) {
+	if p.ErrorReporter != nil {
+		p.ErrorReporter.ReportError(context.Background(), err)
+	}
+}
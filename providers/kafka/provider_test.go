@@ -0,0 +1,190 @@
+package kafka_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/providers/kafka"
+	"github.com/twmb/franz-go/pkg/kadm"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type mockClient func(m *sse.Message) error
+
+func (c mockClient) Send(m *sse.Message) error { return c(m) }
+func (c mockClient) Flush() error              { return nil }
+
+func subscribe(t *testing.T, p *kafka.Provider, lastEventID string, topics ...string) <-chan *sse.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ch := make(chan *sse.Message, 16)
+	sub := sse.Subscription{
+		Client: mockClient(func(m *sse.Message) error {
+			ch <- m
+			return nil
+		}),
+		Topics: topics,
+	}
+	if lastEventID != "" {
+		sub.LastEventID = sse.ID(lastEventID)
+	}
+
+	go func() { _ = p.Subscribe(ctx, sub) }()
+
+	return ch
+}
+
+func receive(t *testing.T, ch <-chan *sse.Message) *sse.Message {
+	t.Helper()
+
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(time.Second * 5):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func text(m *sse.Message) string {
+	s, _ := m.MarshalText()
+	return string(s)
+}
+
+func TestProvider(t *testing.T) {
+	t.Parallel()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "events"))
+	require.NoError(t, err, "failed to start cluster")
+	t.Cleanup(cluster.Close)
+
+	a := &kafka.Provider{Brokers: cluster.ListenAddrs(), Topic: "events"}
+	b := &kafka.Provider{Brokers: cluster.ListenAddrs(), Topic: "events"}
+	t.Cleanup(func() {
+		_ = a.Shutdown(context.Background())
+		_ = b.Shutdown(context.Background())
+	})
+
+	require.NoError(t, b.Healthy(), "provider should be healthy")
+
+	live := subscribe(t, b, "", "orders")
+	// Give the subscription time to be registered.
+	time.Sleep(time.Millisecond * 50)
+
+	first := &sse.Message{Origin: "alice"}
+	first.AppendData("first")
+	require.NoError(t, a.Publish(first, []string{"orders"}), "unexpected publish error")
+
+	other := &sse.Message{}
+	other.AppendData("other")
+	require.NoError(t, a.Publish(other, []string{"payments"}), "unexpected publish error")
+
+	// Records produced by other producers are converted too.
+	producer, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	require.NoError(t, err, "failed to create producer")
+	t.Cleanup(producer.Close)
+
+	record := &kgo.Record{Topic: "events", Value: []byte("second"), Headers: []kgo.RecordHeader{
+		{Key: kafka.HeaderTopic, Value: []byte("orders")},
+		{Key: kafka.HeaderType, Value: []byte("order")},
+	}}
+	require.NoError(t, producer.ProduceSync(context.Background(), record).FirstErr(), "unexpected produce error")
+
+	got := receive(t, live)
+	require.Equal(t, "alice", got.Origin, "origin should be kept")
+	require.Equal(t, "id: 0:0\ndata: first\n\n", text(got))
+
+	got = receive(t, live)
+	require.Equal(t, "id: 0:2\nevent: order\ndata: second\n\n", text(got), "messages of other topics should not be received")
+
+	// Wait for the other instance to consume the records, so they are replayed rather than sent live.
+	time.Sleep(time.Millisecond * 200)
+
+	// A session reconnecting to the other instance is replayed what it missed.
+	replayed := subscribe(t, a, "0:0", "orders")
+	require.Equal(t, "id: 0:2\nevent: order\ndata: second\n\n", text(receive(t, replayed)), "missed record should be replayed")
+
+	require.ErrorIs(t, a.Publish(first, nil), sse.ErrNoTopic)
+	require.NoError(t, a.Shutdown(context.Background()), "unexpected shutdown error")
+	require.ErrorIs(t, a.Publish(first, []string{"orders"}), sse.ErrProviderClosed)
+}
+
+func TestDecodeRecord(t *testing.T) {
+	t.Parallel()
+
+	m, topics, err := kafka.DecodeRecord(&kgo.Record{Value: []byte("hello")})
+	require.NoError(t, err, "unexpected decode error")
+	require.Equal(t, []string{sse.DefaultTopic}, topics, "records without topics should use the default one")
+	require.Equal(t, "data: hello\n\n", text(m))
+
+	_, _, err = kafka.DecodeRecord(&kgo.Record{Headers: []kgo.RecordHeader{{Key: kafka.HeaderContentType, Value: []byte(kafka.ContentTypeMessage)}}})
+	require.Error(t, err, "empty messages should fail to decode")
+}
+
+func TestProvider_NoBrokers(t *testing.T) {
+	t.Parallel()
+
+	p := &kafka.Provider{}
+	require.Error(t, p.Publish(&sse.Message{}, []string{"topic"}), "provider without brokers should fail")
+}
+
+func TestProvider_retryStart(t *testing.T) {
+	t.Parallel()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1))
+	require.NoError(t, err, "failed to start cluster")
+	t.Cleanup(cluster.Close)
+
+	p := &kafka.Provider{Brokers: cluster.ListenAddrs(), Topic: "events"}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	require.Error(t, p.Healthy(), "provider should fail to start without the topic")
+
+	admin, err := kgo.NewClient(kgo.SeedBrokers(cluster.ListenAddrs()...))
+	require.NoError(t, err, "failed to create client")
+	t.Cleanup(admin.Close)
+
+	_, err = kadm.NewClient(admin).CreateTopic(context.Background(), 1, 1, nil, "events")
+	require.NoError(t, err, "failed to create topic")
+	require.NoError(t, p.Healthy(), "provider should start once the topic exists")
+}
+
+func TestProvider_ReplayLimit(t *testing.T) {
+	t.Parallel()
+
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "events"))
+	require.NoError(t, err, "failed to start cluster")
+	t.Cleanup(cluster.Close)
+
+	p := &kafka.Provider{Brokers: cluster.ListenAddrs(), Topic: "events", ReplayLimit: 2}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	live := subscribe(t, p, "", "orders")
+	time.Sleep(time.Millisecond * 50)
+
+	for _, s := range []string{"first", "second", "third", "fourth"} {
+		m := &sse.Message{}
+		m.AppendData(s)
+		require.NoError(t, p.Publish(m, []string{"orders"}), "unexpected publish error")
+	}
+	for i := 0; i < 4; i++ {
+		receive(t, live)
+	}
+
+	replayed := subscribe(t, p, "0:0", "orders")
+	require.Equal(t, "id: 0:2\ndata: third\n\n", text(receive(t, replayed)), "the latest records should be replayed")
+	require.Equal(t, "id: 0:3\ndata: fourth\n\n", text(receive(t, replayed)), "the latest records should be replayed")
+
+	select {
+	case m := <-replayed:
+		t.Fatalf("unexpected message %q", text(m))
+	case <-time.After(time.Millisecond * 100):
+	}
+}
@@ -0,0 +1,229 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// position is the offset of the last record sent from each partition.
+type position map[int32]int64
+
+// String formats the position as "partition:offset" pairs ordered by partition, separated by commas.
+// Partitions with no record sent are omitted.
+func (p position) String() string {
+	partitions := make([]int32, 0, len(p))
+	for partition, offset := range p {
+		if offset >= 0 {
+			partitions = append(partitions, partition)
+		}
+	}
+	sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+	var sb strings.Builder
+	for i, partition := range partitions {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatInt(int64(partition), 10))
+		sb.WriteByte(':')
+		sb.WriteString(strconv.FormatInt(p[partition], 10))
+	}
+
+	return sb.String()
+}
+
+func parsePosition(s string) (position, bool) {
+	p := position{}
+	if s == "" {
+		return p, true
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		partitionPart, offsetPart, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, false
+		}
+
+		partition, err := strconv.ParseInt(partitionPart, 10, 32)
+		if err != nil || partition < 0 {
+			return nil, false
+		}
+		offset, err := strconv.ParseInt(offsetPart, 10, 64)
+		if err != nil || offset < 0 {
+			return nil, false
+		}
+
+		p[int32(partition)] = offset
+	}
+
+	return p, true
+}
+
+// topicReplay replays the records from the Provider's topic. It is used by the Provider's Joe,
+// so its methods are called from the Joe's goroutine only.
+type topicReplay struct {
+	p *Provider
+	// The offsets of the last records sent by the instance. Records after them will be sent
+	// to the sessions by the Joe, so they are not replayed.
+	last position
+}
+
+var _ sse.ReplayProviderWithSince = (*topicReplay)(nil)
+
+// Put records the offset of the message's record, which is its ID, and replaces the ID
+// with the instance's position.
+func (r *topicReplay) Put(m *sse.Message, _ []string) *sse.Message {
+	if record, ok := parsePosition(m.ID.String()); ok {
+		for partition, offset := range record {
+			if offset > r.last[partition] {
+				r.last[partition] = offset
+			}
+		}
+	}
+
+	m.ID = sse.ID(r.last.String())
+
+	return m
+}
+
+// Replay sends the records after the subscription's last event ID. If the ID isn't a position,
+// nothing is replayed.
+func (r *topicReplay) Replay(sub sse.Subscription) error {
+	if !sub.LastEventID.IsSet() {
+		return nil
+	}
+
+	cursor, ok := parsePosition(sub.LastEventID.String())
+	if !ok {
+		return nil
+	}
+
+	from := map[int32]int64{}
+	for partition := range r.last {
+		if offset, ok := cursor[partition]; ok {
+			from[partition] = offset + 1
+		} else {
+			from[partition] = 0
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.p.replayTimeout())
+	defer cancel()
+
+	return r.replay(ctx, sub, cursor, from)
+}
+
+// ReplaySince sends the records produced at or after the given time.
+func (r *topicReplay) ReplaySince(sub sse.Subscription, since time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.p.replayTimeout())
+	defer cancel()
+
+	offsets, err := r.p.admin.ListOffsetsAfterMilli(ctx, since.UnixMilli(), r.p.Topic)
+	if err == nil {
+		err = offsets.Error()
+	}
+	if err != nil {
+		return fmt.Errorf("go-sse.kafka: list offsets: %w", err)
+	}
+
+	cursor, from := position{}, map[int32]int64{}
+	for _, o := range offsets[r.p.Topic] {
+		cursor[o.Partition] = o.Offset - 1
+		from[o.Partition] = o.Offset
+	}
+
+	return r.replay(ctx, sub, cursor, from)
+}
+
+// replay sends the records from the given offsets. The records may be gone because of the topic's
+// retention or compaction, so it waits for them only until the context is done.
+func (r *topicReplay) replay(ctx context.Context, sub sse.Subscription, cursor position, from map[int32]int64) error {
+	limit := r.p.replayLimit()
+	start := map[int32]kgo.Offset{}
+	for partition, offset := range from {
+		if last, ok := r.last[partition]; ok && offset <= last {
+			// Skip the oldest records which are over the limit.
+			if limit > 0 && last-offset >= limit {
+				offset = last - limit + 1
+			}
+			start[partition] = kgo.NewOffset().At(offset)
+		}
+	}
+	if len(start) == 0 {
+		return nil
+	}
+
+	opts := append([]kgo.Opt{kgo.SeedBrokers(r.p.Brokers...)}, r.p.ClientOptions...)
+	client, err := kgo.NewClient(append(opts, kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{r.p.Topic: start}))...)
+	if err != nil {
+		return fmt.Errorf("go-sse.kafka: create client: %w", err)
+	}
+	defer client.Close()
+
+	sent := false
+	for len(start) > 0 {
+		fetches := client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			r.p.report(fmt.Errorf("go-sse.kafka: replay: %w", ctx.Err()))
+			break
+		}
+
+		var sendErr error
+		fetches.EachRecord(func(rec *kgo.Record) {
+			if sendErr != nil {
+				return
+			}
+			if rec.Offset >= r.last[rec.Partition] {
+				delete(start, rec.Partition)
+				if rec.Offset > r.last[rec.Partition] {
+					return
+				}
+			}
+			cursor[rec.Partition] = rec.Offset
+
+			m, topics, err := r.p.decode(rec)
+			if err != nil {
+				r.p.report(err)
+				return
+			}
+			if !topicsIntersect(sub.Topics, topics) {
+				return
+			}
+
+			m.ID = sse.ID(cursor.String())
+			if sendErr = sub.Client.Send(m); sendErr == nil {
+				sent = true
+			}
+		})
+
+		if sendErr != nil {
+			return sendErr
+		}
+	}
+
+	if !sent {
+		return nil
+	}
+
+	return sub.Client.Flush()
+}
+
+// topicsIntersect returns true if the given topic slices have at least one topic in common.
+func topicsIntersect(a, b []string) bool {
+	for _, at := range a {
+		for _, bt := range b {
+			if at == bt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,277 @@
+// Package nats implements a go-sse Provider backed by NATS JetStream, for deployments with multiple server instances.
+//
+// The published messages are stored in a JetStream stream, which all the instances consume, so they share
+// the same event log. The IDs of the messages are their sequence numbers in the stream, so the sessions
+// are replayed the messages they missed from the stream, regardless of the instance they reconnect to.
+package nats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tmaxmax/go-sse"
+)
+
+// The defaults of the Provider's configuration.
+const (
+	DefaultStream  = "SSE"
+	DefaultSubject = "sse.messages"
+	DefaultMaxMsgs = 10000
+
+	DefaultReplayLimit   = 1000
+	DefaultReplayTimeout = time.Second
+)
+
+// The headers of the stream's messages which hold the information not sent to clients.
+const (
+	headerTopic  = "Sse-Topic"
+	headerOrigin = "Sse-Origin"
+)
+
+// Provider is a go-sse Provider which shares the published messages between the server instances using
+// NATS JetStream. Each instance consumes the Stream with an ordered consumer and sends the messages to its
+// own sessions using a Joe. The sessions which reconnect with a last event ID, which is a stream sequence
+// number, or with a replay time (see sse.Session.ReplaySince) are replayed the messages from the Stream.
+//
+// If the Stream doesn't exist, it is created with the Subject and MaxMsgs; otherwise its configuration
+// is left as is, and the Subject must be one of its subjects. Messages may be delivered twice to a session
+// which subscribes while they are being published. The Provider connects to JetStream when it is used for
+// the first time; if that fails, Subscribe and Publish return the error, and the Provider tries to connect
+// again the next time it is used. The Provider must not be copied after it is used.
+//
+// The sessions are replayed from the Joe's goroutine, which doesn't deliver messages in the meantime,
+// so each replay reads at most ReplayLimit messages and waits for JetStream at most ReplayTimeout.
+type Provider struct {
+	// The NATS connection. It is not closed by Shutdown. Required.
+	Conn *nats.Conn
+	// The name of the stream which stores the messages. Defaults to DefaultStream.
+	Stream string
+	// The subject to which messages are published. Defaults to DefaultSubject.
+	Subject string
+	// The maximum number of messages kept by the stream, if the Provider creates it.
+	// Defaults to DefaultMaxMsgs. If it is negative, the number of messages is not limited.
+	MaxMsgs int64
+	// The maximum number of messages replayed to a session. The sessions reconnecting with a last event ID
+	// are replayed the latest messages they missed, and the ones replayed since a time are replayed the
+	// earliest messages after it. Defaults to DefaultReplayLimit. If it is negative, the number of messages
+	// is not limited.
+	ReplayLimit int
+	// How long a replay waits for JetStream. Defaults to DefaultReplayTimeout.
+	ReplayTimeout time.Duration
+	// An optional reporter for the errors which can't be returned, such as invalid messages
+	// consumed from the stream, and for the panics recovered by the Joe provider used.
+	ErrorReporter sse.ErrorReporter
+
+	js       jetstream.JetStream
+	joe      *sse.Joe
+	consumer jetstream.ConsumeContext
+	done     chan struct{}
+	mu       sync.Mutex
+}
+
+// Subscribe subscribes the session to the instance's Joe, replaying the messages it missed from the stream.
+func (p *Provider) Subscribe(ctx context.Context, sub sse.Subscription) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	return p.joe.Subscribe(ctx, sub)
+}
+
+// Publish stores the message in the stream, from which all the server instances consume it.
+// The message's ID is replaced with its sequence number in the stream.
+func (p *Provider) Publish(m *sse.Message, topics []string) error {
+	if len(topics) == 0 {
+		return sse.ErrNoTopic
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-p.done:
+		return sse.ErrProviderClosed
+	default:
+	}
+
+	unidentified := m.Clone()
+	unidentified.ID = sse.EventID{}
+	text, err := unidentified.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	msg := nats.NewMsg(p.subject())
+	msg.Data = text
+	msg.Header[headerTopic] = topics
+	if m.Origin != "" {
+		msg.Header.Set(headerOrigin, m.Origin)
+	}
+
+	if _, err := p.js.PublishMsg(context.Background(), msg); err != nil {
+		return fmt.Errorf("go-sse.nats: publish message: %w", err)
+	}
+
+	return nil
+}
+
+// Shutdown stops consuming the stream and closes the instance's sessions.
+// See sse.Joe.Shutdown for more information.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+		p.consumer.Stop()
+	}
+
+	return p.joe.Shutdown(ctx)
+}
+
+// Healthy reports whether the NATS connection is connected and the instance is not shut down.
+// It implements sse.HealthChecker.
+func (p *Provider) Healthy() error {
+	if err := p.start(); err != nil {
+		return err
+	}
+	if err := p.joe.Healthy(); err != nil {
+		return err
+	}
+	if !p.Conn.IsConnected() {
+		return fmt.Errorf("go-sse.nats: connection %s", p.Conn.Status())
+	}
+
+	return nil
+}
+
+func (p *Provider) start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The Provider is started once it has a Joe; until then, each use tries to start it.
+	if p.joe != nil {
+		return nil
+	}
+
+	return p.connect()
+}
+
+func (p *Provider) connect() error {
+	if p.Conn == nil {
+		return errors.New("go-sse.nats: no connection")
+	}
+
+	js, err := jetstream.New(p.Conn)
+	if err != nil {
+		return fmt.Errorf("go-sse.nats: %w", err)
+	}
+	p.js = js
+
+	ctx := context.Background()
+
+	stream, err := js.Stream(ctx, p.stream())
+	if errors.Is(err, jetstream.ErrStreamNotFound) {
+		stream, err = js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:     p.stream(),
+			Subjects: []string{p.subject()},
+			MaxMsgs:  p.maxMsgs(),
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("go-sse.nats: get stream: %w", err)
+	}
+
+	// The instance consumes the messages published after this point,
+	// and the ones before are replayed.
+	last := stream.CachedInfo().State.LastSeq
+
+	cons, err := js.OrderedConsumer(ctx, p.stream(), jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{p.subject()},
+		DeliverPolicy:  jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:    last + 1,
+	})
+	if err != nil {
+		return fmt.Errorf("go-sse.nats: create consumer: %w", err)
+	}
+
+	p.done = make(chan struct{})
+	p.joe = &sse.Joe{
+		ReplayProvider: &streamReplay{p: p, last: last},
+		ErrorReporter:  p.ErrorReporter,
+	}
+
+	p.consumer, err = cons.Consume(p.receive)
+	if err != nil {
+		_ = p.joe.Shutdown(ctx)
+		p.joe = nil
+		return fmt.Errorf("go-sse.nats: consume stream: %w", err)
+	}
+
+	return nil
+}
+
+// receive sends the messages consumed from the stream to the instance's sessions.
+func (p *Provider) receive(msg jetstream.Msg) {
+	m, topics, err := decodeMessage(msg)
+	if err != nil {
+		p.report(err)
+		return
+	}
+
+	if err := p.joe.Publish(m, topics); err != nil && !errors.Is(err, sse.ErrProviderClosed) {
+		p.report(err)
+	}
+}
+
+func (p *Provider) report(err error) {
+	if p.ErrorReporter != nil {
+		p.ErrorReporter.ReportError(context.Background(), err)
+	}
+}
+
+func (p *Provider) stream() string {
+	if p.Stream != "" {
+		return p.Stream
+	}
+	return DefaultStream
+}
+
+func (p *Provider) subject() string {
+	if p.Subject != "" {
+		return p.Subject
+	}
+	return DefaultSubject
+}
+
+func (p *Provider) maxMsgs() int64 {
+	if p.MaxMsgs == 0 {
+		return DefaultMaxMsgs
+	}
+	if p.MaxMsgs < 0 {
+		return -1
+	}
+	return p.MaxMsgs
+}
+
+func (p *Provider) replayLimit() int {
+	if p.ReplayLimit == 0 {
+		return DefaultReplayLimit
+	}
+	return p.ReplayLimit
+}
+
+func (p *Provider) replayTimeout() time.Duration {
+	if p.ReplayTimeout <= 0 {
+		return DefaultReplayTimeout
+	}
+	return p.ReplayTimeout
+}
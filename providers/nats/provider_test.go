@@ -0,0 +1,186 @@
+package nats_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	ssenats "github.com/tmaxmax/go-sse/providers/nats"
+)
+
+type mockClient func(m *sse.Message) error
+
+func (c mockClient) Send(m *sse.Message) error { return c(m) }
+func (c mockClient) Flush() error              { return nil }
+
+func runServer(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{Port: -1, JetStream: true, StoreDir: t.TempDir()})
+	require.NoError(t, err, "failed to create server")
+
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(time.Second*5), "server not ready")
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err, "failed to connect")
+	t.Cleanup(conn.Close)
+
+	return conn
+}
+
+func subscribe(t *testing.T, p *ssenats.Provider, lastEventID string, topics ...string) <-chan *sse.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ch := make(chan *sse.Message, 16)
+	sub := sse.Subscription{
+		Client: mockClient(func(m *sse.Message) error {
+			ch <- m
+			return nil
+		}),
+		Topics: topics,
+	}
+	if lastEventID != "" {
+		sub.LastEventID = sse.ID(lastEventID)
+	}
+
+	go func() { _ = p.Subscribe(ctx, sub) }()
+
+	return ch
+}
+
+func receive(t *testing.T, ch <-chan *sse.Message) *sse.Message {
+	t.Helper()
+
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(time.Second * 2):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func text(m *sse.Message) string {
+	s, _ := m.MarshalText()
+	return string(s)
+}
+
+func TestProvider(t *testing.T) {
+	t.Parallel()
+
+	conn := runServer(t)
+
+	a := &ssenats.Provider{Conn: conn}
+	b := &ssenats.Provider{Conn: conn}
+	t.Cleanup(func() {
+		_ = a.Shutdown(context.Background())
+		_ = b.Shutdown(context.Background())
+	})
+
+	require.NoError(t, b.Healthy(), "provider should be healthy")
+
+	live := subscribe(t, b, "", "orders")
+	// Give the subscription time to be registered.
+	time.Sleep(time.Millisecond * 50)
+
+	first := &sse.Message{Origin: "alice"}
+	first.AppendData("first")
+	require.NoError(t, a.Publish(first, []string{"orders"}), "unexpected publish error")
+
+	other := &sse.Message{}
+	other.AppendData("other")
+	require.NoError(t, a.Publish(other, []string{"payments"}), "unexpected publish error")
+
+	second := &sse.Message{}
+	second.AppendData("second")
+	require.NoError(t, a.Publish(second, []string{"orders"}), "unexpected publish error")
+
+	got := receive(t, live)
+	require.Equal(t, "1", got.ID.String(), "message should have the stream sequence as ID")
+	require.Equal(t, "alice", got.Origin, "origin should be kept")
+	require.Equal(t, "id: 1\ndata: first\n\n", text(got))
+
+	got = receive(t, live)
+	require.Equal(t, "id: 3\ndata: second\n\n", text(got), "messages of other topics should not be received")
+
+	// Wait for the other instance to consume the messages, so they are replayed rather than sent live.
+	time.Sleep(time.Millisecond * 100)
+
+	// A session reconnecting to the other instance is replayed what it missed.
+	replayed := subscribe(t, a, "1", "orders")
+	require.Equal(t, "id: 3\ndata: second\n\n", text(receive(t, replayed)), "missed message should be replayed")
+
+	require.ErrorIs(t, a.Publish(first, nil), sse.ErrNoTopic)
+	require.NoError(t, a.Shutdown(context.Background()), "unexpected shutdown error")
+	require.ErrorIs(t, a.Publish(first, []string{"orders"}), sse.ErrProviderClosed)
+}
+
+func TestProvider_NoConn(t *testing.T) {
+	t.Parallel()
+
+	p := &ssenats.Provider{}
+	require.Error(t, p.Publish(&sse.Message{}, []string{"topic"}), "provider without connection should fail")
+}
+
+func TestProvider_retryStart(t *testing.T) {
+	t.Parallel()
+
+	srv, err := server.NewServer(&server.Options{Port: -1})
+	require.NoError(t, err, "failed to create server")
+
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(time.Second*5), "server not ready")
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err, "failed to connect")
+	t.Cleanup(conn.Close)
+
+	p := &ssenats.Provider{Conn: conn}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	require.Error(t, p.Healthy(), "provider should fail to start without JetStream")
+
+	require.NoError(t, srv.EnableJetStream(&server.JetStreamConfig{StoreDir: t.TempDir()}), "failed to enable JetStream")
+	require.NoError(t, p.Healthy(), "provider should start once JetStream is enabled")
+}
+
+func TestProvider_ReplayLimit(t *testing.T) {
+	t.Parallel()
+
+	conn := runServer(t)
+
+	p := &ssenats.Provider{Conn: conn, ReplayLimit: 2}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	live := subscribe(t, p, "", "orders")
+	time.Sleep(time.Millisecond * 50)
+
+	for _, s := range []string{"first", "second", "third", "fourth"} {
+		m := &sse.Message{}
+		m.AppendData(s)
+		require.NoError(t, p.Publish(m, []string{"orders"}), "unexpected publish error")
+	}
+	for i := 0; i < 4; i++ {
+		receive(t, live)
+	}
+
+	replayed := subscribe(t, p, "1", "orders")
+	require.Equal(t, "id: 3\ndata: third\n\n", text(receive(t, replayed)), "the latest messages should be replayed")
+	require.Equal(t, "id: 4\ndata: fourth\n\n", text(receive(t, replayed)), "the latest messages should be replayed")
+
+	select {
+	case m := <-replayed:
+		t.Fatalf("unexpected message %q", text(m))
+	case <-time.After(time.Millisecond * 100):
+	}
+}
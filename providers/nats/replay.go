@@ -0,0 +1,182 @@
+package nats
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/tmaxmax/go-sse"
+)
+
+// replayBatch is the number of messages fetched at once when replaying.
+const replayBatch = 256
+
+// streamReplay replays the messages from the Provider's stream. It is used by the Provider's Joe,
+// so its methods are called from the Joe's goroutine only.
+type streamReplay struct {
+	p *Provider
+	// The sequence of the last message in the stream that the instance knows of. Messages after it
+	// will be sent to the sessions by the Joe, so they are not replayed.
+	last uint64
+}
+
+var _ sse.ReplayProviderWithSince = (*streamReplay)(nil)
+
+// Put records the message as the last one sent by the instance. The message is already in the stream.
+func (r *streamReplay) Put(m *sse.Message, _ []string) *sse.Message {
+	if seq, err := strconv.ParseUint(m.ID.String(), 10, 64); err == nil && seq > r.last {
+		r.last = seq
+	}
+
+	return m
+}
+
+// Replay sends the messages after the subscription's last event ID. If the ID isn't a stream sequence
+// number, nothing is replayed.
+func (r *streamReplay) Replay(sub sse.Subscription) error {
+	if !sub.LastEventID.IsSet() {
+		return nil
+	}
+
+	seq, err := strconv.ParseUint(sub.LastEventID.String(), 10, 64)
+	if err != nil || seq >= r.last {
+		return nil
+	}
+
+	start := seq + 1
+	// Skip the oldest messages which are over the limit.
+	if limit := r.p.replayLimit(); limit > 0 && r.last-seq > uint64(limit) {
+		start = r.last - uint64(limit) + 1
+	}
+
+	return r.replay(sub, jetstream.OrderedConsumerConfig{
+		DeliverPolicy: jetstream.DeliverByStartSequencePolicy,
+		OptStartSeq:   start,
+	})
+}
+
+// ReplaySince sends the messages stored in the stream at or after the given time.
+func (r *streamReplay) ReplaySince(sub sse.Subscription, since time.Time) error {
+	if r.last == 0 {
+		return nil
+	}
+
+	return r.replay(sub, jetstream.OrderedConsumerConfig{
+		DeliverPolicy: jetstream.DeliverByStartTimePolicy,
+		OptStartTime:  &since,
+	})
+}
+
+func (r *streamReplay) replay(sub sse.Subscription, cfg jetstream.OrderedConsumerConfig) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.p.replayTimeout())
+	defer cancel()
+
+	cfg.FilterSubjects = []string{r.p.subject()}
+
+	cons, err := r.p.js.OrderedConsumer(ctx, r.p.stream(), cfg)
+	if err != nil {
+		return fmt.Errorf("go-sse.nats: create replay consumer: %w", err)
+	}
+
+	limit := r.p.replayLimit()
+	sent, read := false, 0
+	for done := false; !done; {
+		size := replayBatch
+		if limit > 0 && limit-read < size {
+			size = limit - read
+		}
+		if size == 0 {
+			break
+		}
+
+		batch, err := cons.FetchNoWait(size)
+		if err != nil {
+			return fmt.Errorf("go-sse.nats: fetch messages: %w", err)
+		}
+
+		received := 0
+		for msgs := batch.Messages(); ; {
+			var msg jetstream.Msg
+			select {
+			case msg = <-msgs:
+			case <-ctx.Done():
+				return fmt.Errorf("go-sse.nats: fetch messages: %w", ctx.Err())
+			}
+			if msg == nil {
+				break
+			}
+			received++
+
+			meta, err := msg.Metadata()
+			if err != nil || meta.Sequence.Stream > r.last {
+				done = true
+				continue
+			}
+			if meta.Sequence.Stream == r.last {
+				done = true
+			}
+
+			m, topics, err := decodeMessage(msg)
+			if err != nil {
+				r.p.report(err)
+				continue
+			}
+			if !topicsIntersect(sub.Topics, topics) {
+				continue
+			}
+
+			if err := sub.Client.Send(m); err != nil {
+				return err
+			}
+			sent = true
+		}
+
+		if err := batch.Error(); err != nil {
+			return fmt.Errorf("go-sse.nats: fetch messages: %w", err)
+		}
+		if received == 0 {
+			done = true
+		}
+		read += received
+	}
+
+	if !sent {
+		return nil
+	}
+
+	return sub.Client.Flush()
+}
+
+func decodeMessage(msg jetstream.Msg) (*sse.Message, []string, error) {
+	meta, err := msg.Metadata()
+	if err != nil {
+		return nil, nil, fmt.Errorf("go-sse.nats: invalid message: %w", err)
+	}
+
+	id := strconv.FormatUint(meta.Sequence.Stream, 10)
+
+	m := &sse.Message{}
+	if err := m.UnmarshalText(msg.Data()); err != nil {
+		return nil, nil, fmt.Errorf("go-sse.nats: invalid message %s: %w", id, err)
+	}
+
+	m.ID = sse.ID(id)
+	m.Origin = msg.Headers().Get(headerOrigin)
+
+	return m, msg.Headers()[headerTopic], nil
+}
+
+// topicsIntersect returns true if the given topic slices have at least one topic in common.
+func topicsIntersect(a, b []string) bool {
+	for _, at := range a {
+		for _, bt := range b {
+			if at == bt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,312 @@
+// Package redis implements a go-sse Provider backed by Redis, for deployments with multiple server instances.
+//
+// Messages are published to all the instances using Redis Pub/Sub, and they are also stored in a Redis stream,
+// from which the sessions are replayed the messages they missed, regardless of the instance they reconnect to.
+// The IDs of the messages are the IDs of their stream entries.
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/tmaxmax/go-sse"
+)
+
+// The default names of the Redis keys used by the Provider.
+const (
+	DefaultStream  = "sse:stream"
+	DefaultChannel = "sse:messages"
+)
+
+// DefaultMaxLen is the default approximate maximum number of messages kept in the stream.
+const DefaultMaxLen = 10000
+
+// The defaults of the Provider's replay limits.
+const (
+	DefaultReplayLimit   = 1000
+	DefaultReplayTimeout = time.Second
+)
+
+// Provider is a go-sse Provider which shares the published messages between the server instances using Redis.
+// Each instance sends the messages to its own sessions using a Joe. Publish stores the message in the Stream
+// and announces it on the Channel, to which all the instances are subscribed. The sessions which reconnect with
+// a last event ID, or with a replay time (see sse.Session.ReplaySince), are replayed the messages from the Stream.
+//
+// Messages may be delivered twice to a session which subscribes while they are being published.
+// The Provider connects to Redis when it is used for the first time; if that fails, Subscribe and Publish
+// return the error, and the Provider tries to connect again the next time it is used. The Provider must
+// not be copied after it is used.
+//
+// The sessions are replayed from the Joe's goroutine, which doesn't deliver messages in the meantime,
+// so each replay reads at most ReplayLimit messages and waits for Redis at most ReplayTimeout.
+type Provider struct {
+	// The Redis client. It is not closed by Shutdown. Required.
+	Client goredis.UniversalClient
+	// The key of the stream which stores the messages for replay. Defaults to DefaultStream.
+	Stream string
+	// The Pub/Sub channel on which the messages are announced. Defaults to DefaultChannel.
+	Channel string
+	// The approximate maximum number of messages kept in the stream. Defaults to DefaultMaxLen.
+	// If it is negative, the stream is not trimmed.
+	MaxLen int64
+	// The maximum number of messages replayed to a session, which are the latest ones it missed.
+	// Defaults to DefaultReplayLimit. If it is negative, the number of messages is not limited.
+	ReplayLimit int64
+	// How long a replay waits for Redis. Defaults to DefaultReplayTimeout.
+	ReplayTimeout time.Duration
+	// An optional reporter for the errors which can't be returned, such as invalid messages
+	// received from the channel, and for the panics recovered by the Joe provider used.
+	ErrorReporter sse.ErrorReporter
+
+	joe    *sse.Joe
+	pubsub *goredis.PubSub
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// envelope is the format of the messages announced on the channel.
+type envelope struct {
+	ID      string   `json:"id"`
+	Topics  []string `json:"topics"`
+	Origin  string   `json:"origin,omitempty"`
+	Message string   `json:"message"`
+}
+
+// Subscribe subscribes the session to the instance's Joe, replaying the messages it missed from the stream.
+func (p *Provider) Subscribe(ctx context.Context, sub sse.Subscription) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	return p.joe.Subscribe(ctx, sub)
+}
+
+// Publish stores the message in the stream and announces it to all the server instances.
+// The message's ID is replaced with the ID of its stream entry.
+func (p *Provider) Publish(m *sse.Message, topics []string) error {
+	if len(topics) == 0 {
+		return sse.ErrNoTopic
+	}
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-p.done:
+		return sse.ErrProviderClosed
+	default:
+	}
+
+	unidentified := m.Clone()
+	unidentified.ID = sse.EventID{}
+	text, err := unidentified.MarshalText()
+	if err != nil {
+		return err
+	}
+
+	topicsJSON, _ := json.Marshal(topics)
+
+	ctx := context.Background()
+	args := &goredis.XAddArgs{
+		Stream: p.stream(),
+		Values: map[string]any{"topics": string(topicsJSON), "origin": m.Origin, "message": string(text)},
+	}
+	if maxLen := p.maxLen(); maxLen > 0 {
+		args.MaxLen = maxLen
+		args.Approx = true
+	}
+
+	id, err := p.Client.XAdd(ctx, args).Result()
+	if err != nil {
+		return fmt.Errorf("go-sse.redis: store message: %w", err)
+	}
+
+	data, _ := json.Marshal(envelope{ID: id, Topics: topics, Origin: m.Origin, Message: string(text)})
+	if err := p.Client.Publish(ctx, p.channel(), data).Err(); err != nil {
+		return fmt.Errorf("go-sse.redis: announce message: %w", err)
+	}
+
+	return nil
+}
+
+// Shutdown unsubscribes the instance from the channel and closes its sessions.
+// See sse.Joe.Shutdown for more information.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if err := p.start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-p.done:
+	default:
+		close(p.done)
+		_ = p.pubsub.Close()
+	}
+
+	return p.joe.Shutdown(ctx)
+}
+
+// Healthy reports whether Redis can be reached and the instance is not shut down.
+// It implements sse.HealthChecker.
+func (p *Provider) Healthy() error {
+	if err := p.start(); err != nil {
+		return err
+	}
+	if err := p.joe.Healthy(); err != nil {
+		return err
+	}
+
+	return p.Client.Ping(context.Background()).Err()
+}
+
+func (p *Provider) start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The Provider is started once it has a Joe; until then, each use tries to start it.
+	if p.joe != nil {
+		return nil
+	}
+	if p.Client == nil {
+		return errors.New("go-sse.redis: no client")
+	}
+
+	ctx := context.Background()
+
+	// Subscribe before finding the end of the stream, so no message is missed in between.
+	pubsub := p.Client.Subscribe(ctx, p.channel())
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return fmt.Errorf("go-sse.redis: subscribe to channel: %w", err)
+	}
+
+	last := "0-0"
+	entries, err := p.Client.XRevRangeN(ctx, p.stream(), "+", "-", 1).Result()
+	if err != nil {
+		_ = pubsub.Close()
+		return fmt.Errorf("go-sse.redis: read stream: %w", err)
+	}
+	if len(entries) > 0 {
+		last = entries[0].ID
+	}
+
+	p.pubsub = pubsub
+	p.done = make(chan struct{})
+	p.joe = &sse.Joe{
+		ReplayProvider: &streamReplay{p: p, last: last},
+		ErrorReporter:  p.ErrorReporter,
+	}
+
+	go p.receive(pubsub.Channel())
+
+	return nil
+}
+
+// receive sends the messages announced on the channel to the instance's sessions.
+func (p *Provider) receive(ch <-chan *goredis.Message) {
+	for msg := range ch {
+		var env envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil {
+			p.report(fmt.Errorf("go-sse.redis: invalid announcement: %w", err))
+			continue
+		}
+
+		m, err := decodeMessage(env.ID, env.Origin, env.Message)
+		if err != nil {
+			p.report(err)
+			continue
+		}
+
+		if err := p.joe.Publish(m, env.Topics); err != nil {
+			if errors.Is(err, sse.ErrProviderClosed) {
+				return
+			}
+			p.report(err)
+		}
+	}
+}
+
+func (p *Provider) report(err error) {
+	if p.ErrorReporter != nil {
+		p.ErrorReporter.ReportError(context.Background(), err)
+	}
+}
+
+func (p *Provider) stream() string {
+	if p.Stream != "" {
+		return p.Stream
+	}
+	return DefaultStream
+}
+
+func (p *Provider) channel() string {
+	if p.Channel != "" {
+		return p.Channel
+	}
+	return DefaultChannel
+}
+
+func (p *Provider) maxLen() int64 {
+	if p.MaxLen == 0 {
+		return DefaultMaxLen
+	}
+	return p.MaxLen
+}
+
+func (p *Provider) replayLimit() int64 {
+	if p.ReplayLimit == 0 {
+		return DefaultReplayLimit
+	}
+	return p.ReplayLimit
+}
+
+func (p *Provider) replayTimeout() time.Duration {
+	if p.ReplayTimeout <= 0 {
+		return DefaultReplayTimeout
+	}
+	return p.ReplayTimeout
+}
+
+func decodeMessage(id, origin, text string) (*sse.Message, error) {
+	m := &sse.Message{}
+	if err := m.UnmarshalText([]byte(text)); err != nil {
+		return nil, fmt.Errorf("go-sse.redis: invalid message %s: %w", id, err)
+	}
+
+	m.ID = sse.ID(id)
+	m.Origin = origin
+
+	return m, nil
+}
+
+// streamID is a parsed Redis stream entry ID.
+type streamID struct{ ms, seq uint64 }
+
+func parseStreamID(id string) (streamID, bool) {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return streamID{}, false
+	}
+
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return streamID{}, false
+	}
+
+	return streamID{ms: ms, seq: seq}, true
+}
+
+func (s streamID) after(other streamID) bool {
+	return s.ms > other.ms || (s.ms == other.ms && s.seq > other.seq)
+}
@@ -0,0 +1,169 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/providers/redis"
+)
+
+type mockClient func(m *sse.Message) error
+
+func (c mockClient) Send(m *sse.Message) error { return c(m) }
+func (c mockClient) Flush() error              { return nil }
+
+func subscribe(t *testing.T, p *redis.Provider, lastEventID string, topics ...string) <-chan *sse.Message {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	ch := make(chan *sse.Message, 16)
+	sub := sse.Subscription{
+		Client: mockClient(func(m *sse.Message) error {
+			ch <- m
+			return nil
+		}),
+		Topics: topics,
+	}
+	if lastEventID != "" {
+		sub.LastEventID = sse.ID(lastEventID)
+	}
+
+	go func() { _ = p.Subscribe(ctx, sub) }()
+
+	return ch
+}
+
+func receive(t *testing.T, ch <-chan *sse.Message) *sse.Message {
+	t.Helper()
+
+	select {
+	case m := <-ch:
+		return m
+	case <-time.After(time.Second * 2):
+		t.Fatal("no message received")
+		return nil
+	}
+}
+
+func data(m *sse.Message) string {
+	text, _ := m.MarshalText()
+	return string(text)
+}
+
+func TestProvider(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	a := &redis.Provider{Client: client}
+	b := &redis.Provider{Client: client}
+	t.Cleanup(func() {
+		_ = a.Shutdown(context.Background())
+		_ = b.Shutdown(context.Background())
+	})
+
+	require.NoError(t, b.Healthy(), "provider should be healthy")
+
+	live := subscribe(t, b, "", "orders")
+	// Give the subscription time to be registered.
+	time.Sleep(time.Millisecond * 50)
+
+	first := &sse.Message{Origin: "alice"}
+	first.AppendData("first")
+	require.NoError(t, a.Publish(first, []string{"orders"}), "unexpected publish error")
+
+	other := &sse.Message{}
+	other.AppendData("other")
+	require.NoError(t, a.Publish(other, []string{"payments"}), "unexpected publish error")
+
+	second := &sse.Message{}
+	second.AppendData("second")
+	require.NoError(t, a.Publish(second, []string{"orders"}), "unexpected publish error")
+
+	got := receive(t, live)
+	require.True(t, got.ID.IsSet(), "message should have the stream ID")
+	require.Equal(t, "alice", got.Origin, "origin should be kept")
+	require.Contains(t, data(got), "data: first\n")
+	firstID := got.ID.String()
+
+	got = receive(t, live)
+	require.Contains(t, data(got), "data: second\n", "messages of other topics should not be received")
+
+	// A session reconnecting to the other instance is replayed what it missed.
+	replayed := subscribe(t, a, firstID, "orders")
+	got = receive(t, replayed)
+	require.Contains(t, data(got), "data: second\n", "missed message should be replayed")
+	require.Equal(t, "id: "+got.ID.String()+"\ndata: second\n\n", data(got))
+
+	require.ErrorIs(t, a.Publish(first, nil), sse.ErrNoTopic)
+	require.NoError(t, a.Shutdown(context.Background()), "unexpected shutdown error")
+	require.ErrorIs(t, a.Publish(first, []string{"orders"}), sse.ErrProviderClosed)
+}
+
+func TestProvider_NoClient(t *testing.T) {
+	t.Parallel()
+
+	p := &redis.Provider{}
+	require.Error(t, p.Publish(&sse.Message{}, []string{"topic"}), "provider without client should fail")
+}
+
+func TestProvider_retryStart(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { _ = client.Close() })
+
+	p := &redis.Provider{Client: client}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	srv.Close()
+	require.Error(t, p.Healthy(), "provider should fail to start while Redis is down")
+
+	require.NoError(t, srv.Restart(), "unexpected restart error")
+	require.NoError(t, p.Healthy(), "provider should start once Redis is up")
+}
+
+func TestProvider_ReplayLimit(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	p := &redis.Provider{Client: client, ReplayLimit: 2}
+	t.Cleanup(func() { _ = p.Shutdown(context.Background()) })
+
+	live := subscribe(t, p, "", "orders")
+	time.Sleep(time.Millisecond * 50)
+
+	for _, s := range []string{"first", "second", "third", "fourth"} {
+		m := &sse.Message{}
+		m.AppendData(s)
+		require.NoError(t, p.Publish(m, []string{"orders"}), "unexpected publish error")
+	}
+
+	firstID := receive(t, live).ID.String()
+	for i := 0; i < 3; i++ {
+		receive(t, live)
+	}
+
+	replayed := subscribe(t, p, firstID, "orders")
+	require.Contains(t, data(receive(t, replayed)), "data: third\n", "the latest messages should be replayed")
+	require.Contains(t, data(receive(t, replayed)), "data: fourth\n", "the latest messages should be replayed")
+
+	select {
+	case m := <-replayed:
+		t.Fatalf("unexpected message %q", data(m))
+	case <-time.After(time.Millisecond * 100):
+	}
+}
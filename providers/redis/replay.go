@@ -0,0 +1,132 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/tmaxmax/go-sse"
+)
+
+// streamReplay replays the messages from the Provider's stream. It is used by the Provider's Joe,
+// so its methods are called from the Joe's goroutine only.
+type streamReplay struct {
+	p *Provider
+	// The ID of the last message in the stream that the instance knows of. Messages after it
+	// will be sent to the sessions by the Joe, so they are not replayed.
+	last string
+}
+
+var _ sse.ReplayProviderWithSince = (*streamReplay)(nil)
+
+// Put records the message as the last one sent by the instance. The message is already in the stream.
+func (r *streamReplay) Put(m *sse.Message, _ []string) *sse.Message {
+	id := m.ID.String()
+	if next, ok := parseStreamID(id); ok {
+		if last, ok := parseStreamID(r.last); !ok || next.after(last) {
+			r.last = id
+		}
+	}
+
+	return m
+}
+
+// Replay sends the messages after the subscription's last event ID. If the ID isn't a stream ID,
+// nothing is replayed.
+func (r *streamReplay) Replay(sub sse.Subscription) error {
+	if !sub.LastEventID.IsSet() {
+		return nil
+	}
+
+	id := sub.LastEventID.String()
+	if _, ok := parseStreamID(id); !ok {
+		return nil
+	}
+
+	return r.replayRange(sub, "("+id)
+}
+
+// ReplaySince sends the messages put in the stream at or after the given time.
+func (r *streamReplay) ReplaySince(sub sse.Subscription, since time.Time) error {
+	return r.replayRange(sub, strconv.FormatInt(since.UnixMilli(), 10))
+}
+
+func (r *streamReplay) replayRange(sub sse.Subscription, start string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), r.p.replayTimeout())
+	defer cancel()
+
+	var (
+		entries []goredis.XMessage
+		err     error
+	)
+	if limit := r.p.replayLimit(); limit > 0 {
+		// Read the latest entries backwards, so the ones over the limit are the oldest.
+		entries, err = r.p.Client.XRevRangeN(ctx, r.p.stream(), r.last, start, limit).Result()
+		for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+			entries[i], entries[j] = entries[j], entries[i]
+		}
+	} else {
+		entries, err = r.p.Client.XRange(ctx, r.p.stream(), start, r.last).Result()
+	}
+	if err != nil {
+		return fmt.Errorf("go-sse.redis: read stream: %w", err)
+	}
+
+	sent := false
+	for _, e := range entries {
+		m, topics, err := decodeEntry(e)
+		if err != nil {
+			r.p.report(err)
+			continue
+		}
+		if !topicsIntersect(sub.Topics, topics) {
+			continue
+		}
+
+		if err := sub.Client.Send(m); err != nil {
+			return err
+		}
+		sent = true
+	}
+
+	if !sent {
+		return nil
+	}
+
+	return sub.Client.Flush()
+}
+
+func decodeEntry(e goredis.XMessage) (*sse.Message, []string, error) {
+	str := func(key string) string {
+		s, _ := e.Values[key].(string)
+		return s
+	}
+
+	var topics []string
+	if err := json.Unmarshal([]byte(str("topics")), &topics); err != nil {
+		return nil, nil, err
+	}
+
+	m, err := decodeMessage(e.ID, str("origin"), str("message"))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return m, topics, nil
+}
+
+// topicsIntersect returns true if the given topic slices have at least one topic in common.
+func topicsIntersect(a, b []string) bool {
+	for _, at := range a {
+		for _, bt := range b {
+			if at == bt {
+				return true
+			}
+		}
+	}
+
+	return false
+}
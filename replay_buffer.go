@@ -15,6 +15,7 @@ type buffer interface {
 	len() int
 	cap() int
 	slice(EventID) []messageWithTopics
+	all() []messageWithTopics
 }
 
 type bufferBase struct {
@@ -29,6 +30,10 @@ func (b *bufferBase) cap() int {
 	return cap(b.buf)
 }
 
+func (b *bufferBase) all() []messageWithTopics {
+	return b.buf
+}
+
 func (b *bufferBase) front() *messageWithTopics {
 	if b.len() == 0 {
 		return nil
@@ -0,0 +1,286 @@
+package sse
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TopicRetention is the retention policy of a topic of a CompactedReplayProvider.
+type TopicRetention struct {
+	// Key returns the compaction key of a message, for example the ID of the entity whose state
+	// it describes. Only the latest message with each key is kept, as in Kafka's compacted topics,
+	// so the topic can be replayed as the current state of all the entities. If it is nil,
+	// the topic is not compacted.
+	Key func(*Message) string
+	// For how long the messages are kept. If it is 0, messages are kept until they are compacted.
+	TTL time.Duration
+}
+
+// CompactedReplayProvider is a ReplayProvider which retains messages according to the retention policy
+// of their topics: time-based retention, compaction by key, or both. Use it for "current state" streams,
+// where clients need only the latest event for each entity. Call its GC method periodically to remove the
+// expired messages and release the resources of the compacted ones. The subscriptions' topics may be patterns.
+// The messages must have an ID unless the AutoIDs flag is toggled. If it isn't, clients whose last event ID
+// belongs to a removed message are replayed nothing, as the position of the message is no longer known.
+type CompactedReplayProvider struct {
+	// The retention policy of each topic. The topics which aren't in the map use the Default policy.
+	Topics map[string]TopicRetention
+	// The retention policy of the topics which aren't in the Topics map.
+	Default TopicRetention
+	// The function used to retrieve the current time. Defaults to time.Now.
+	Now func() time.Time
+	// AutoIDs configures CompactedReplayProvider to automatically set the IDs of events.
+	AutoIDs bool
+
+	// The retained entries, ordered by their sequence numbers.
+	entries []compactedEntry
+	// The sequence number of the next entry.
+	next int64
+	// The sequence numbers of the retained entries with each ID, if AutoIDs is not set.
+	ids map[string]int64
+	// The sequence number of the latest entry for each topic and compaction key.
+	latest map[compactionKey]int64
+}
+
+type compactedEntry struct {
+	message *Message
+	seq     int64
+	// The topics the message is retained for, each with its expiry time. A zero expiry never expires.
+	topics   []string
+	expiries []time.Time
+}
+
+type compactionKey struct {
+	topic, key string
+}
+
+func (c *CompactedReplayProvider) retention(topic string) TopicRetention {
+	if r, ok := c.Topics[topic]; ok {
+		return r
+	}
+	return c.Default
+}
+
+// Put puts the message into the provider, removing from each of its compacted topics
+// the previous message with the same key.
+func (c *CompactedReplayProvider) Put(message *Message, topics []string) *Message {
+	if len(topics) == 0 {
+		panic(errors.New("go-sse: no topics provided for Message.\n" + formatMessagePanicString(message)))
+	}
+
+	seq := c.next
+	c.next++
+	if c.AutoIDs {
+		message = message.Clone()
+		message.ID = ID(strconv.FormatInt(seq, autoIDBase))
+	} else {
+		if !message.ID.IsSet() {
+			panic(errors.New("go-sse: a Message without an ID was given to a provider that doesn't set IDs automatically.\n" + formatMessagePanicString(message)))
+		}
+		if c.ids == nil {
+			c.ids = map[string]int64{}
+		}
+		c.ids[message.ID.String()] = seq
+	}
+
+	now := c.now()
+	entry := compactedEntry{message: message, seq: seq, topics: topics, expiries: make([]time.Time, len(topics))}
+
+	for i, topic := range topics {
+		r := c.retention(topic)
+		if r.TTL > 0 {
+			entry.expiries[i] = now.Add(r.TTL)
+		}
+		if r.Key == nil {
+			continue
+		}
+
+		key := compactionKey{topic: topic, key: r.Key(message)}
+		if prev, ok := c.latest[key]; ok {
+			if i := c.index(prev); i != -1 {
+				c.entries[i].remove(topic)
+			}
+		}
+		if c.latest == nil {
+			c.latest = map[compactionKey]int64{}
+		}
+		c.latest[key] = seq
+	}
+
+	c.entries = append(c.entries, entry)
+
+	return message
+}
+
+// remove stops retaining the entry for the given topic.
+func (e *compactedEntry) remove(topic string) {
+	for i, t := range e.topics {
+		if t == topic {
+			// The slices may be shared with the caller of Put, so they are copied.
+			e.topics = append(e.topics[:i:i], e.topics[i+1:]...)
+			e.expiries = append(e.expiries[:i:i], e.expiries[i+1:]...)
+			return
+		}
+	}
+}
+
+// expire stops retaining the entry for the topics which expired at the given time.
+func (e *compactedEntry) expire(now time.Time) {
+	for i := 0; i < len(e.topics); {
+		if e.expiries[i].IsZero() || e.expiries[i].After(now) {
+			i++
+			continue
+		}
+		e.remove(e.topics[i])
+	}
+}
+
+// retains reports whether the entry is retained at the given time for any of the given topics.
+func (e *compactedEntry) retains(topics []string, now time.Time) bool {
+	for i, t := range e.topics {
+		if e.expiries[i].IsZero() || e.expiries[i].After(now) {
+			for _, topic := range topics {
+				if matchPattern(topic, t) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// GC removes the expired and the compacted messages from the provider's buffer.
+func (c *CompactedReplayProvider) GC() error {
+	now := c.now()
+
+	kept := c.entries[:0]
+	for i := range c.entries {
+		e := &c.entries[i]
+		if e.expire(now); len(e.topics) > 0 {
+			kept = append(kept, *e)
+		} else if c.ids != nil {
+			delete(c.ids, e.message.ID.String())
+		}
+	}
+	// Release the removed messages, which are still referenced past the kept entries.
+	for i := len(kept); i < len(c.entries); i++ {
+		c.entries[i] = compactedEntry{}
+	}
+	if len(kept) < cap(kept)/4 {
+		kept = append([]compactedEntry(nil), kept...)
+	}
+	c.entries = kept
+
+	for key, seq := range c.latest {
+		if i := c.index(seq); i == -1 || !c.entries[i].has(key.topic) {
+			delete(c.latest, key)
+		}
+	}
+
+	return nil
+}
+
+// index returns the index of the entry with the given sequence number, or -1 if it was removed.
+func (c *CompactedReplayProvider) index(seq int64) int {
+	i := sort.Search(len(c.entries), func(i int) bool { return c.entries[i].seq >= seq })
+	if i == len(c.entries) || c.entries[i].seq != seq {
+		return -1
+	}
+	return i
+}
+
+// has reports whether the entry is retained for the given topic.
+func (e *compactedEntry) has(topic string) bool {
+	for _, t := range e.topics {
+		if t == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// after returns the index of the first entry after the one with the given ID, and whether the ID is valid.
+// If the ID is not set, it returns the index of the first entry.
+func (c *CompactedReplayProvider) after(id EventID) (int, bool) {
+	if !id.IsSet() {
+		return 0, true
+	}
+
+	var seq int64
+	if c.AutoIDs {
+		var err error
+		if seq, err = strconv.ParseInt(id.String(), autoIDBase, 64); err != nil {
+			return 0, false
+		}
+	} else {
+		var ok bool
+		if seq, ok = c.ids[id.String()]; !ok {
+			return 0, false
+		}
+	}
+
+	if seq < 0 || seq >= c.next {
+		return 0, false
+	}
+
+	return sort.Search(len(c.entries), func(i int) bool { return c.entries[i].seq > seq }), true
+}
+
+// Replay replays the retained messages published after the subscription's LastEventID to its topics.
+// For compacted topics, only the latest message with each key is replayed.
+func (c *CompactedReplayProvider) Replay(subscription Subscription) error {
+	if !subscription.LastEventID.IsSet() {
+		return nil
+	}
+
+	start, ok := c.after(subscription.LastEventID)
+	if !ok {
+		return nil
+	}
+
+	now := c.now()
+	for _, e := range c.entries[start:] {
+		if e.retains(subscription.Topics, now) {
+			if err := subscription.Client.Send(e.message); err != nil {
+				return err
+			}
+		}
+	}
+
+	return subscription.Client.Flush()
+}
+
+// Iter returns an iterator over the retained messages. See ReplayProviderWithIter.
+func (c *CompactedReplayProvider) Iter(topic string, fromID EventID) *ReplayIterator {
+	start, ok := c.after(fromID)
+	if !ok {
+		return &ReplayIterator{}
+	}
+
+	now := c.now()
+	topics := []string{topic}
+
+	var messages []messageWithTopics
+	for _, e := range c.entries[start:] {
+		if e.retains(topics, now) {
+			messages = append(messages, messageWithTopics{message: e.message, topics: e.topics})
+		}
+	}
+
+	return &ReplayIterator{messages: messages}
+}
+
+func (c *CompactedReplayProvider) now() time.Time {
+	if c.Now == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
+
+var (
+	_ ReplayProviderWithGC   = (*CompactedReplayProvider)(nil)
+	_ ReplayProviderWithIter = (*CompactedReplayProvider)(nil)
+)
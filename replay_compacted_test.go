@@ -0,0 +1,27 @@
+package sse
+
+import "testing"
+
+func TestCompactedReplayProvider_GC_releasesMessages(t *testing.T) {
+	t.Parallel()
+
+	p := &CompactedReplayProvider{
+		Default: TopicRetention{Key: func(m *Message) string { return m.Type.String() }},
+		AutoIDs: true,
+	}
+
+	p.Put(&Message{Type: Type("pinned")}, []string{DefaultTopic})
+	for i := 0; i < 100; i++ {
+		p.Put(&Message{Type: Type("updated")}, []string{DefaultTopic})
+	}
+	if err := p.GC(); err != nil {
+		t.Fatalf("unexpected GC error: %v", err)
+	}
+
+	if l := len(p.entries); l != 2 {
+		t.Fatalf("compacted entries should be removed, %d entries are left", l)
+	}
+	if l := len(p.latest); l != 2 {
+		t.Fatalf("invalid number of compaction keys: %d", l)
+	}
+}
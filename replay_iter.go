@@ -0,0 +1,158 @@
+package sse
+
+import "errors"
+
+// ErrReplayIterUnsupported is returned by Joe.ReplayIter if its replay provider can't be iterated.
+var ErrReplayIterUnsupported = errors.New("go-sse.server: replay provider doesn't support iteration")
+
+// ReplayProviderWithIter is a ReplayProvider whose stored messages can be iterated outside live sessions,
+// so batch jobs can process the retained history – for example, to rebuild a cache.
+type ReplayProviderWithIter interface {
+	ReplayProvider
+	// Iter returns an iterator over the valid messages published to the given topic after the message
+	// with the given ID, in the order they were put. If the ID is not set, all the valid messages are
+	// iterated. If the ID is invalid, nothing is iterated. The iterator is a snapshot: it isn't
+	// affected by the messages put or removed afterwards.
+	Iter(topic string, fromID EventID) *ReplayIterator
+}
+
+// ReplayIterator iterates over messages stored by a replay provider. Call Next to advance to the
+// next message, starting with the first, and Message to get it:
+//
+//	for it := provider.Iter("orders", sse.EventID{}); it.Next(); {
+//		rebuild(it.Message())
+//	}
+//
+// The messages are shared with the provider, so they must not be modified.
+type ReplayIterator struct {
+	messages []messageWithTopics
+	current  int
+}
+
+// Next advances the iterator to the next message. It returns false when there are no more messages.
+func (r *ReplayIterator) Next() bool {
+	if r.current > len(r.messages) {
+		return false
+	}
+
+	r.current++
+
+	return r.current <= len(r.messages)
+}
+
+// Message returns the current message. It returns nil if Next wasn't called or returned false.
+func (r *ReplayIterator) Message() *Message {
+	if r.current == 0 || r.current > len(r.messages) {
+		return nil
+	}
+
+	return r.messages[r.current-1].message
+}
+
+// Topics returns the topics the current message was published to.
+func (r *ReplayIterator) Topics() []string {
+	if r.current == 0 || r.current > len(r.messages) {
+		return nil
+	}
+
+	return r.messages[r.current-1].topics
+}
+
+// Len returns the number of messages which weren't iterated yet.
+func (r *ReplayIterator) Len() int {
+	if r.current > len(r.messages) {
+		return 0
+	}
+
+	return len(r.messages) - r.current
+}
+
+// newReplayIterator returns an iterator over the buffered messages after the given ID which
+// were published to the given topic and for which valid, if non-nil, returns true.
+func newReplayIterator(b buffer, topic string, fromID EventID, valid func(i int) bool) *ReplayIterator {
+	if b == nil {
+		return &ReplayIterator{}
+	}
+
+	events := b.all()
+	if fromID.IsSet() {
+		events = b.slice(fromID)
+	}
+
+	offset := b.len() - len(events)
+	topics := []string{topic}
+
+	var messages []messageWithTopics
+	for i, e := range events {
+		if (valid == nil || valid(i+offset)) && topicsIntersect(topics, e.topics) {
+			messages = append(messages, e)
+		}
+	}
+
+	return &ReplayIterator{messages: messages}
+}
+
+// Iter returns an iterator over the buffered messages. See ReplayProviderWithIter.
+func (f *FiniteReplayProvider) Iter(topic string, fromID EventID) *ReplayIterator {
+	return newReplayIterator(f.b, topic, fromID, nil)
+}
+
+// Iter returns an iterator over the buffered messages which aren't expired. See ReplayProviderWithIter.
+func (v *ValidReplayProvider) Iter(topic string, fromID EventID) *ReplayIterator {
+	now := v.now()
+	return newReplayIterator(v.b, topic, fromID, func(i int) bool { return v.expiries[i].After(now) })
+}
+
+// ReplayIter returns an iterator over the messages stored by Joe's replay provider. See
+// ReplayProviderWithIter.Iter for the meaning of the arguments. The iterator is created on Joe's
+// goroutine, so it is safe to use while messages are published. It returns ErrReplayIterUnsupported
+// if the replay provider doesn't implement ReplayProviderWithIter.
+func (j *Joe) ReplayIter(topic string, fromID EventID) (*ReplayIterator, error) {
+	j.init()
+
+	result := make(chan replayIterResult, 1)
+
+	select {
+	case j.replayIter <- replayIterRequest{topic: topic, fromID: fromID, result: result}:
+	case <-j.done:
+		return nil, ErrProviderClosed
+	}
+
+	r := <-result
+
+	return r.iter, r.err
+}
+
+type (
+	replayIterRequest struct {
+		result chan<- replayIterResult
+		topic  string
+		fromID EventID
+	}
+
+	replayIterResult struct {
+		iter *ReplayIterator
+		err  error
+	}
+)
+
+func (j *Joe) tryIter(req replayIterRequest, replay ReplayProvider) (res replayIterResult) {
+	defer func() {
+		if r := recover(); r != nil {
+			res.err = ErrReplayFailed
+			j.reportPanic(r)
+		}
+	}()
+
+	it, ok := replay.(ReplayProviderWithIter)
+	if !ok {
+		return replayIterResult{err: ErrReplayIterUnsupported}
+	}
+
+	return replayIterResult{iter: it.Iter(req.topic, req.fromID)}
+}
+
+var (
+	_ ReplayProviderWithIter = (*FiniteReplayProvider)(nil)
+	_ ReplayProviderWithIter = (*ValidReplayProvider)(nil)
+)
@@ -130,6 +130,35 @@ func (v *ValidReplayProvider) Replay(subscription Subscription) error {
 	return subscription.Client.Flush()
 }
 
+// ReplaySince replays all the valid messages which were put at or after the given time.
+func (v *ValidReplayProvider) ReplaySince(subscription Subscription, since time.Time) error {
+	if v.b == nil {
+		return nil
+	}
+
+	// Messages are put in order, so their expiry times are ordered too.
+	events, expiries := v.b.all(), v.expiries
+	for len(expiries) > 0 && expiries[0].Add(-v.TTL).Before(since) {
+		events, expiries = events[1:], expiries[1:]
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	now := v.now()
+
+	for i, e := range events {
+		if expiries[i].After(now) && topicsIntersect(subscription.Topics, e.topics) {
+			if err := subscription.Client.Send(e.message); err != nil {
+				return err
+			}
+		}
+	}
+
+	return subscription.Client.Flush()
+}
+
 func (v *ValidReplayProvider) now() time.Time {
 	if v.Now == nil {
 		return time.Now()
@@ -139,10 +168,11 @@ func (v *ValidReplayProvider) now() time.Time {
 }
 
 // topicsIntersect returns true if the given topic slices have at least one topic in common.
+// The topics in the first slice, which are the ones subscribed to, may be patterns.
 func topicsIntersect(a, b []string) bool {
 	for _, at := range a {
 		for _, bt := range b {
-			if at == bt {
+			if at == bt || matchPattern(at, bt) {
 				return true
 			}
 		}
@@ -1,6 +1,8 @@
 package sse_test
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -145,3 +147,188 @@ The message is the following:
 
 	testReplayError(t, &sse.FiniteReplayProvider{Count: 10}, nil)
 }
+
+func TestValidReplayProvider_ReplaySince(t *testing.T) {
+	t.Parallel()
+
+	tm := &tests.Time{}
+	p := &sse.ValidReplayProvider{
+		TTL:     time.Minute * 10,
+		AutoIDs: true,
+		Now:     tm.Now,
+	}
+
+	var replayed []string
+	sub := sse.Subscription{
+		Client: mockClient(func(m *sse.Message) error {
+			if m != nil {
+				replayed = append(replayed, m.String())
+			}
+			return nil
+		}),
+		Topics: []string{sse.DefaultTopic},
+	}
+
+	now := time.Now()
+	require.NoError(t, p.ReplaySince(sub, now), "replay failed on provider without messages")
+
+	tm.Set(now)
+	p.Put(msg(t, "old", ""), []string{sse.DefaultTopic})
+	tm.Add(time.Minute * 5)
+	p.Put(msg(t, "recent", ""), []string{sse.DefaultTopic})
+	p.Put(msg(t, "other topic", ""), []string{"t"})
+	tm.Add(time.Minute)
+	p.Put(msg(t, "new", ""), []string{sse.DefaultTopic})
+
+	tm.Add(time.Minute * 5)
+	require.NoError(t, p.ReplaySince(sub, now), "unexpected ReplaySince error")
+	require.Equal(t, []string{"id: 1\ndata: recent\n\n", "id: 3\ndata: new\n\n"}, replayed, "expired messages were replayed")
+
+	replayed = nil
+	require.NoError(t, p.ReplaySince(sub, now.Add(time.Minute*6)), "unexpected ReplaySince error")
+	require.Equal(t, []string{"id: 3\ndata: new\n\n"}, replayed, "messages put before the given time were replayed")
+}
+
+func iterData(it *sse.ReplayIterator) []string {
+	var data []string
+	for it.Next() {
+		data = append(data, it.Message().String())
+	}
+	return data
+}
+
+func TestReplayProvider_Iter(t *testing.T) {
+	t.Parallel()
+
+	f := &sse.FiniteReplayProvider{Count: 3}
+	require.Empty(t, iterData(f.Iter(sse.DefaultTopic, sse.EventID{})), "empty provider should iterate nothing")
+
+	f.Put(msg(t, "a", "1"), []string{sse.DefaultTopic})
+	f.Put(msg(t, "b", "2"), []string{"t"})
+	f.Put(msg(t, "c", "3"), []string{sse.DefaultTopic, "t"})
+	f.Put(msg(t, "d", "4"), []string{sse.DefaultTopic})
+
+	it := f.Iter(sse.DefaultTopic, sse.EventID{})
+	require.Equal(t, 2, it.Len(), "invalid iterator length")
+	f.Put(msg(t, "e", "5"), []string{sse.DefaultTopic})
+	require.Equal(t, []string{"id: 3\ndata: c\n\n", "id: 4\ndata: d\n\n"}, iterData(it), "iterator should be a snapshot")
+	require.Nil(t, it.Message(), "exhausted iterator should have no message")
+	require.False(t, it.Next(), "exhausted iterator should stay exhausted")
+	require.Zero(t, it.Len(), "exhausted iterator should have no messages left")
+
+	require.Equal(t, []string{"id: 5\ndata: e\n\n"}, iterData(f.Iter(sse.DefaultTopic, sse.ID("4"))), "invalid messages after ID")
+	require.Empty(t, iterData(f.Iter(sse.DefaultTopic, sse.ID("unknown"))), "unknown ID should iterate nothing")
+
+	it = f.Iter("t", sse.EventID{})
+	require.True(t, it.Next(), "topic should have messages")
+	require.Contains(t, it.Topics(), "t", "invalid topics")
+
+	tm := &tests.Time{}
+	v := &sse.ValidReplayProvider{TTL: time.Minute, AutoIDs: true, Now: tm.Now}
+	tm.Set(time.Now())
+	v.Put(msg(t, "old", ""), []string{sse.DefaultTopic})
+	tm.Add(time.Second * 30)
+	v.Put(msg(t, "new", ""), []string{sse.DefaultTopic})
+	tm.Add(time.Second * 45)
+
+	require.Equal(t, []string{"id: 1\ndata: new\n\n"}, iterData(v.Iter(sse.DefaultTopic, sse.EventID{})), "expired messages should be skipped")
+}
+
+func TestCompactedReplayProvider(t *testing.T) {
+	t.Parallel()
+
+	tm := &tests.Time{}
+	tm.Set(time.Now())
+
+	key := func(m *sse.Message) string {
+		data := m.String()
+		return data[strings.Index(data, "data: ")+len("data: ") : strings.Index(data, "=")]
+	}
+	p := &sse.CompactedReplayProvider{
+		Topics: map[string]sse.TopicRetention{
+			"state":  {Key: key},
+			"ticker": {TTL: time.Minute},
+		},
+		Now:     tm.Now,
+		AutoIDs: true,
+	}
+
+	p.Put(msg(t, "a=1", ""), []string{"state", "log"})
+	p.Put(msg(t, "b=1", ""), []string{"state"})
+	p.Put(msg(t, "t=1", ""), []string{"ticker"})
+	p.Put(msg(t, "a=2", ""), []string{"state"})
+	tm.Add(time.Second * 30)
+	p.Put(msg(t, "t=2", ""), []string{"ticker"})
+
+	require.Equal(t, []string{"id: 1\ndata: b=1\n\n", "id: 3\ndata: a=2\n\n"}, iterData(p.Iter("state", sse.EventID{})), "only the latest message of each key should be retained")
+	require.Equal(t, []string{"id: 0\ndata: a=1\n\n"}, iterData(p.Iter("log", sse.EventID{})), "compaction should apply only to the compacted topic")
+
+	var replayed []string
+	sub := sse.Subscription{
+		Client: mockClient(func(m *sse.Message) error {
+			if m != nil {
+				replayed = append(replayed, m.String())
+			}
+			return nil
+		}),
+		LastEventID: sse.ID("0"),
+		Topics:      []string{"state", "ticker"},
+	}
+	require.NoError(t, p.Replay(sub), "unexpected Replay error")
+	require.Equal(t, []string{"id: 1\ndata: b=1\n\n", "id: 2\ndata: t=1\n\n", "id: 3\ndata: a=2\n\n", "id: 4\ndata: t=2\n\n"}, replayed, "invalid replayed messages")
+
+	tm.Add(time.Second * 45)
+	require.NoError(t, p.GC(), "unexpected GC error")
+	require.Equal(t, []string{"id: 4\ndata: t=2\n\n"}, iterData(p.Iter("ticker", sse.EventID{})), "expired messages should be removed")
+
+	replayed = nil
+	require.NoError(t, p.Replay(sub), "replay from a removed message should work")
+	require.Equal(t, []string{"id: 1\ndata: b=1\n\n", "id: 3\ndata: a=2\n\n", "id: 4\ndata: t=2\n\n"}, replayed, "invalid replayed messages after GC")
+
+	replayed = nil
+	sub.LastEventID = sse.ID("unknown")
+	require.NoError(t, p.Replay(sub), "unexpected Replay error")
+	require.Empty(t, replayed, "invalid IDs should replay nothing")
+
+	withIDs := &sse.CompactedReplayProvider{Default: sse.TopicRetention{Key: key}}
+	withIDs.Put(msg(t, "a=1", "x"), []string{sse.DefaultTopic})
+	withIDs.Put(msg(t, "a=2", "y"), []string{sse.DefaultTopic})
+	require.NoError(t, withIDs.GC(), "unexpected GC error")
+	require.Equal(t, []string{"id: y\ndata: a=2\n\n"}, iterData(withIDs.Iter(sse.DefaultTopic, sse.EventID{})), "default policy not applied")
+	require.Empty(t, iterData(withIDs.Iter(sse.DefaultTopic, sse.ID("x"))), "compacted ID should be forgotten after GC")
+}
+
+func TestCompactedReplayProvider_GC(t *testing.T) {
+	t.Parallel()
+
+	key := func(m *sse.Message) string {
+		data := m.String()
+		return data[strings.Index(data, "data: ")+len("data: ") : strings.Index(data, "=")]
+	}
+	p := &sse.CompactedReplayProvider{Default: sse.TopicRetention{Key: key}, AutoIDs: true}
+
+	// The first key is never updated, so its message stays at the start of the buffer.
+	p.Put(msg(t, "pinned=1", ""), []string{"state.pinned"})
+	for i := 0; i < 100; i++ {
+		p.Put(msg(t, fmt.Sprintf("a=%d", i), ""), []string{"state.a"})
+	}
+	require.NoError(t, p.GC(), "unexpected GC error")
+
+	expected := []string{"id: 0\ndata: pinned=1\n\n", "id: 100\ndata: a=99\n\n"}
+	require.Equal(t, expected, iterData(p.Iter("state.*", sse.EventID{})), "compacted messages behind the first one should be removed")
+	require.Equal(t, expected[1:], iterData(p.Iter("state.*", sse.ID("50"))), "replay from a removed message should work")
+
+	var replayed []string
+	sub := sse.Subscription{
+		Client: mockClient(func(m *sse.Message) error {
+			if m != nil {
+				replayed = append(replayed, m.String())
+			}
+			return nil
+		}),
+		LastEventID: sse.ID("0"),
+		Topics:      []string{"state.*"},
+	}
+	require.NoError(t, p.Replay(sub), "unexpected Replay error")
+	require.Equal(t, expected[1:], replayed, "topic patterns should be replayed")
+}
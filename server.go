@@ -21,6 +21,8 @@ import (
 	"errors"
 	"net/http"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/exp/slog"
 )
@@ -34,12 +36,31 @@ type Subscription struct {
 	// The events will replay starting from the first valid event sent after the one with the given ID.
 	// If the ID is invalid replaying events will be omitted and new events will be sent as normal.
 	LastEventID EventID
+	// An optional time from which to replay events, for clients which have no last event ID.
+	// It is used only if LastEventID is unset and the replay provider implements ReplayProviderWithSince.
+	ReplaySince time.Time
 	// The topics to receive message from. If no topic is specified, a default topic is implied.
 	// Topics are orthogonal to event types. They are used to filter what the server sends to each client.
 	//
+	// Topics may be patterns, in which each '*' matches any sequence of characters: for example, "orders.*"
+	// receives the messages published to all the topics starting with "orders.". Patterns are supported
+	// by Joe and the replay providers of this package; other providers may not support them.
+	//
 	// If using a Provider directly, without a Server instance, you must specify at least one topic.
 	// The Server automatically adds the default topic if no topic is specified.
 	Topics []string
+	// The identity of the subscriber, for example a user ID. It is compared to the Origin of messages.
+	Identity string
+	// NoEcho makes the session not receive the messages it published itself, that is, the messages
+	// whose Origin is the subscription's Identity. Chat applications usually want the messages echoed,
+	// while command streams must exclude their originator. It has no effect if Identity is empty.
+	// NoEcho is implemented by the Server, so it is ignored when using a Provider directly.
+	NoEcho bool
+	// Filter makes the session receive only the messages for which it returns true – for example,
+	// the messages whose ID has a certain prefix or whose data contains a certain value – so sessions
+	// don't receive messages only to discard them. If it is nil, all messages are received.
+	// Filter is implemented by the Server, so it is ignored when using a Provider directly.
+	Filter func(*Message) bool
 }
 
 // A Provider is a publish-subscribe system that can be used to implement a HTML5 server-sent events
@@ -121,9 +142,141 @@ type Server struct {
 	// the data you want to be logged together with what the library adds,
 	// for example identification info like request IP, origin etc.
 	Logger func(*http.Request) *slog.Logger
+	// OnSend is called before each message is sent to a session's subscription.
+	// It can return a different message, adapted to the session – for example, to its
+	// advertised capabilities. The given message is shared by all the sessions, so it must
+	// not be modified; clone it instead. If nil is returned, the message is not sent
+	// to the session.
+	OnSend func(*Session, *Message) *Message
+	// SessionLimit is the maximum number of concurrent sessions with the same SessionKey.
+	// Browsers allow only six concurrent HTTP/1.1 connections per origin, so a user which opens
+	// too many streams (for example, in multiple tabs) blocks all its other requests to the server.
+	// Sessions over the limit are rejected with 429 Too Many Requests, whose body is a JSON object
+	// describing the error, so browsers don't reconnect. Use SessionLimitStats to find out how often
+	// this happens.
+	//
+	// If it is 0, sessions are not limited.
+	SessionLimit int
+	// SessionKey returns the key which identifies the sessions of a single browser.
+	// Defaults to a key made of the request's Origin header and the IP address of the client.
+	SessionKey func(*http.Request) string
+	// Quota is the maximum number of bytes sent in a QuotaPeriod to the sessions with the same QuotaKey,
+	// for example to meter the usage of paying customers. A session which would exceed the quota
+	// receives a message with the type QuotaExceededEventType, whose data is a JSON object describing
+	// the error, after which it is ended. The message's retry value makes clients reconnect only after
+	// the quota is reset. New sessions for keys which exceeded their quota are rejected with 429 Too
+	// Many Requests, whose Retry-After header tells when the quota is reset and whose body is the same
+	// JSON object, or with 204 No Content if the quota is never reset, so clients stop reconnecting.
+	// Use QuotaUsage to get the bytes sent to a key.
+	//
+	// If it is 0, no quota is enforced and the bytes sent are not tracked. To only track them,
+	// set a quota which can't be reached, such as math.MaxInt64.
+	Quota int64
+	// The period after which the bytes sent to a QuotaKey are reset. If it is 0, they are never reset.
+	QuotaPeriod time.Duration
+	// QuotaKey returns the key which identifies the sessions sharing a quota – for example, the sessions
+	// of the same user. Defaults to the SessionKey.
+	QuotaKey func(*http.Request) string
+	// TopicAliases maps deprecated topic names to their new names, for renaming topics without downtime.
+	// Messages published to either name are delivered to the sessions subscribed to any of them.
+	// Use TopicAliasStats to find out whether the deprecated names are still used.
+	// The map must not be modified after the server is used.
+	TopicAliases map[string]string
+	// ErrorReporter receives the errors of the sessions which can't be returned to the client,
+	// such as failed writes, and the panics of the handler, which are then propagated further.
+	// The Joe provider created by default also reports to it the panics of its replay provider.
+	ErrorReporter ErrorReporter
+	// Beacon makes the server send each session a message with the type SessionIDEventType, whose data
+	// is an ID with which the browser can release the session on page unload – see BeaconHandler.
+	// This frees the resources held by sessions whose client left without the server noticing.
+	Beacon bool
+	// DeduplicateMessages makes the server drop the messages which were already sent to a session,
+	// recognized by their address among the last 64 messages sent. Joe sends each message once to
+	// every session, but providers which match topic patterns may send a message once for each pattern
+	// a session's topics match. Don't use it with providers which reuse the Message values they send.
+	// Use TopicOverlapStats to find out how many messages were dropped.
+	DeduplicateMessages bool
+	// MaxSessionAge closes the sessions which have been open for this long, so a fleet of servers
+	// rebalances its sessions after scaling out, instead of the new servers sitting idle. Before being
+	// closed, sessions receive a message with the type ReconnectEventType, whose retry value is a random
+	// duration up to ReconnectJitter, so the clients don't all reconnect at once. A random duration of up
+	// to a tenth of MaxSessionAge is subtracted from the age of each session, so sessions started together
+	// aren't closed together. Use ExpiredSessions to find out how many sessions were closed.
+	//
+	// If it is 0, sessions are kept open indefinitely.
+	MaxSessionAge time.Duration
+	// The maximum retry value of the messages sent to the sessions closed because of the MaxSessionAge.
+	// Defaults to 5 seconds.
+	ReconnectJitter time.Duration
+	// EventVersions makes the server publish the messages whose type has registered versions
+	// once for each version, instead of once with the unversioned type. The versions of a message
+	// share its ID, so clients which handle multiple versions receive only one of them – see
+	// Connection.SubscribeEventVersions. For this, messages with versioned types must have IDs,
+	// so don't use it with replay providers which set the IDs of the messages.
+	EventVersions *EventVersions
+	// HeartbeatInterval makes the server send each session a message with the type HeartbeatEventType
+	// at this interval, whose data is the server's time. Clients use it to estimate the stream's transit
+	// latency and, together with the EchoHandler, the offset between their clock and the server's.
+	// Heartbeats aren't passed to OnSend or to the subscription's Filter, and aren't counted against the Quota.
+	// If it is 0, no heartbeats are sent.
+	HeartbeatInterval time.Duration
+	// KeepAlive makes the server write a comment to each session which wasn't sent anything for this long,
+	// so idle proxies and load balancers don't close the connection. Clients ignore comments.
+	// As heartbeats, the comments aren't passed to OnSend or to the subscription's Filter.
+	// If it is 0, no comments are written.
+	KeepAlive time.Duration
+	// The text of the keep-alive comments. Defaults to "ping", so the comments are ": ping".
+	KeepAliveComment string
+	// PublishQuota returns the maximum number of messages the given publisher can publish in a PublishQuotaPeriod,
+	// so the traffic of the teams or services sharing a server can be attributed and limited. Publishers are
+	// identified by the messages' Origin – see PublishContext. Messages over the quota are not published, and
+	// Publish returns a *PublishQuotaError for them. If it returns 0, the publisher is not limited.
+	// Use PublisherStats to find out how much each publisher published.
+	//
+	// If it is nil, no quota is enforced and the published messages are not tracked.
+	PublishQuota func(publisher string) int64
+	// The period after which the messages published by a publisher are reset. If it is 0, they are never reset.
+	PublishQuotaPeriod time.Duration
+	// OnSessionEnd is called after a session subscribed to the provider is closed, with the reason
+	// it was closed for. Use it, for example, to record metrics about disconnects – see also CloseReasons.
+	OnSessionEnd func(*Session, CloseReason)
+	// CloseEvents makes the server tell the sessions why they are closed, if they can still be written to,
+	// using a final message with the type CloseEventType. go-sse clients return the reason in a *ClosedError.
+	// The events aren't part of the SSE specification, so other clients dispatch them as usual –
+	// enable this only if the clients expect them.
+	CloseEvents bool
+	// Presence tracks which users are online, using the sessions whose subscription has an Identity.
+	// If it is nil, presence is not tracked.
+	Presence *Presence
+	// WriteTimeout is the maximum duration of sending a message to a session. The default provider, Joe,
+	// sends each message to the sessions one at a time, so a client which doesn't keep up with the stream,
+	// for example because of a bad network, delays the messages of all the other clients. The sessions
+	// which exceed the timeout are closed instead. Use SlowSessions to find out how often this happens.
+	// WriteTimeout requires Go 1.20 or newer, and HTTP servers which support write deadlines.
+	//
+	// If it is 0, sessions are never closed for being slow.
+	WriteTimeout time.Duration
+	// Compression makes the server compress the streams of the clients which accept gzip or deflate
+	// using the Accept-Encoding header, which cuts the bandwidth used by verbose payloads, such as JSON.
+	// Each message is still sent as soon as it is flushed, at the cost of a slightly worse compression.
+	// Compressed streams use more memory, as each session keeps its own compression state.
+	Compression bool
 
 	provider Provider
+	limiter  sessionLimiter
+	quotas   quotaTracker
+	beacons  beaconRegistry
+	aliases  topicAliases
+	overlaps topicOverlaps
+	expired  atomic.Uint64
+	slow     atomic.Uint64
+	closes   sessionCloses
+	draining atomic.Bool
+	retired  topicRetirement
+	topics   sessionTopics
 	initDone sync.Once
+
+	publishers publisherTracker
 }
 
 // ServeHTTP implements a default HTTP handler for a server.
@@ -142,11 +295,41 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	s.init()
 	// Make sure to keep the ServeHTTP implementation line number in sync with the number in the README!
 
+	if s.ErrorReporter != nil {
+		defer reportPanic(r.Context(), s.ErrorReporter)
+	}
+
 	l := s.logger(r)
 	if l != nil {
 		l.InfoContext(r.Context(), "sse: starting new session")
 	}
 
+	// The sessions over the limits are rejected before they are upgraded, as browsers reconnect
+	// to the streams which end after they started, but not to the failed requests.
+	if s.Quota > 0 {
+		if key := s.quotaKey(r); s.QuotaUsage(key) >= s.Quota {
+			if l != nil {
+				l.WarnContext(r.Context(), "sse: quota exceeded", "quota", s.Quota)
+			}
+
+			s.rejectOverQuota(w, key)
+			return
+		}
+	}
+
+	if s.SessionLimit > 0 {
+		key := s.sessionKey(r)
+		if !s.limiter.acquire(key, s.SessionLimit) {
+			if l != nil {
+				l.WarnContext(r.Context(), "sse: session limit exceeded", "limit", s.SessionLimit)
+			}
+
+			rejectSession(w, http.StatusTooManyRequests, sessionLimitError(s.SessionLimit))
+			return
+		}
+		defer s.limiter.release(key)
+	}
+
 	sess, err := Upgrade(w, r)
 	if err != nil {
 		if l != nil {
@@ -157,6 +340,13 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if s.Compression {
+		if cw := newCompressWriter(sess.Res, r); cw != nil {
+			sess.Res = cw
+			defer cw.Close()
+		}
+	}
+
 	sub, ok := s.getSubscription(sess)
 	if !ok {
 		if l != nil {
@@ -166,20 +356,140 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	var overlap bool
+	if sub.Topics, overlap = dedupeTopics(s.resolveTopics(sub.Topics, false)); overlap {
+		s.overlaps.sessions.Add(1)
+		if l != nil {
+			l.WarnContext(r.Context(), "sse: session subscribed to overlapping topics", "topics", getTopicsLog(sub.Topics))
+		}
+	}
+
+	ctx, topics, unregister, ok := s.retired.register(r.Context(), sub.Topics)
+	if !ok {
+		if l != nil {
+			l.InfoContext(r.Context(), "sse: session subscribed to retired topics", "topics", getTopicsLog(sub.Topics))
+		}
+
+		_ = sess.EndStream()
+		return
+	}
+	defer unregister()
+	sub.Topics = topics
+	retireCtx := ctx
+
+	if s.WriteTimeout > 0 {
+		sub.Client = withWriteTimeout(sub.Client, w, s.WriteTimeout)
+	}
+
+	// The heartbeats and the keep-alive comments are written under the wrappers below, so they
+	// aren't filtered, transformed or counted as the messages published to the session are.
+	var locked *lockedWriter
+	if s.HeartbeatInterval > 0 || s.KeepAlive > 0 {
+		locked = &lockedWriter{MessageWriter: sub.Client, sent: time.Now()}
+		sub.Client = locked
+	}
+
+	if s.Quota > 0 {
+		sub.Client = quotaWriter{MessageWriter: sub.Client, s: s, key: s.quotaKey(r)}
+	}
+
+	if s.OnSend != nil {
+		sub.Client = onSendWriter{MessageWriter: sub.Client, sess: sess, onSend: s.OnSend}
+	}
+
+	if sub.NoEcho && sub.Identity != "" {
+		sub.Client = noEchoWriter{MessageWriter: sub.Client, identity: sub.Identity}
+	}
+
+	if sub.Filter != nil {
+		sub.Client = filterWriter{MessageWriter: sub.Client, filter: sub.Filter}
+	}
+
+	if s.DeduplicateMessages {
+		sub.Client = &dedupeWriter{MessageWriter: sub.Client, dropped: &s.overlaps.dropped}
+	}
+
+	ctx, closedFor, unregisterClose := s.closes.register(ctx, sub.Identity)
+	defer unregisterClose()
+
+	beaconCtx := ctx
+	if s.Beacon {
+		var id string
+		ctx, id = s.beacons.register(ctx)
+		beaconCtx = ctx
+		defer s.beacons.unregister(id)
+
+		m := &Message{Type: Type(SessionIDEventType)}
+		m.AppendData(id)
+		if err := sess.Send(m); err != nil {
+			return
+		}
+		if err := sess.Flush(); err != nil {
+			return
+		}
+	}
+
+	if s.Presence != nil && sub.Identity != "" {
+		s.Presence.join(sub.Identity)
+		defer s.Presence.leave(sub.Identity)
+	}
+
+	ctx, cancel, expired := s.withSessionAge(ctx)
+	defer cancel()
+
+	stopHeartbeats, stopKeepAlive := func() {}, func() {}
+	if locked != nil {
+		stopHeartbeats = s.startHeartbeats(ctx, locked)
+		defer stopHeartbeats()
+
+		stopKeepAlive = s.startKeepAlive(ctx, locked)
+		defer stopKeepAlive()
+	}
+
 	if l != nil {
 		l.InfoContext(r.Context(), "sse: subscribing session", "topics", getTopicsLog(sub.Topics), "lastEventID", sub.LastEventID)
 	}
 
-	if err = s.provider.Subscribe(r.Context(), sub); err != nil {
+	err = s.subscribe(ctx, sub)
+	// The session is written to directly from now on.
+	stopHeartbeats()
+	stopKeepAlive()
+
+	reason, notify := CloseError, true
+	if errors.Is(err, ErrQuotaExceeded) {
+		reason = CloseQuota
+		if l != nil {
+			l.WarnContext(r.Context(), "sse: quota exceeded", "quota", s.Quota)
+		}
+	} else if errors.Is(err, ErrSlowSession) {
+		reason = CloseSlowConsumer
+		s.slow.Add(1)
+		if l != nil {
+			l.WarnContext(r.Context(), "sse: session too slow", "writeTimeout", s.WriteTimeout)
+		}
+	} else if err != nil {
 		if l != nil {
 			l.ErrorContext(r.Context(), "sse: subscribe error", "err", err)
 		}
+		if s.ErrorReporter != nil {
+			s.ErrorReporter.ReportError(r.Context(), err)
+		}
+	} else {
+		reason, notify = s.sessionCloseReason(r.Context(), retireCtx, beaconCtx, closedFor, expired)
+	}
+
+	if reason == CloseMaxAge {
+		s.expired.Add(1)
+		if l != nil {
+			l.InfoContext(r.Context(), "sse: session reached its maximum age", "maxAge", s.MaxSessionAge)
+		}
 
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+		_ = sess.Send(s.reconnectMessage())
 	}
 
-	if l != nil {
+	s.endSession(w, sess, reason, notify, err)
+
+	if l != nil && reason != CloseError {
 		l.InfoContext(r.Context(), "sse: session ended")
 	}
 }
@@ -188,7 +498,78 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // The topics are optional - if none are specified, the event is published to the DefaultTopic.
 func (s *Server) Publish(e *Message, topics ...string) error {
 	s.init()
-	return s.provider.Publish(e, getTopics(topics))
+
+	if err := s.chargePublisher(e); err != nil {
+		return err
+	}
+
+	topics = s.resolveTopics(getTopics(topics), true)
+
+	if s.EventVersions != nil {
+		versions, err := s.EventVersions.transform(e)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range versions {
+			if err := s.provider.Publish(v, topics); err != nil {
+				return err
+			}
+		}
+
+		if versions != nil {
+			return nil
+		}
+	}
+
+	return s.provider.Publish(e, topics)
+}
+
+// PublishContext is the same as Publish, but if the message has no TraceID, it is set to the trace ID
+// carried by the context, if any, and if it has no Origin, it is set to the publisher carried by the context.
+// See ContextWithTraceID and ContextWithPublisher.
+func (s *Server) PublishContext(ctx context.Context, e *Message, topics ...string) error {
+	id, publisher := TraceIDFromContext(ctx), PublisherFromContext(ctx)
+	if (id != "" && e.TraceID == "") || (publisher != "" && e.Origin == "") {
+		e = e.Clone()
+		if e.TraceID == "" {
+			e.TraceID = id
+		}
+		if e.Origin == "" {
+			e.Origin = publisher
+		}
+	}
+
+	return s.Publish(e, topics...)
+}
+
+// PublishFromChannel publishes the values received from the given channel to the given topic,
+// until the channel is closed or the context is done. Each value is converted to a Message using
+// the marshal function. The returned error is nil if the channel was closed.
+//
+// Values are published one at a time, as they are received: if publishing blocks, the channel won't
+// be drained further, so its producers are slowed down accordingly. Publishing stops at the first
+// marshalling or publishing error, which is returned.
+func (s *Server) PublishFromChannel(ctx context.Context, topic string, ch <-chan any, marshal func(any) (*Message, error)) error {
+	for {
+		select {
+		case v, ok := <-ch:
+			if !ok {
+				return nil
+			}
+
+			m, err := marshal(v)
+			if err != nil {
+				return err
+			}
+
+			if err := s.Publish(m, topic); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 }
 
 // Shutdown closes all the connections and stops the server. Publish operations will fail
@@ -196,19 +577,41 @@ func (s *Server) Publish(e *Message, topics ...string) error {
 //
 // Call this method when shutting down the HTTP server using http.Server's RegisterOnShutdown
 // method. Not doing this will result in the server never shutting down or connections being
-// abruptly stopped.
+// abruptly stopped. Once Shutdown is called, Healthy reports that the server is draining.
 //
 // See the Provider.Shutdown documentation for information on context usage and errors.
 func (s *Server) Shutdown(ctx context.Context) error {
 	s.init()
+	s.draining.Store(true)
 	return s.provider.Shutdown(ctx)
 }
 
+// SetReplayProvider replaces the replay provider of the server's provider at runtime, without downtime.
+// For the given overlap duration, both the previous and the new replay provider are used, so the replay
+// window isn't lost – see Joe.SetReplayProvider for details. It returns ErrReplaySwapUnsupported if the
+// server's provider doesn't have a SetReplayProvider method with the same signature.
+func (s *Server) SetReplayProvider(replay ReplayProvider, overlap time.Duration) error {
+	s.init()
+
+	p, ok := s.provider.(interface {
+		SetReplayProvider(ReplayProvider, time.Duration) error
+	})
+	if !ok {
+		return ErrReplaySwapUnsupported
+	}
+
+	return p.SetReplayProvider(replay, overlap)
+}
+
+// ErrReplaySwapUnsupported is returned by Server.SetReplayProvider when the server's
+// provider doesn't support replacing its replay provider.
+var ErrReplaySwapUnsupported = errors.New("go-sse.server: provider doesn't support replacing the replay provider")
+
 func (s *Server) init() {
 	s.initDone.Do(func() {
 		s.provider = s.Provider
 		if s.provider == nil {
-			s.provider = &Joe{}
+			s.provider = &Joe{ErrorReporter: s.ErrorReporter}
 		}
 	})
 }
@@ -221,6 +624,7 @@ func (s *Server) getSubscription(sess *Session) (Subscription, bool) {
 	return Subscription{
 		Client:      sess,
 		LastEventID: sess.LastEventID,
+		ReplaySince: sess.ReplaySince,
 		Topics:      defaultTopicSlice,
 	}, true
 }
@@ -233,6 +637,46 @@ func (s *Server) logger(r *http.Request) *slog.Logger {
 	return nil
 }
 
+type onSendWriter struct {
+	MessageWriter
+	sess   *Session
+	onSend func(*Session, *Message) *Message
+}
+
+func (o onSendWriter) Send(m *Message) error {
+	if m = o.onSend(o.sess, m); m == nil {
+		return nil
+	}
+
+	return o.MessageWriter.Send(m)
+}
+
+type noEchoWriter struct {
+	MessageWriter
+	identity string
+}
+
+func (n noEchoWriter) Send(m *Message) error {
+	if m.Origin == n.identity {
+		return nil
+	}
+
+	return n.MessageWriter.Send(m)
+}
+
+type filterWriter struct {
+	MessageWriter
+	filter func(*Message) bool
+}
+
+func (f filterWriter) Send(m *Message) error {
+	if !f.filter(m) {
+		return nil
+	}
+
+	return f.MessageWriter.Send(m)
+}
+
 var defaultTopicSlice = []string{DefaultTopic}
 
 func getTopics(initial []string) []string {
@@ -0,0 +1,79 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// ReconnectEventType is the type of the message sent to the sessions closed because of the Server's MaxSessionAge.
+const ReconnectEventType = "reconnect"
+
+// defaultReconnectJitter is the default maximum retry value of the reconnect messages.
+const defaultReconnectJitter = 5 * time.Second
+
+// ExpiredSessions returns the number of sessions closed because of the MaxSessionAge.
+func (s *Server) ExpiredSessions() uint64 {
+	return s.expired.Load()
+}
+
+// sessionAge returns for how long the next session is kept open, or 0 if it is kept indefinitely.
+func (s *Server) sessionAge() time.Duration {
+	if s.MaxSessionAge <= 0 {
+		return 0
+	}
+
+	jitter := s.MaxSessionAge / 10
+	if jitter <= 0 {
+		return s.MaxSessionAge
+	}
+
+	return s.MaxSessionAge - time.Duration(rand.Int63n(int64(jitter)+1)) //nolint:gosec // Jitter doesn't need a secure generator.
+}
+
+// withSessionAge returns a context which is done when the session reaches its age, and a function
+// which reports whether it did. The context must be canceled when the session ends.
+func (s *Server) withSessionAge(ctx context.Context) (context.Context, context.CancelFunc, func() bool) {
+	age := s.sessionAge()
+	if age <= 0 {
+		return ctx, func() {}, func() bool { return false }
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	var expired atomic.Bool
+	t := time.AfterFunc(age, func() {
+		expired.Store(true)
+		cancel()
+	})
+
+	return ctx, func() { t.Stop(); cancel() }, expired.Load
+}
+
+type reconnectNotice struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (s *Server) reconnectMessage() *Message {
+	jitter := s.ReconnectJitter
+	if jitter <= 0 {
+		jitter = defaultReconnectJitter
+	}
+
+	data, _ := json.Marshal(reconnectNotice{
+		Reason:  "max_session_age",
+		Message: "The session reached its maximum age. Reconnect to continue receiving events.",
+	})
+
+	// The retry value is at least a millisecond, as a value of 0 is not sent.
+	m := &Message{
+		Type:  Type(ReconnectEventType),
+		Retry: time.Millisecond + time.Duration(rand.Int63n(int64(jitter))), //nolint:gosec // Jitter doesn't need a secure generator.
+	}
+	m.AppendData(string(data))
+
+	return m
+}
@@ -0,0 +1,85 @@
+package sse
+
+import "sync"
+
+// TopicAliasUsage describes how often a deprecated topic name is still used. See Server.TopicAliases.
+type TopicAliasUsage struct {
+	// The number of messages published to the deprecated name.
+	Publishes uint64
+	// The number of sessions subscribed to the deprecated name.
+	Subscriptions uint64
+}
+
+// TopicAliasStats returns the usage of each deprecated topic name in the TopicAliases, so you know
+// when it is safe to remove them. Names which weren't used are not included.
+func (s *Server) TopicAliasStats() map[string]TopicAliasUsage {
+	return s.aliases.stats()
+}
+
+// resolveTopics returns the given topics together with all their aliases.
+func (s *Server) resolveTopics(topics []string, isPublish bool) []string {
+	if len(s.TopicAliases) == 0 {
+		return topics
+	}
+
+	var resolved []string
+	add := func(topic string) {
+		for _, t := range resolved {
+			if t == topic {
+				return
+			}
+		}
+		resolved = append(resolved, topic)
+	}
+
+	for _, topic := range topics {
+		add(topic)
+
+		if current, ok := s.TopicAliases[topic]; ok {
+			s.aliases.record(topic, isPublish)
+			add(current)
+		}
+
+		for deprecated, current := range s.TopicAliases {
+			if current == topic {
+				add(deprecated)
+			}
+		}
+	}
+
+	return resolved
+}
+
+type topicAliases struct {
+	usage map[string]TopicAliasUsage
+	mu    sync.Mutex
+}
+
+func (a *topicAliases) record(deprecated string, isPublish bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.usage == nil {
+		a.usage = map[string]TopicAliasUsage{}
+	}
+
+	u := a.usage[deprecated]
+	if isPublish {
+		u.Publishes++
+	} else {
+		u.Subscriptions++
+	}
+	a.usage[deprecated] = u
+}
+
+func (a *topicAliases) stats() map[string]TopicAliasUsage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	stats := make(map[string]TopicAliasUsage, len(a.usage))
+	for k, v := range a.usage {
+		stats[k] = v
+	}
+
+	return stats
+}
@@ -0,0 +1,117 @@
+package sse
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SessionIDEventType is the type of the message which tells a session its ID, if the Server's
+// Beacon option is set. The message's data is the ID.
+const SessionIDEventType = "session-id"
+
+// BeaconStats describes the sessions released using the Server's BeaconHandler.
+type BeaconStats struct {
+	// The number of sessions which can currently be released by a beacon.
+	Active int
+	// The number of sessions released by a beacon. These are the ghost sessions: the browser
+	// had already left, but the server would have noticed only after the TCP connection timed out.
+	Released uint64
+	// The number of beacons received for sessions which had already ended or never existed.
+	Unknown uint64
+}
+
+// BeaconStats returns statistics about the sessions released by beacons.
+func (s *Server) BeaconStats() BeaconStats {
+	return s.beacons.stats()
+}
+
+// BeaconHandler returns the handler of the endpoint to which browsers send their session's ID
+// on page unload, to release the session immediately. The server must have the Beacon option set.
+// The handler accepts only POST requests, whose body is the session ID:
+//
+//	let sessionID
+//	source.addEventListener('session-id', (e) => { sessionID = e.data })
+//	window.addEventListener('pagehide', () => navigator.sendBeacon('/events/beacon', sessionID))
+//
+// It always responds with 204 No Content, as browsers ignore the responses to beacons.
+func (s *Server) BeaconHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		// IDs are 32 characters long, so anything longer is invalid.
+		id, _ := io.ReadAll(io.LimitReader(r.Body, 64))
+		s.beacons.release(strings.TrimSpace(string(id)))
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+type beaconRegistry struct {
+	sessions map[string]context.CancelFunc
+	st       BeaconStats
+	mu       sync.Mutex
+}
+
+// register returns the ID of a new session and a context which is canceled when a beacon is received for it.
+// The session must be unregistered when it ends.
+func (b *beaconRegistry) register(ctx context.Context) (context.Context, string) {
+	var buf [16]byte
+	_, _ = rand.Read(buf[:])
+	id := hex.EncodeToString(buf[:])
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.sessions == nil {
+		b.sessions = map[string]context.CancelFunc{}
+	}
+	b.sessions[id] = cancel
+
+	return ctx, id
+}
+
+func (b *beaconRegistry) unregister(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if cancel, ok := b.sessions[id]; ok {
+		cancel()
+		delete(b.sessions, id)
+	}
+}
+
+func (b *beaconRegistry) release(id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cancel, ok := b.sessions[id]
+	if !ok {
+		b.st.Unknown++
+		return
+	}
+
+	cancel()
+	delete(b.sessions, id)
+	b.st.Released++
+}
+
+func (b *beaconRegistry) stats() BeaconStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	st := b.st
+	st.Active = len(b.sessions)
+
+	return st
+}
@@ -0,0 +1,160 @@
+package sse
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// CloseReasons returns the number of sessions closed for each reason. Only the sessions
+// which were subscribed to the provider are counted.
+func (s *Server) CloseReasons() map[CloseReason]uint64 {
+	return s.closes.counts()
+}
+
+// CloseSessions closes the sessions whose subscription has the given Identity, for the given reason
+// – for example, CloseAuthRevoked when a user logs out. It returns the number of sessions closed.
+func (s *Server) CloseSessions(identity string, reason CloseReason) int {
+	return s.closes.close(identity, reason)
+}
+
+type closableSession struct {
+	cancel context.CancelFunc
+	reason CloseReason
+}
+
+type sessionCloses struct {
+	sessions map[string]map[*closableSession]struct{}
+	reasons  map[CloseReason]uint64
+	mu       sync.Mutex
+}
+
+// register returns a context which is canceled when the sessions with the given identity are closed,
+// and a function which returns the reason they were closed for, if they were.
+func (c *sessionCloses) register(ctx context.Context, identity string) (context.Context, func() (CloseReason, bool), func()) {
+	if identity == "" {
+		return ctx, func() (CloseReason, bool) { return "", false }, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sess := &closableSession{cancel: cancel}
+
+	c.mu.Lock()
+	if c.sessions == nil {
+		c.sessions = map[string]map[*closableSession]struct{}{}
+	}
+	if c.sessions[identity] == nil {
+		c.sessions[identity] = map[*closableSession]struct{}{}
+	}
+	c.sessions[identity][sess] = struct{}{}
+	c.mu.Unlock()
+
+	reason := func() (CloseReason, bool) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		return sess.reason, sess.reason != ""
+	}
+
+	unregister := func() {
+		cancel()
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.sessions[identity], sess)
+		if len(c.sessions[identity]) == 0 {
+			delete(c.sessions, identity)
+		}
+	}
+
+	return ctx, reason, unregister
+}
+
+func (c *sessionCloses) close(identity string, reason CloseReason) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	closed := 0
+	for sess := range c.sessions[identity] {
+		if sess.reason == "" {
+			sess.reason = reason
+			sess.cancel()
+			closed++
+		}
+	}
+
+	return closed
+}
+
+func (c *sessionCloses) count(reason CloseReason) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.reasons == nil {
+		c.reasons = map[CloseReason]uint64{}
+	}
+	c.reasons[reason]++
+}
+
+func (c *sessionCloses) counts() map[CloseReason]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[CloseReason]uint64, len(c.reasons))
+	for r, n := range c.reasons {
+		counts[r] = n
+	}
+
+	return counts
+}
+
+// endSession records why the session was closed, notifies the OnSessionEnd callback and,
+// if the session can still be written to, notify is true and CloseEvents is enabled, sends it the reason.
+func (s *Server) endSession(w http.ResponseWriter, sess *Session, reason CloseReason, notify bool, err error) {
+	s.closes.count(reason)
+
+	switch {
+	case !notify || reason == CloseClientCancel || reason == CloseSlowConsumer:
+	case reason == CloseError && !sess.didUpgrade:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	case s.CloseEvents:
+		if sess.Send(closeMessage(reason)) == nil {
+			_ = sess.Flush()
+		}
+	}
+
+	if s.OnSessionEnd != nil {
+		s.OnSessionEnd(sess, reason)
+	}
+}
+
+// sessionCloseReason returns why a session whose subscription ended without an error was closed,
+// by checking the contexts of the request and of the topic retirement, the CloseSessions calls and
+// the context of the beacon, in this order, as each is derived from the previous one. The session
+// is not notified if the provider ended the subscription by itself, as the provider may have
+// already told the client why.
+func (s *Server) sessionCloseReason(
+	req, retired, beacon context.Context,
+	closedFor func() (CloseReason, bool),
+	expired func() bool,
+) (reason CloseReason, notify bool) {
+	switch {
+	case req.Err() != nil:
+		return CloseClientCancel, true
+	case retired.Err() != nil:
+		return CloseRetired, true
+	}
+
+	if reason, ok := closedFor(); ok {
+		return reason, true
+	}
+	if beacon.Err() != nil {
+		return CloseClientCancel, true
+	}
+	if expired() {
+		return CloseMaxAge, true
+	}
+
+	return CloseServerShutdown, s.draining.Load()
+}
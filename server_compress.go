@@ -0,0 +1,129 @@
+package sse
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// compressFlusher is implemented by both gzip and zlib writers.
+type compressFlusher interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter compresses what is written to the response. The compression starts with the first
+// write or flush, unless a status other than 200 OK is written first – for example, an error response
+// written in the OnSession callback – in which case the response is not compressed.
+type compressWriter struct {
+	ResponseWriter
+	encoding string
+	w        compressFlusher
+	plain    bool
+}
+
+// newCompressWriter returns a writer which compresses the response using the encoding preferred
+// by the client, or nil if the client accepts neither gzip nor deflate.
+func newCompressWriter(res ResponseWriter, r *http.Request) *compressWriter {
+	encoding := negotiateEncoding(r.Header.Values("Accept-Encoding"))
+	if encoding == "" {
+		return nil
+	}
+
+	return &compressWriter{ResponseWriter: res, encoding: encoding}
+}
+
+func (c *compressWriter) start() {
+	if c.w != nil || c.plain {
+		return
+	}
+
+	h := c.Header()
+	h.Set("Content-Encoding", c.encoding)
+	h.Add("Vary", "Accept-Encoding")
+	h.Del("Content-Length")
+
+	if c.encoding == "gzip" {
+		c.w = gzip.NewWriter(c.ResponseWriter)
+	} else {
+		c.w = zlib.NewWriter(c.ResponseWriter)
+	}
+}
+
+func (c *compressWriter) WriteHeader(code int) {
+	if c.w == nil && code != http.StatusOK {
+		c.plain = true
+	}
+	c.start()
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *compressWriter) Write(p []byte) (int, error) {
+	c.start()
+	if c.plain {
+		return c.ResponseWriter.Write(p)
+	}
+	return c.w.Write(p)
+}
+
+// Flush writes the data compressed so far to the client, so each message is received
+// as soon as it is flushed, as with uncompressed streams.
+func (c *compressWriter) Flush() error {
+	c.start()
+	if !c.plain {
+		if err := c.w.Flush(); err != nil {
+			return err
+		}
+	}
+	return c.ResponseWriter.Flush()
+}
+
+// Close ends the compressed stream, if it was started.
+func (c *compressWriter) Close() error {
+	if c.w == nil {
+		return nil
+	}
+	if err := c.w.Close(); err != nil {
+		return err
+	}
+	return c.ResponseWriter.Flush()
+}
+
+// negotiateEncoding returns the encoding the server compresses streams with which the client
+// accepts with the highest quality, preferring gzip. It returns an empty string if there's none.
+func negotiateEncoding(accept []string) string {
+	var best string
+	var bestQ float64
+
+	for _, v := range accept {
+		for _, part := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(part, ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+
+			q := 1.0
+			if k, v, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(k) == "q" {
+				var err error
+				if q, err = strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+					continue
+				}
+			}
+
+			switch name {
+			case "gzip", "x-gzip", "*":
+				name = "gzip"
+			case "deflate":
+			default:
+				continue
+			}
+
+			if q > bestQ || (q == bestQ && q > 0 && name == "gzip") {
+				best, bestQ = name, q
+			}
+		}
+	}
+
+	return best
+}
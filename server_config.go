@@ -0,0 +1,248 @@
+package sse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig is the configuration of a Server which can be loaded from the environment, using
+// ServerConfigFromEnv, or from a JSON file, using ReadServerConfig. This allows deploying servers
+// without hardcoding their limits. Apply it to a Server using the Apply method.
+type ServerConfig struct {
+	// See Server.SessionLimit.
+	SessionLimit int
+	// See Server.Quota.
+	Quota int64
+	// See Server.QuotaPeriod.
+	QuotaPeriod time.Duration
+	// See Server.Beacon.
+	Beacon bool
+	// See Server.KeepAlive.
+	KeepAlive time.Duration
+	// See Server.KeepAliveComment.
+	KeepAliveComment string
+	// Whether the server's Prometheus metrics are collected. Apply ignores it, as the metrics are
+	// collected by the sseprom module: configure the server using sseprom.ConfigureServer instead.
+	Metrics bool
+
+	// The number of messages replayed, if positive. See FiniteReplayProvider.
+	ReplayCount int
+	// For how long messages are replayed, if positive and ReplayCount is not set. See ValidReplayProvider.
+	ReplayTTL time.Duration
+	// See Joe.ReplayGCInterval. Defaults to ReplayTTL.
+	ReplayGCInterval time.Duration
+	// Whether the replay provider sets the IDs of the messages.
+	ReplayAutoIDs bool
+
+	// See Joe.HighWaterMarks.
+	HighWaterMarks map[string]int
+	// See Joe.CongestionPolicy.
+	CongestionPolicy CongestionPolicy
+}
+
+// The environment variables read by ServerConfigFromEnv. Durations use the format accepted by
+// time.ParseDuration, high-water marks are comma-separated topic=mark pairs and the congestion
+// policy is either "reject" or "block".
+const (
+	EnvSessionLimit     = "SSE_SESSION_LIMIT"
+	EnvQuota            = "SSE_QUOTA"
+	EnvQuotaPeriod      = "SSE_QUOTA_PERIOD"
+	EnvBeacon           = "SSE_BEACON"
+	EnvKeepAlive        = "SSE_KEEP_ALIVE"
+	EnvKeepAliveComment = "SSE_KEEP_ALIVE_COMMENT"
+	EnvMetrics          = "SSE_METRICS"
+	EnvReplayCount      = "SSE_REPLAY_COUNT"
+	EnvReplayTTL        = "SSE_REPLAY_TTL"
+	EnvReplayGCInterval = "SSE_REPLAY_GC_INTERVAL"
+	EnvReplayAutoIDs    = "SSE_REPLAY_AUTO_IDS"
+	EnvHighWaterMarks   = "SSE_HIGH_WATER_MARKS"
+	EnvCongestionPolicy = "SSE_CONGESTION_POLICY"
+)
+
+// ServerConfigFromEnv loads the server configuration from the environment variables listed above.
+// The variables which aren't set are left at their zero value. An error is returned if any value is invalid.
+func ServerConfigFromEnv() (ServerConfig, error) {
+	var c ServerConfig
+	err := c.load(func(name string) (string, bool) { return os.LookupEnv(name) })
+
+	return c, err
+}
+
+// ReadServerConfig reads the server configuration from a JSON object. The object's keys are the
+// names of the ServerConfig fields in camel case, for example "sessionLimit" or "replayTTL".
+// Durations are strings in the format accepted by time.ParseDuration, and the congestion policy
+// is either "reject" or "block". Unknown keys are an error.
+func ReadServerConfig(r io.Reader) (ServerConfig, error) {
+	var raw struct {
+		SessionLimit     *int           `json:"sessionLimit"`
+		Quota            *int64         `json:"quota"`
+		QuotaPeriod      *string        `json:"quotaPeriod"`
+		Beacon           *bool          `json:"beacon"`
+		KeepAlive        *string        `json:"keepAlive"`
+		KeepAliveComment *string        `json:"keepAliveComment"`
+		Metrics          *bool          `json:"metrics"`
+		ReplayCount      *int           `json:"replayCount"`
+		ReplayTTL        *string        `json:"replayTTL"`
+		ReplayGCInterval *string        `json:"replayGCInterval"`
+		ReplayAutoIDs    *bool          `json:"replayAutoIDs"`
+		HighWaterMarks   map[string]int `json:"highWaterMarks"`
+		CongestionPolicy *string        `json:"congestionPolicy"`
+	}
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return ServerConfig{}, fmt.Errorf("go-sse.server: invalid config: %w", err)
+	}
+
+	values := map[string]string{}
+	set := func(name string, v any, ok bool) {
+		if ok {
+			values[name] = fmt.Sprint(v)
+		}
+	}
+	set(EnvSessionLimit, deref(raw.SessionLimit), raw.SessionLimit != nil)
+	set(EnvQuota, deref(raw.Quota), raw.Quota != nil)
+	set(EnvQuotaPeriod, deref(raw.QuotaPeriod), raw.QuotaPeriod != nil)
+	set(EnvBeacon, deref(raw.Beacon), raw.Beacon != nil)
+	set(EnvKeepAlive, deref(raw.KeepAlive), raw.KeepAlive != nil)
+	set(EnvKeepAliveComment, deref(raw.KeepAliveComment), raw.KeepAliveComment != nil)
+	set(EnvMetrics, deref(raw.Metrics), raw.Metrics != nil)
+	set(EnvReplayCount, deref(raw.ReplayCount), raw.ReplayCount != nil)
+	set(EnvReplayTTL, deref(raw.ReplayTTL), raw.ReplayTTL != nil)
+	set(EnvReplayGCInterval, deref(raw.ReplayGCInterval), raw.ReplayGCInterval != nil)
+	set(EnvReplayAutoIDs, deref(raw.ReplayAutoIDs), raw.ReplayAutoIDs != nil)
+	set(EnvCongestionPolicy, deref(raw.CongestionPolicy), raw.CongestionPolicy != nil)
+
+	var c ServerConfig
+	if err := c.load(func(name string) (string, bool) {
+		v, ok := values[name]
+		return v, ok
+	}); err != nil {
+		return ServerConfig{}, err
+	}
+	c.HighWaterMarks = raw.HighWaterMarks
+
+	return c, nil
+}
+
+func deref[T any](p *T) (v T) {
+	if p != nil {
+		v = *p
+	}
+	return
+}
+
+// Apply configures the server. If a replay provider or high-water marks are configured,
+// the server's provider is set to a Joe which uses them, so the server must not have a provider.
+func (c ServerConfig) Apply(s *Server) {
+	s.SessionLimit = c.SessionLimit
+	s.Quota = c.Quota
+	s.QuotaPeriod = c.QuotaPeriod
+	s.Beacon = c.Beacon
+	s.KeepAlive = c.KeepAlive
+	s.KeepAliveComment = c.KeepAliveComment
+
+	var replay ReplayProvider
+	gcInterval := c.ReplayGCInterval
+	if c.ReplayCount > 0 {
+		replay = &FiniteReplayProvider{Count: c.ReplayCount, AutoIDs: c.ReplayAutoIDs}
+	} else if c.ReplayTTL > 0 {
+		replay = &ValidReplayProvider{TTL: c.ReplayTTL, AutoIDs: c.ReplayAutoIDs}
+		if gcInterval <= 0 {
+			gcInterval = c.ReplayTTL
+		}
+	}
+
+	if replay == nil && len(c.HighWaterMarks) == 0 {
+		return
+	}
+
+	s.Provider = &Joe{
+		ReplayProvider:   replay,
+		ReplayGCInterval: gcInterval,
+		HighWaterMarks:   c.HighWaterMarks,
+		CongestionPolicy: c.CongestionPolicy,
+		ErrorReporter:    s.ErrorReporter,
+	}
+}
+
+// load sets the configuration from the values returned by the lookup function for the environment variables.
+func (c *ServerConfig) load(lookup func(string) (string, bool)) error {
+	var err error
+	parse := func(name string, fn func(string) error) {
+		v, ok := lookup(name)
+		if !ok || err != nil {
+			return
+		}
+		if e := fn(strings.TrimSpace(v)); e != nil {
+			err = fmt.Errorf("go-sse.server: invalid %s: %w", name, e)
+		}
+	}
+	parseInt := func(dst *int) func(string) error {
+		return func(v string) (e error) { *dst, e = strconv.Atoi(v); return }
+	}
+	parseDuration := func(dst *time.Duration) func(string) error {
+		return func(v string) (e error) { *dst, e = time.ParseDuration(v); return }
+	}
+	parseBool := func(dst *bool) func(string) error {
+		return func(v string) (e error) { *dst, e = strconv.ParseBool(v); return }
+	}
+
+	parse(EnvSessionLimit, parseInt(&c.SessionLimit))
+	parse(EnvQuota, func(v string) (e error) { c.Quota, e = strconv.ParseInt(v, 10, 64); return })
+	parse(EnvQuotaPeriod, parseDuration(&c.QuotaPeriod))
+	parse(EnvBeacon, parseBool(&c.Beacon))
+	parse(EnvKeepAlive, parseDuration(&c.KeepAlive))
+	parse(EnvKeepAliveComment, func(v string) error { c.KeepAliveComment = v; return nil })
+	parse(EnvMetrics, parseBool(&c.Metrics))
+	parse(EnvReplayCount, parseInt(&c.ReplayCount))
+	parse(EnvReplayTTL, parseDuration(&c.ReplayTTL))
+	parse(EnvReplayGCInterval, parseDuration(&c.ReplayGCInterval))
+	parse(EnvReplayAutoIDs, parseBool(&c.ReplayAutoIDs))
+	parse(EnvHighWaterMarks, c.parseHighWaterMarks)
+	parse(EnvCongestionPolicy, c.parseCongestionPolicy)
+
+	return err
+}
+
+func (c *ServerConfig) parseHighWaterMarks(v string) error {
+	if v == "" {
+		return nil
+	}
+
+	c.HighWaterMarks = map[string]int{}
+	for _, pair := range strings.Split(v, ",") {
+		topic, mark, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("expected topic=mark, received %q", pair)
+		}
+
+		n, err := strconv.Atoi(strings.TrimSpace(mark))
+		if err != nil {
+			return err
+		}
+
+		c.HighWaterMarks[strings.TrimSpace(topic)] = n
+	}
+
+	return nil
+}
+
+func (c *ServerConfig) parseCongestionPolicy(v string) error {
+	switch strings.ToLower(v) {
+	case "", "reject":
+		c.CongestionPolicy = CongestionReject
+	case "block":
+		c.CongestionPolicy = CongestionBlock
+	default:
+		return fmt.Errorf("expected reject or block, received %q", v)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package sse
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FanInSource is a source of values for a FanIn.
+type FanInSource struct {
+	// The values to publish. The source is done when the channel is closed.
+	Values <-chan any
+	// The function which converts the source's values to messages.
+	Marshal func(any) (*Message, error)
+	// The name of the source, which identifies its messages. See FanIn.Tag.
+	Name string
+}
+
+// FanIn publishes the values of multiple sources to a single topic, for example to build
+// a dashboard which aggregates the events of many backends. Each source is read in its own goroutine
+// and its errors are isolated: a value which fails to be marshalled is reported and skipped,
+// without affecting the other sources.
+type FanIn struct {
+	// Tag marks each message with the source it came from. The message must not be modified;
+	// clone it instead. Defaults to TagSourceType.
+	Tag func(source string, m *Message) *Message
+	// OnError is called when a source's value fails to be marshalled. It is called concurrently
+	// from the goroutines of the sources. If it is nil, errors are ignored.
+	OnError func(source string, err error)
+	// The sources whose values are published.
+	Sources []FanInSource
+}
+
+// TagSourceType prefixes the message's type with the source's name and a dot. Messages without a type
+// get the source's name as their type, so clients can subscribe to all the events of a source
+// or to a specific event type from a source.
+func TagSourceType(source string, m *Message) *Message {
+	m = m.Clone()
+	if m.Type.IsSet() {
+		m.Type = Type(source + "." + m.Type.String())
+	} else {
+		m.Type = Type(source)
+	}
+
+	return m
+}
+
+// Run publishes the values of all the sources to the given topic using the given server, until
+// all the sources are done, the context is done or publishing fails. It returns nil if all the
+// sources are done, the context's error if it is done, or the first publishing error.
+func (f *FanIn) Run(ctx context.Context, s *Server, topic string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for _, src := range f.Sources {
+		wg.Add(1)
+
+		go func(src FanInSource) {
+			defer wg.Done()
+
+			if err := f.run(ctx, s, topic, src); err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(src)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return ctx.Err()
+}
+
+func (f *FanIn) run(ctx context.Context, s *Server, topic string, src FanInSource) error {
+	for {
+		select {
+		case v, ok := <-src.Values:
+			if !ok {
+				return nil
+			}
+
+			m, err := marshalFanIn(src.Marshal, v)
+			if err != nil {
+				if f.OnError != nil {
+					f.OnError(src.Name, err)
+				}
+				continue
+			}
+
+			if err := s.Publish(f.tag(src.Name, m), topic); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (f *FanIn) tag(source string, m *Message) *Message {
+	if f.Tag != nil {
+		return f.Tag(source, m)
+	}
+
+	return TagSourceType(source, m)
+}
+
+func marshalFanIn(marshal func(any) (*Message, error), v any) (m *Message, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("go-sse.server: marshal panicked: %v", r)
+		}
+	}()
+
+	return marshal(v)
+}
@@ -0,0 +1,69 @@
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrServerDraining is returned by Server.Healthy after the server started shutting down.
+var ErrServerDraining = errors.New("go-sse.server: server is draining")
+
+// HealthChecker is implemented by the providers which can report their health – for example,
+// whether they are connected to the broker or database they use. Joe reports whether it is closed.
+type HealthChecker interface {
+	// Healthy returns nil if the provider can publish and subscribe, or the reason why it can't otherwise.
+	Healthy() error
+}
+
+// Healthy returns nil if the server can accept new sessions. Otherwise, it returns ErrServerDraining
+// if the server is shutting down, or the error reported by the provider, if it is a HealthChecker.
+func (s *Server) Healthy() error {
+	s.init()
+
+	if s.draining.Load() {
+		return ErrServerDraining
+	}
+
+	if hc, ok := s.provider.(HealthChecker); ok {
+		if err := hc.Healthy(); err != nil {
+			return fmt.Errorf("go-sse.server: provider is unhealthy: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// HealthHandler returns a handler which reports the server's health, for use as a readiness probe.
+// It responds with 200 OK if the server is healthy and with 503 Service Unavailable otherwise,
+// so draining servers stop receiving new sessions. The response body is a JSON object:
+//
+//	{"healthy":false,"draining":false,"error":"go-sse.server: provider is unhealthy: ..."}
+//
+// Liveness probes shouldn't use it, as a server whose provider lost its connection
+// would be restarted instead of waiting for the connection to be restored.
+func (s *Server) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		var status struct {
+			Healthy  bool   `json:"healthy"`
+			Draining bool   `json:"draining"`
+			Error    string `json:"error,omitempty"`
+		}
+
+		err := s.Healthy()
+		status.Healthy = err == nil
+		status.Draining = errors.Is(err, ErrServerDraining)
+		if err != nil {
+			status.Error = err.Error()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		if err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
@@ -0,0 +1,117 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HeartbeatEventType is the type of the messages sent periodically to each session if the Server's
+// HeartbeatInterval is set. The message's data is a JSON object with the server's time, which clients
+// use to estimate the transit latency of the stream:
+//
+//	{"time":"2026-10-15T10:00:00.123456789Z"}
+//
+// The Server's EchoHandler responds with the same object, so clients can also estimate the offset
+// between their clock and the server's – see Client.EchoURL.
+const HeartbeatEventType = "heartbeat"
+
+type heartbeat struct {
+	Time time.Time `json:"time"`
+}
+
+func heartbeatMessage(now time.Time) *Message {
+	data, _ := json.Marshal(heartbeat{Time: now})
+
+	m := &Message{Type: Type(HeartbeatEventType)}
+	m.AppendData(string(data))
+
+	return m
+}
+
+// EchoHandler returns the handler of the endpoint clients use to estimate the offset between
+// their clock and the server's, by measuring a round trip. It responds to any request with
+// a JSON object with the server's time, the same as the data of the heartbeat messages.
+// See HeartbeatEventType. If the Server tracks Presence, the requests also touch the presence
+// of the users they identify – see Presence.Identity.
+func (s *Server) EchoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p := s.Presence; p != nil && p.Identity != nil {
+			if id := p.Identity(r); id != "" {
+				p.Touch(id)
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(heartbeat{Time: time.Now()})
+	})
+}
+
+// lockedWriter serializes the writes of the provider, of the heartbeats and of the keep-alive comments.
+type lockedWriter struct {
+	MessageWriter
+	// When a message was last sent.
+	sent time.Time
+	mu   sync.Mutex
+}
+
+func (l *lockedWriter) Send(m *Message) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sent = time.Now()
+
+	return l.MessageWriter.Send(m)
+}
+
+func (l *lockedWriter) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.MessageWriter.Flush()
+}
+
+// startHeartbeats sends heartbeats to the writer until the context is done or the returned
+// function is called, which waits for the heartbeats to stop. The function can be called multiple times.
+func (s *Server) startHeartbeats(ctx context.Context, w *lockedWriter) (stop func()) {
+	if s.HeartbeatInterval <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTicker(s.HeartbeatInterval)
+		defer t.Stop()
+
+		for {
+			select {
+			case now := <-t.C:
+				w.mu.Lock()
+				w.sent = now
+				err := w.MessageWriter.Send(heartbeatMessage(now))
+				if err == nil {
+					err = w.MessageWriter.Flush()
+				}
+				w.mu.Unlock()
+
+				if err != nil {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultKeepAliveComment is the text of the keep-alive comments of a Server which doesn't set one.
+const DefaultKeepAliveComment = "ping"
+
+func (s *Server) keepAliveMessage() *Message {
+	comment := s.KeepAliveComment
+	if comment == "" {
+		comment = DefaultKeepAliveComment
+	}
+
+	m := &Message{}
+	m.AppendComment(comment)
+
+	return m
+}
+
+// startKeepAlive writes a comment to the writer each time nothing was sent to it for the
+// Server's KeepAlive, until the context is done or the returned function is called,
+// which waits for the comments to stop. The function can be called multiple times.
+func (s *Server) startKeepAlive(ctx context.Context, w *lockedWriter) (stop func()) {
+	if s.KeepAlive <= 0 {
+		return func() {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTimer(s.KeepAlive)
+		defer t.Stop()
+
+		for {
+			select {
+			case now := <-t.C:
+				w.mu.Lock()
+				idle := now.Sub(w.sent)
+				var err error
+				if idle >= s.KeepAlive {
+					idle = 0
+					w.sent = now
+					// A new message is sent each time, so it isn't taken for a duplicate of the previous one.
+					err = w.MessageWriter.Send(s.keepAliveMessage())
+					if err == nil {
+						err = w.MessageWriter.Flush()
+					}
+				}
+				w.mu.Unlock()
+
+				if err != nil {
+					return
+				}
+
+				t.Reset(s.KeepAlive - idle)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
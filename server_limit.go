@@ -0,0 +1,105 @@
+package sse
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// SessionLimitStats describes how often the Server's SessionLimit was exceeded.
+type SessionLimitStats struct {
+	// The number of sessions ended because the limit was exceeded.
+	Rejected uint64
+	// The highest number of concurrent sessions attempted for one key,
+	// including the rejected session.
+	MaxAttempted int
+}
+
+// SessionLimitStats returns statistics about the sessions rejected because of the SessionLimit.
+func (s *Server) SessionLimitStats() SessionLimitStats {
+	return s.limiter.stats()
+}
+
+func (s *Server) sessionKey(r *http.Request) string {
+	if s.SessionKey != nil {
+		return s.SessionKey(r)
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return r.Header.Get("Origin") + " " + host
+}
+
+type sessionLimiter struct {
+	sessions map[string]int
+	st       SessionLimitStats
+	mu       sync.Mutex
+}
+
+func (l *sessionLimiter) acquire(key string, limit int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.sessions == nil {
+		l.sessions = map[string]int{}
+	}
+
+	n := l.sessions[key] + 1
+	if n > l.st.MaxAttempted {
+		l.st.MaxAttempted = n
+	}
+	if n > limit {
+		l.st.Rejected++
+		return false
+	}
+
+	l.sessions[key] = n
+
+	return true
+}
+
+func (l *sessionLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.sessions[key]--; l.sessions[key] <= 0 {
+		delete(l.sessions, key)
+	}
+}
+
+func (l *sessionLimiter) stats() SessionLimitStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.st
+}
+
+type sessionLimitResponse struct {
+	Error   string `json:"error"`
+	Message string `json:"message"`
+	Limit   int    `json:"limit"`
+}
+
+func sessionLimitError(limit int) sessionLimitResponse {
+	return sessionLimitResponse{
+		Error: "session_limit_exceeded",
+		Message: "Too many concurrent event streams were opened for this origin (the limit is " + strconv.Itoa(limit) + "). " +
+			"Multiplex the streams over a single connection, for example using topics or logical streams.",
+		Limit: limit,
+	}
+}
+
+// rejectSession responds with the given status and the error encoded as JSON, instead of upgrading
+// the request to an event stream.
+func rejectSession(w http.ResponseWriter, status int, err any) {
+	data, _ := json.Marshal(err)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,97 @@
+package sse
+
+import "sync/atomic"
+
+// TopicOverlapStats describes the subscriptions which would have received the same messages multiple times.
+type TopicOverlapStats struct {
+	// The number of sessions subscribed to the same topic multiple times, directly, through
+	// TopicAliases or through patterns which match other topics they are subscribed to. Their
+	// duplicate topics, and the ones matched by their patterns, are removed before they are subscribed.
+	Sessions uint64
+	// The number of messages which weren't sent because they were already sent to the session.
+	// See Server.DeduplicateMessages.
+	DroppedMessages uint64
+}
+
+// TopicOverlapStats returns statistics about the overlapping subscriptions.
+func (s *Server) TopicOverlapStats() TopicOverlapStats {
+	return TopicOverlapStats{
+		Sessions:        s.overlaps.sessions.Load(),
+		DroppedMessages: s.overlaps.dropped.Load(),
+	}
+}
+
+type topicOverlaps struct {
+	sessions atomic.Uint64
+	dropped  atomic.Uint64
+}
+
+// dedupeTopics returns the topics without duplicates and without the topics matched by the patterns
+// among them, and whether there were any. Of the patterns which match each other, such as "a.*"
+// and "a.**", the first one is kept.
+func dedupeTopics(topics []string) ([]string, bool) {
+	seen := make(map[string]struct{}, len(topics))
+	unique := topics[:0:0]
+
+	for _, t := range topics {
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		unique = append(unique, t)
+	}
+
+	deduped := unique[:0:0]
+	for i, t := range unique {
+		if !coveredTopic(unique, i) {
+			deduped = append(deduped, t)
+		}
+	}
+
+	return deduped, len(deduped) != len(topics)
+}
+
+// coveredTopic reports whether the topic at the given index is matched by another pattern among
+// the topics. Patterns are matched as the topics they are, so "a.*" matches "a.b.*" too, as it
+// matches all the topics "a.b.*" does.
+func coveredTopic(topics []string, i int) bool {
+	t := topics[i]
+	for j, p := range topics {
+		if j == i || !isPattern(p) || !matchPattern(p, t) {
+			continue
+		}
+		// The patterns which match each other are covered by the first of them.
+		if j > i && matchPattern(t, p) {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// dedupeWindow is the number of recently sent messages a dedupeWriter remembers.
+const dedupeWindow = 64
+
+// dedupeWriter drops the messages which were recently sent, recognized by their address.
+type dedupeWriter struct {
+	MessageWriter
+	dropped *atomic.Uint64
+	recent  [dedupeWindow]*Message
+	next    int
+}
+
+func (w *dedupeWriter) Send(m *Message) error {
+	for _, r := range w.recent {
+		if r == m {
+			w.dropped.Add(1)
+			return nil
+		}
+	}
+
+	w.recent[w.next] = m
+	w.next = (w.next + 1) % dedupeWindow
+
+	return w.MessageWriter.Send(m)
+}
@@ -0,0 +1,234 @@
+package sse
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultPresenceHalfLife is the HalfLife used by a Presence which doesn't set one.
+const DefaultPresenceHalfLife = 30 * time.Second
+
+// forgetHalfLives is the number of half-lives after which an offline user with no watchers
+// is forgotten, when its score is under 0.1%.
+const forgetHalfLives = 10
+
+// Presence tracks which users are online, using the sessions of a Server. Users are identified by the
+// Identity of their sessions' subscriptions. Set it as the Server's Presence to use it.
+//
+// A user is online while it has open sessions. After its last session is closed, the user's presence
+// score decays exponentially from 1, halving every HalfLife, and the user is considered offline once
+// the score drops under 0.5 – that is, one HalfLife after it was last seen. This way, users which
+// reconnect quickly, for example when reloading a page, don't appear to go offline. A user is seen
+// when its sessions are opened or closed and when Touch is called for it – for example, when the
+// heartbeats are acknowledged using the Server's EchoHandler (see the Identity field).
+//
+// The zero value is ready to use. It is safe for concurrent use.
+type Presence struct {
+	// How long it takes for the score of a user without sessions to halve.
+	// Defaults to DefaultPresenceHalfLife.
+	HalfLife time.Duration
+	// Identity returns the identity of the user which made a request to the Server's EchoHandler,
+	// or an empty string if it is unknown. If it is set, each request touches the user's presence.
+	Identity func(*http.Request) string
+
+	mu    sync.Mutex
+	users map[string]*presenceUser
+}
+
+// A PresenceChange is received from the channels returned by Presence.Watch.
+type PresenceChange struct {
+	// When the user was last seen.
+	LastSeen time.Time
+	// The user whose presence changed.
+	UserID string
+	// Whether the user is online.
+	Online bool
+}
+
+type presenceUser struct {
+	id       string
+	lastSeen time.Time
+	timer    *time.Timer
+	watchers map[chan PresenceChange]struct{}
+	sessions int
+	online   bool
+}
+
+// Online reports whether the given user is online.
+func (p *Presence) Online(userID string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.users[userID]
+	return u != nil && u.online
+}
+
+// LastSeen returns when the given user was last seen. The returned boolean is false if the user
+// was never seen or if it was forgotten, after being offline for a long time.
+func (p *Presence) LastSeen(userID string) (time.Time, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.users[userID]
+	if u == nil || u.lastSeen.IsZero() {
+		return time.Time{}, false
+	}
+
+	return u.lastSeen, true
+}
+
+// Score returns the presence score of the given user: 1 while the user has open sessions, decaying
+// exponentially towards 0 afterwards. Use it, for example, to rank the users by how recently they were active.
+func (p *Presence) Score(userID string) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.users[userID]
+	if u == nil || u.lastSeen.IsZero() {
+		return 0
+	}
+	if u.sessions > 0 {
+		return 1
+	}
+
+	return math.Exp2(-float64(time.Since(u.lastSeen)) / float64(p.halfLife()))
+}
+
+// Touch marks the given user as seen now, which makes it online for at least a HalfLife.
+func (p *Presence) Touch(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.see(p.user(userID))
+}
+
+// Watch returns a channel which receives the changes of the given user's presence, starting with
+// its current presence. If the receiver can't keep up, only the latest change is kept.
+// The channel is closed when the given context is done.
+func (p *Presence) Watch(ctx context.Context, userID string) <-chan PresenceChange {
+	ch := make(chan PresenceChange, 1)
+
+	p.mu.Lock()
+	u := p.user(userID)
+	u.watchers[ch] = struct{}{}
+	ch <- PresenceChange{UserID: userID, Online: u.online, LastSeen: u.lastSeen}
+	p.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		delete(u.watchers, ch)
+		close(ch)
+		p.forgetIfUnused(u)
+	}()
+
+	return ch
+}
+
+func (p *Presence) join(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.user(userID)
+	u.sessions++
+	p.see(u)
+}
+
+func (p *Presence) leave(userID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	u := p.user(userID)
+	u.sessions--
+	p.see(u)
+}
+
+func (p *Presence) halfLife() time.Duration {
+	if p.HalfLife > 0 {
+		return p.HalfLife
+	}
+	return DefaultPresenceHalfLife
+}
+
+// user returns the state of the given user, creating it if needed. The Presence must be locked.
+func (p *Presence) user(userID string) *presenceUser {
+	if p.users == nil {
+		p.users = map[string]*presenceUser{}
+	}
+
+	u := p.users[userID]
+	if u == nil {
+		u = &presenceUser{id: userID, watchers: map[chan PresenceChange]struct{}{}}
+		u.timer = time.AfterFunc(math.MaxInt64, func() { p.decay(u) })
+		p.users[userID] = u
+	}
+
+	return u
+}
+
+// see marks the user as seen now and schedules it going offline. The Presence must be locked.
+func (p *Presence) see(u *presenceUser) {
+	u.lastSeen = time.Now()
+	u.timer.Stop()
+
+	if u.sessions <= 0 {
+		u.sessions = 0
+		u.timer.Reset(p.halfLife())
+	}
+
+	p.setOnline(u, true)
+}
+
+// decay is called when a user wasn't seen for a HalfLife, and afterwards when it can be forgotten.
+func (p *Presence) decay(u *presenceUser) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// The user may have been seen while the timer fired.
+	if p.users[u.id] != u || u.sessions > 0 || time.Since(u.lastSeen) < p.halfLife() {
+		return
+	}
+
+	if u.online {
+		p.setOnline(u, false)
+		u.timer.Reset((forgetHalfLives - 1) * p.halfLife())
+		return
+	}
+
+	p.forgetIfUnused(u)
+}
+
+// forgetIfUnused removes the offline users whose score is negligible and which are not watched.
+// The Presence must be locked.
+func (p *Presence) forgetIfUnused(u *presenceUser) {
+	if p.users[u.id] != u || u.online || len(u.watchers) > 0 || time.Since(u.lastSeen) < forgetHalfLives*p.halfLife() {
+		return
+	}
+
+	u.timer.Stop()
+	delete(p.users, u.id)
+}
+
+// setOnline updates the user's presence, notifying its watchers if it changed. The Presence must be locked.
+func (p *Presence) setOnline(u *presenceUser, online bool) {
+	if u.online == online {
+		return
+	}
+	u.online = online
+
+	change := PresenceChange{UserID: u.id, Online: online, LastSeen: u.lastSeen}
+	for ch := range u.watchers {
+		// Keep only the latest change for slow receivers.
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- change
+	}
+}
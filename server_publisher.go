@@ -0,0 +1,121 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+type publisherKey struct{}
+
+// ContextWithPublisher returns a context which carries the identity of a publisher, for example
+// the name of the team or service publishing messages. Servers use it to fill the Origin of the
+// messages published using PublishContext, which attributes them to the publisher.
+func ContextWithPublisher(ctx context.Context, publisher string) context.Context {
+	return context.WithValue(ctx, publisherKey{}, publisher)
+}
+
+// PublisherFromContext returns the publisher identity carried by the context, if any.
+func PublisherFromContext(ctx context.Context) string {
+	p, _ := ctx.Value(publisherKey{}).(string)
+	return p
+}
+
+// ErrPublishQuotaExceeded is wrapped by the errors returned when a publisher exceeds the Server's PublishQuota.
+var ErrPublishQuotaExceeded = errors.New("go-sse.server: publish quota exceeded")
+
+// A PublishQuotaError is returned by Publish when the message's publisher exceeded its PublishQuota.
+// It wraps ErrPublishQuotaExceeded.
+type PublishQuotaError struct {
+	// The publisher which exceeded its quota, which is the message's Origin.
+	Publisher string
+	// The publisher's quota.
+	Quota int64
+	// The time until the publisher can publish again, or 0 if its quota is never reset.
+	ResetsIn time.Duration
+}
+
+func (e *PublishQuotaError) Error() string {
+	return fmt.Sprintf("%v: publisher %q (quota %d)", ErrPublishQuotaExceeded, e.Publisher, e.Quota)
+}
+
+func (e *PublishQuotaError) Unwrap() error {
+	return ErrPublishQuotaExceeded
+}
+
+// PublisherStats describes the messages published by a publisher.
+type PublisherStats struct {
+	// The number of messages published.
+	Published uint64
+	// The number of messages rejected because the quota was exceeded.
+	Rejected uint64
+	// The number of messages published in the current PublishQuotaPeriod.
+	Usage int64
+}
+
+// PublisherStats returns statistics about the messages published by each publisher, identified by the
+// messages' Origin. It is always empty if the Server has no PublishQuota.
+func (s *Server) PublisherStats() map[string]PublisherStats {
+	s.publishers.mu.Lock()
+	defer s.publishers.mu.Unlock()
+
+	stats := make(map[string]PublisherStats, len(s.publishers.stats))
+	for p, st := range s.publishers.stats {
+		stats[p] = st
+	}
+
+	for p, st := range stats {
+		st.Usage = s.publishers.quotas.usage(p, s.PublishQuotaPeriod, time.Now())
+		stats[p] = st
+	}
+
+	return stats
+}
+
+type publisherTracker struct {
+	quotas quotaTracker
+	stats  map[string]PublisherStats
+	mu     sync.Mutex
+}
+
+// chargePublisher counts a message towards its publisher's quota, returning a *PublishQuotaError
+// if the quota is exceeded.
+func (s *Server) chargePublisher(m *Message) error {
+	if s.PublishQuota == nil {
+		return nil
+	}
+
+	now := time.Now()
+	quota := s.PublishQuota(m.Origin)
+	if quota <= 0 {
+		// Unlimited, but the usage is still tracked.
+		quota = math.MaxInt64
+	}
+	allowed := s.publishers.quotas.spend(m.Origin, 1, quota, s.PublishQuotaPeriod, now)
+
+	s.publishers.mu.Lock()
+	if s.publishers.stats == nil {
+		s.publishers.stats = map[string]PublisherStats{}
+	}
+	st := s.publishers.stats[m.Origin]
+	if allowed {
+		st.Published++
+	} else {
+		st.Rejected++
+	}
+	s.publishers.stats[m.Origin] = st
+	s.publishers.mu.Unlock()
+
+	if allowed {
+		return nil
+	}
+
+	return &PublishQuotaError{
+		Publisher: m.Origin,
+		Quota:     quota,
+		ResetsIn:  s.publishers.quotas.resetsIn(m.Origin, s.PublishQuotaPeriod, now),
+	}
+}
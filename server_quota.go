@@ -0,0 +1,183 @@
+package sse
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QuotaExceededEventType is the type of the message sent to the sessions which exceed the Server's Quota.
+const QuotaExceededEventType = "quota-exceeded"
+
+// ErrQuotaExceeded is returned by the MessageWriters of the sessions which exceed the Server's Quota.
+var ErrQuotaExceeded = errors.New("go-sse.server: quota exceeded")
+
+// QuotaUsage returns the number of bytes sent in the current quota period to the sessions with the given
+// QuotaKey. It is always 0 if the Server has no Quota.
+func (s *Server) QuotaUsage(key string) int64 {
+	return s.quotas.usage(key, s.QuotaPeriod, time.Now())
+}
+
+func (s *Server) quotaKey(r *http.Request) string {
+	if s.QuotaKey != nil {
+		return s.QuotaKey(r)
+	}
+
+	return s.sessionKey(r)
+}
+
+type quotaUsage struct {
+	since time.Time
+	used  int64
+}
+
+type quotaTracker struct {
+	lastPrune time.Time
+	usages    map[string]*quotaUsage
+	mu        sync.Mutex
+}
+
+// get returns the usage of the given key, resetting it if its period ended.
+// The tracker must be locked.
+func (q *quotaTracker) get(key string, period time.Duration, now time.Time) *quotaUsage {
+	if q.usages == nil {
+		q.usages = map[string]*quotaUsage{}
+	}
+
+	if period > 0 && now.Sub(q.lastPrune) >= period {
+		// Remove the keys whose period ended, so keys which aren't used anymore don't leak.
+		for k, u := range q.usages {
+			if now.Sub(u.since) >= period {
+				delete(q.usages, k)
+			}
+		}
+		q.lastPrune = now
+	}
+
+	u := q.usages[key]
+	if u == nil {
+		u = &quotaUsage{since: now}
+		q.usages[key] = u
+	} else if period > 0 && now.Sub(u.since) >= period {
+		u.used = 0
+		u.since = now
+	}
+
+	return u
+}
+
+func (q *quotaTracker) usage(key string, period time.Duration, now time.Time) int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.usages[key] == nil {
+		return 0
+	}
+
+	return q.get(key, period, now).used
+}
+
+// spend adds n bytes to the key's usage. It returns false and leaves
+// the usage unchanged if the quota would be exceeded.
+func (q *quotaTracker) spend(key string, n, quota int64, period time.Duration, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	u := q.get(key, period, now)
+	if u.used+n > quota {
+		return false
+	}
+
+	u.used += n
+
+	return true
+}
+
+// resetsIn returns the time until the key's usage is reset, or 0 if it is never reset.
+func (q *quotaTracker) resetsIn(key string, period time.Duration, now time.Time) time.Duration {
+	if period <= 0 {
+		return 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.get(key, period, now).since.Add(period).Sub(now)
+}
+
+type quotaWriter struct {
+	MessageWriter
+	s   *Server
+	key string
+}
+
+func (w quotaWriter) Send(m *Message) error {
+	n, _ := m.WriteTo(io.Discard)
+	if w.s.quotas.spend(w.key, n, w.s.Quota, w.s.QuotaPeriod, time.Now()) {
+		return w.MessageWriter.Send(m)
+	}
+
+	if err := w.MessageWriter.Send(w.s.quotaExceededMessage(w.key)); err != nil {
+		return err
+	}
+	if err := w.MessageWriter.Flush(); err != nil {
+		return err
+	}
+
+	return ErrQuotaExceeded
+}
+
+type quotaExceededError struct {
+	Error    string `json:"error"`
+	Message  string `json:"message"`
+	Quota    int64  `json:"quota"`
+	ResetsIn int64  `json:"resetsIn,omitempty"`
+}
+
+// minQuotaRetry is the retry value of the quota exceeded messages for the quotas which are reset
+// sooner or never. Clients must not reconnect immediately, and if the quota is never reset,
+// they are told to stop once they reconnect.
+const minQuotaRetry = time.Second
+
+func (s *Server) quotaExceededError(resetsIn time.Duration) quotaExceededError {
+	return quotaExceededError{
+		Error:    "quota_exceeded",
+		Message:  "The data quota for this stream was exceeded.",
+		Quota:    s.Quota,
+		ResetsIn: int64(resetsIn / time.Second),
+	}
+}
+
+func (s *Server) quotaExceededMessage(key string) *Message {
+	resetsIn := s.quotas.resetsIn(key, s.QuotaPeriod, time.Now())
+	data, _ := json.Marshal(s.quotaExceededError(resetsIn))
+
+	// Clients reconnect only after the quota is reset.
+	retry := resetsIn
+	if retry < minQuotaRetry {
+		retry = minQuotaRetry
+	}
+
+	m := &Message{Type: Type(QuotaExceededEventType), Retry: retry}
+	m.AppendData(string(data))
+
+	return m
+}
+
+// rejectOverQuota rejects a new session for a key which exceeded its quota. If the quota is never
+// reset, the session is rejected with 204 No Content, so clients stop reconnecting.
+func (s *Server) rejectOverQuota(w http.ResponseWriter, key string) {
+	resetsIn := s.quotas.resetsIn(key, s.QuotaPeriod, time.Now())
+	if resetsIn <= 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Retry-After is in whole seconds, so clients don't reconnect before the reset.
+	w.Header().Set("Retry-After", strconv.FormatInt(int64((resetsIn+time.Second-1)/time.Second), 10))
+	rejectSession(w, http.StatusTooManyRequests, s.quotaExceededError(resetsIn))
+}
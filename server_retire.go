@@ -0,0 +1,113 @@
+package sse
+
+import (
+	"context"
+	"sync"
+)
+
+// RetireTopic retires the given topic, so its clients stop reconnecting. The sessions whose topics
+// are all retired are ended, and new sessions for them are rejected with 204 No Content, which tells
+// clients to stop reconnecting – see Session.EndStream. The sessions also subscribed to other topics
+// are left as they are, so they keep receiving the messages published to the retired topic until
+// they reconnect; retired topics are removed from the topics of the new sessions. It returns the
+// number of sessions which were ended.
+func (s *Server) RetireTopic(topic string) int {
+	return s.retired.retire(topic)
+}
+
+// RetiredTopics returns the topics retired using RetireTopic.
+func (s *Server) RetiredTopics() []string {
+	return s.retired.topics()
+}
+
+type topicRetirement struct {
+	retired  map[string]struct{}
+	sessions map[*retirableSession]struct{}
+	mu       sync.Mutex
+}
+
+type retirableSession struct {
+	cancel context.CancelFunc
+	topics []string
+}
+
+func (t *topicRetirement) retire(topic string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.retired == nil {
+		t.retired = map[string]struct{}{}
+	}
+	t.retired[topic] = struct{}{}
+
+	ended := 0
+	for sess := range t.sessions {
+		if t.allRetired(sess.topics) {
+			sess.cancel()
+			delete(t.sessions, sess)
+			ended++
+		}
+	}
+
+	return ended
+}
+
+func (t *topicRetirement) topics() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	topics := make([]string, 0, len(t.retired))
+	for topic := range t.retired {
+		topics = append(topics, topic)
+	}
+
+	return topics
+}
+
+// register returns the topics which aren't retired and a context which is done when they are retired.
+// If all the topics are retired, ok is false and the session must be ended.
+func (t *topicRetirement) register(ctx context.Context, topics []string) (_ context.Context, active []string, unregister func(), ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	topics = getTopics(topics)
+	if len(t.retired) == 0 {
+		active = topics
+	} else {
+		for _, topic := range topics {
+			if _, retired := t.retired[topic]; !retired {
+				active = append(active, topic)
+			}
+		}
+	}
+
+	if len(active) == 0 {
+		return ctx, nil, func() {}, false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	sess := &retirableSession{cancel: cancel, topics: active}
+
+	if t.sessions == nil {
+		t.sessions = map[*retirableSession]struct{}{}
+	}
+	t.sessions[sess] = struct{}{}
+
+	return ctx, active, func() {
+		t.mu.Lock()
+		delete(t.sessions, sess)
+		t.mu.Unlock()
+
+		cancel()
+	}, true
+}
+
+func (t *topicRetirement) allRetired(topics []string) bool {
+	for _, topic := range topics {
+		if _, ok := t.retired[topic]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
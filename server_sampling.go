@@ -0,0 +1,182 @@
+package sse
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// TopicSampling configures which of the messages published to a topic are delivered.
+// If both a rate and a probability are set, messages must pass both to be delivered.
+type TopicSampling struct {
+	// Key returns the sampling key of a message, for example the ID of the entity it is about.
+	// With a Probability, the messages with the same key are either all delivered or all sampled out.
+	// With a Rate, each key is limited separately. If it is nil, all messages have the same key.
+	Key func(*Message) string
+	// The probability a message is delivered, between 0 and 1. If it is 0, it is not used.
+	Probability float64
+	// The maximum number of messages delivered in a Period for each key. If it is 0, it is not used.
+	Rate int64
+	// The period of the Rate. If it is 0, the Rate is the number of messages delivered ever.
+	Period time.Duration
+}
+
+// SamplingStats describes the messages published to a topic of a SamplingProvider.
+type SamplingStats struct {
+	// The number of messages delivered.
+	Delivered uint64
+	// The number of messages sampled out.
+	SampledOut uint64
+}
+
+// SamplingProvider is a Provider which samples the messages published to high-volume topics before
+// they are delivered, so debug-grade firehose topics can be exposed to dashboards affordably.
+// The messages are published using the wrapped Provider to the topics which didn't sample them out.
+// Messages which are sampled out of all their topics are dropped without an error.
+//
+// Use it as the Server's provider:
+//
+//	s := &sse.Server{
+//		Provider: &sse.SamplingProvider{
+//			Provider: &sse.Joe{},
+//			Topics:   map[string]sse.TopicSampling{"debug": {Probability: 0.01}},
+//		},
+//	}
+type SamplingProvider struct {
+	// The provider which delivers the messages. It is required.
+	Provider Provider
+	// The sampling configuration of each topic. The topics which aren't in the map are not sampled.
+	// The map must not be modified after the provider is used.
+	Topics map[string]TopicSampling
+
+	stats map[string]*SamplingStats
+	rates map[string]*quotaTracker
+	mu    sync.Mutex
+}
+
+// Subscribe subscribes to the wrapped provider.
+func (s *SamplingProvider) Subscribe(ctx context.Context, sub Subscription) error {
+	return s.Provider.Subscribe(ctx, sub)
+}
+
+// Publish publishes the message to the topics which didn't sample it out.
+func (s *SamplingProvider) Publish(msg *Message, topics []string) error {
+	if len(topics) == 0 {
+		return ErrNoTopic
+	}
+
+	sampled := make([]string, 0, len(topics))
+	for _, t := range topics {
+		if s.sample(msg, t, time.Now()) {
+			sampled = append(sampled, t)
+		}
+	}
+
+	if len(sampled) == 0 {
+		return nil
+	}
+
+	return s.Provider.Publish(msg, sampled)
+}
+
+// Shutdown shuts the wrapped provider down.
+func (s *SamplingProvider) Shutdown(ctx context.Context) error {
+	return s.Provider.Shutdown(ctx)
+}
+
+// SetReplayProvider replaces the wrapped provider's replay provider, if it supports it.
+// See Server.SetReplayProvider.
+func (s *SamplingProvider) SetReplayProvider(replay ReplayProvider, overlap time.Duration) error {
+	p, ok := s.Provider.(interface {
+		SetReplayProvider(ReplayProvider, time.Duration) error
+	})
+	if !ok {
+		return ErrReplaySwapUnsupported
+	}
+
+	return p.SetReplayProvider(replay, overlap)
+}
+
+// Healthy returns the wrapped provider's health, if it is a HealthChecker, and nil otherwise.
+func (s *SamplingProvider) Healthy() error {
+	if hc, ok := s.Provider.(HealthChecker); ok {
+		return hc.Healthy()
+	}
+
+	return nil
+}
+
+// Stats returns statistics about the messages published to the given topic.
+// Only the sampled topics have statistics.
+func (s *SamplingProvider) Stats(topic string) SamplingStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st := s.stats[topic]; st != nil {
+		return *st
+	}
+
+	return SamplingStats{}
+}
+
+// sample reports whether the message is delivered to the topic.
+func (s *SamplingProvider) sample(msg *Message, topic string, now time.Time) bool {
+	cfg, ok := s.Topics[topic]
+	if !ok {
+		return true
+	}
+
+	var key string
+	if cfg.Key != nil {
+		key = cfg.Key(msg)
+	}
+
+	keep := true
+	if cfg.Probability > 0 {
+		keep = sampleProbability(cfg, key) < cfg.Probability
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keep && cfg.Rate > 0 {
+		if s.rates == nil {
+			s.rates = map[string]*quotaTracker{}
+		}
+		if s.rates[topic] == nil {
+			s.rates[topic] = &quotaTracker{}
+		}
+
+		keep = s.rates[topic].spend(key, 1, cfg.Rate, cfg.Period, now)
+	}
+
+	if s.stats == nil {
+		s.stats = map[string]*SamplingStats{}
+	}
+	if s.stats[topic] == nil {
+		s.stats[topic] = &SamplingStats{}
+	}
+
+	if keep {
+		s.stats[topic].Delivered++
+	} else {
+		s.stats[topic].SampledOut++
+	}
+
+	return keep
+}
+
+// sampleProbability returns a number in [0, 1) which is random if there is no key,
+// and derived from the key's hash otherwise, so messages with the same key are sampled alike.
+func sampleProbability(cfg TopicSampling, key string) float64 {
+	if cfg.Key == nil {
+		return rand.Float64() //nolint:gosec // Sampling doesn't need a secure generator.
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
@@ -0,0 +1,12 @@
+package sse
+
+import "errors"
+
+// ErrSlowSession is returned by the MessageWriters of the sessions which couldn't receive
+// a message within the Server's WriteTimeout.
+var ErrSlowSession = errors.New("go-sse.server: session too slow")
+
+// SlowSessions returns the number of sessions closed because of the WriteTimeout.
+func (s *Server) SlowSessions() uint64 {
+	return s.slow.Load()
+}
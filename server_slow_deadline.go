@@ -0,0 +1,50 @@
+//go:build go1.20
+
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"time"
+)
+
+// withWriteTimeout makes each write to the session fail with ErrSlowSession if it doesn't complete
+// within the timeout, using the write deadline of the response's connection.
+func withWriteTimeout(w MessageWriter, res http.ResponseWriter, timeout time.Duration) MessageWriter {
+	return deadlineWriter{MessageWriter: w, rc: http.NewResponseController(res), timeout: timeout}
+}
+
+type deadlineWriter struct {
+	MessageWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func (d deadlineWriter) Send(m *Message) error {
+	return d.write(func() error { return d.MessageWriter.Send(m) })
+}
+
+func (d deadlineWriter) Flush() error {
+	return d.write(d.MessageWriter.Flush)
+}
+
+func (d deadlineWriter) write(fn func() error) error {
+	if err := d.rc.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return ErrSlowSession
+		}
+		return err
+	}
+
+	// Clear the deadline, so the writes done directly to the session don't fail.
+	if err := d.rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+
+	return nil
+}
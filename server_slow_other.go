@@ -0,0 +1,13 @@
+//go:build !go1.20
+
+package sse
+
+import (
+	"net/http"
+	"time"
+)
+
+// withWriteTimeout does nothing before Go 1.20, which is required to set write deadlines.
+func withWriteTimeout(w MessageWriter, _ http.ResponseWriter, _ time.Duration) MessageWriter {
+	return w
+}
@@ -1,6 +1,7 @@
 package sse_test
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -10,7 +11,9 @@ import (
 	"net/http/httptest"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tmaxmax/go-sse"
@@ -109,6 +112,41 @@ func TestServer_ShutdownPublish(t *testing.T) {
 	require.True(t, p.Stopped, "Stop wasn't called")
 }
 
+func TestServer_PublishFromChannel(t *testing.T) {
+	t.Parallel()
+
+	p := &mockProvider{}
+	s := &sse.Server{Provider: p}
+
+	marshal := func(v any) (*sse.Message, error) {
+		str, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected value %v", v)
+		}
+
+		m := &sse.Message{}
+		m.AppendData(str)
+
+		return m, nil
+	}
+
+	ch := make(chan any, 1)
+	ch <- "hello"
+	close(ch)
+
+	require.NoError(t, s.PublishFromChannel(context.Background(), "topic", ch, marshal), "unexpected error on closed channel")
+	require.Equal(t, "data: hello\n\n", p.Pub.String(), "invalid message published")
+	require.Equal(t, []string{"topic"}, p.PubTopics, "invalid topics")
+
+	ch = make(chan any, 1)
+	ch <- 5
+	require.Error(t, s.PublishFromChannel(context.Background(), "topic", ch, marshal), "expected marshal error")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, s.PublishFromChannel(ctx, "topic", make(chan any), marshal), context.Canceled, "expected context error")
+}
+
 func request(tb testing.TB, method, address string, body io.Reader) (*http.Request, context.CancelFunc) { //nolint
 	tb.Helper()
 
@@ -137,6 +175,33 @@ func TestServer_ServeHTTP(t *testing.T) {
 	require.Equal(t, "level=INFO msg=\"sse: starting new session\"\nlevel=INFO msg=\"sse: subscribing session\" topics=<sse:default> lastEventID=5\nlevel=INFO msg=\"sse: session ended\"\n", sb.String(), "invalid log output")
 }
 
+func TestServer_OnSend(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	req, cancel := request(t, "", "http://localhost", nil)
+	defer cancel()
+	req.Header.Set(sse.HeaderCapabilities, "shout")
+	p := newMockProvider(t, nil)
+
+	go cancel()
+	(&sse.Server{
+		Provider: p,
+		OnSend: func(s *sse.Session, m *sse.Message) *sse.Message {
+			if !s.Capabilities.Has("shout") {
+				return m
+			}
+
+			shouted := &sse.Message{}
+			shouted.AppendData(strings.ToUpper(toEv(t, m.String()).Data))
+
+			return shouted
+		},
+	}).ServeHTTP(rec, req)
+
+	require.Equal(t, "data: HELLO\n\n", rec.Body.String(), "message not transformed")
+}
+
 type noFlusher struct {
 	http.ResponseWriter
 }
@@ -284,3 +349,1181 @@ func BenchmarkServer(b *testing.B) {
 		})
 	}
 }
+
+func TestServer_SessionLimit(t *testing.T) {
+	t.Parallel()
+
+	subscribed := make(chan struct{}, 1)
+	s := &sse.Server{
+		SessionLimit: 1,
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			subscribed <- struct{}{}
+			return sse.Subscription{Client: s, Topics: []string{sse.DefaultTopic}}, true
+		},
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	req, cancel := request(t, "", "http://localhost", nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+	<-subscribed
+
+	rec := httptest.NewRecorder()
+	limited, cancelLimited := request(t, "", "http://localhost", nil)
+	defer cancelLimited()
+	s.ServeHTTP(rec, limited)
+
+	// Browsers reconnect to the streams which end, but not after failed requests.
+	require.Equal(t, http.StatusTooManyRequests, rec.Code, "session over the limit should be rejected")
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"), "invalid content type")
+	require.Contains(t, rec.Body.String(), `"limit":1`, "invalid error")
+	require.Equal(t, sse.SessionLimitStats{Rejected: 1, MaxAttempted: 2}, s.SessionLimitStats(), "invalid stats")
+
+	other, cancelOther := request(t, "", "http://localhost", nil)
+	other.Header.Set("Origin", "http://example.com")
+	go cancelOther()
+	s.ServeHTTP(httptest.NewRecorder(), other)
+	<-subscribed
+
+	cancel()
+	<-done
+
+	again, cancelAgain := request(t, "", "http://localhost", nil)
+	go cancelAgain()
+	s.ServeHTTP(httptest.NewRecorder(), again)
+	<-subscribed
+
+	require.Equal(t, uint64(1), s.SessionLimitStats().Rejected, "sessions under the limit should not be rejected")
+}
+
+type helloProvider struct{ mockProvider }
+
+func (helloProvider) Subscribe(ctx context.Context, sub sse.Subscription) error {
+	return (&mockProvider{Closed: make(chan struct{})}).Subscribe(ctx, sub)
+}
+
+func TestServer_Quota(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		Provider:    &helloProvider{},
+		Quota:       20,
+		QuotaPeriod: time.Hour,
+		QuotaKey:    func(r *http.Request) string { return r.Header.Get("X-User") },
+	}
+
+	serve := func(user string) string {
+		rec := httptest.NewRecorder()
+		req, cancel := request(t, "", "http://localhost", nil)
+		defer cancel()
+		req.Header.Set("X-User", user)
+
+		go cancel()
+		s.ServeHTTP(rec, req)
+
+		return rec.Body.String()
+	}
+
+	require.Equal(t, "data: hello\n\n", serve("a"), "message under quota not sent")
+	require.Equal(t, int64(13), s.QuotaUsage("a"), "invalid usage")
+
+	body := serve("a")
+	require.Contains(t, body, "event: "+sse.QuotaExceededEventType+"\n", "quota exceeded event not sent")
+	require.Contains(t, body, "retry: ", "quota exceeded event should make clients wait for the reset")
+	require.Contains(t, body, `"quota":20`, "invalid quota exceeded event data")
+	require.NotContains(t, body, "hello", "message over quota was sent")
+	require.Equal(t, int64(13), s.QuotaUsage("a"), "rejected messages should not be counted")
+
+	require.Equal(t, "data: hello\n\n", serve("b"), "quotas should be separate for each key")
+}
+
+func TestServer_Quota_reject(t *testing.T) {
+	t.Parallel()
+
+	for _, period := range []time.Duration{time.Hour, 0} {
+		s := &sse.Server{Provider: &helloProvider{}, Quota: 13, QuotaPeriod: period}
+
+		serve := func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req, cancel := request(t, "", "http://localhost", nil)
+			defer cancel()
+
+			go cancel()
+			s.ServeHTTP(rec, req)
+
+			return rec
+		}
+
+		require.Equal(t, "data: hello\n\n", serve().Body.String(), "message under quota not sent")
+
+		rec := serve()
+		if period == 0 {
+			require.Equal(t, http.StatusNoContent, rec.Code, "quota which is never reset should stop clients")
+			continue
+		}
+
+		require.Equal(t, http.StatusTooManyRequests, rec.Code, "session over quota should be rejected")
+		require.Equal(t, "3600", rec.Header().Get("Retry-After"), "clients should wait for the reset")
+		require.Contains(t, rec.Body.String(), `"quota":13`, "invalid error")
+	}
+}
+
+func TestServer_Quota_noPeriod(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{Provider: &helloProvider{}, Quota: 20}
+
+	serve := func() string {
+		rec := httptest.NewRecorder()
+		req, cancel := request(t, "", "http://localhost", nil)
+		defer cancel()
+
+		go cancel()
+		s.ServeHTTP(rec, req)
+
+		return rec.Body.String()
+	}
+
+	require.Equal(t, "data: hello\n\n", serve(), "message under quota not sent")
+
+	// The quota is exceeded after the session started, so it can't be rejected.
+	body := serve()
+	require.Contains(t, body, "event: "+sse.QuotaExceededEventType+"\n", "quota exceeded event not sent")
+	require.Contains(t, body, "retry: 1000\n", "clients should not reconnect immediately")
+}
+
+func TestServer_SetReplayProvider(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{Provider: &mockProvider{}}
+	require.ErrorIs(t, s.SetReplayProvider(&sse.FiniteReplayProvider{Count: 1}, 0), sse.ErrReplaySwapUnsupported, "invalid error")
+
+	s = &sse.Server{}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+	require.NoError(t, s.SetReplayProvider(&sse.FiniteReplayProvider{Count: 1}, 0), "Joe should support replacing the replay provider")
+}
+
+func TestServer_TopicAliases(t *testing.T) {
+	t.Parallel()
+
+	p := newMockProvider(t, nil)
+	s := &sse.Server{
+		Provider:     p,
+		TopicAliases: map[string]string{"orders": "orders.v2"},
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{"orders"}}, true
+		},
+	}
+
+	require.NoError(t, s.Publish(&sse.Message{}, "orders"), "unexpected Publish error")
+	require.Equal(t, []string{"orders", "orders.v2"}, p.PubTopics, "deprecated topic not resolved")
+	require.NoError(t, s.Publish(&sse.Message{}, "orders.v2"), "unexpected Publish error")
+	require.Equal(t, []string{"orders.v2", "orders"}, p.PubTopics, "new topic not resolved")
+	require.NoError(t, s.Publish(&sse.Message{}, "users"), "unexpected Publish error")
+	require.Equal(t, []string{"users"}, p.PubTopics, "topic without aliases changed")
+
+	req, cancel := request(t, "", "http://localhost", nil)
+	go cancel()
+	s.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Equal(t, []string{"orders", "orders.v2"}, p.Sub.Topics, "subscription topics not resolved")
+	require.Equal(t, map[string]sse.TopicAliasUsage{"orders": {Publishes: 1, Subscriptions: 1}}, s.TopicAliasStats(), "invalid stats")
+}
+
+type recordingProvider struct {
+	mockProvider
+	published []string
+	mu        sync.Mutex
+}
+
+func (r *recordingProvider) Publish(m *sse.Message, topics []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.published = append(r.published, strings.Join(topics, ",")+" "+m.String())
+	return nil
+}
+
+func TestFanIn(t *testing.T) {
+	t.Parallel()
+
+	p := &recordingProvider{}
+	s := &sse.Server{Provider: p}
+
+	db, queue := make(chan any, 2), make(chan any, 2)
+	db <- "update"
+	db <- 42
+	close(db)
+	queue <- "job"
+	close(queue)
+
+	marshal := func(v any) (*sse.Message, error) {
+		m := &sse.Message{}
+		m.AppendData(v.(string))
+		return m, nil
+	}
+
+	var failed []string
+	f := &sse.FanIn{
+		Sources: []sse.FanInSource{
+			{Name: "db", Values: db, Marshal: marshal},
+			{Name: "queue", Values: queue, Marshal: func(v any) (*sse.Message, error) {
+				m, _ := marshal(v)
+				m.Type = sse.Type("created")
+				return m, nil
+			}},
+		},
+		OnError: func(source string, err error) { failed = append(failed, source) },
+	}
+
+	require.NoError(t, f.Run(context.Background(), s, "dashboard"), "unexpected Run error")
+	require.ElementsMatch(t, []string{
+		"dashboard event: db\ndata: update\n\n",
+		"dashboard event: queue.created\ndata: job\n\n",
+	}, p.published, "invalid published messages")
+	require.Equal(t, []string{"db"}, failed, "panicking marshal should be reported")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, (&sse.FanIn{Sources: []sse.FanInSource{{Values: make(chan any)}}}).Run(ctx, s, "dashboard"), context.Canceled, "invalid error on context done")
+}
+
+type originProvider struct{ mockProvider }
+
+func (originProvider) Subscribe(ctx context.Context, sub sse.Subscription) error {
+	for _, origin := range []string{"alice", "bob"} {
+		m := &sse.Message{Origin: origin}
+		m.AppendData("from " + origin)
+		if err := sub.Client.Send(m); err != nil {
+			return err
+		}
+	}
+
+	if err := sub.Client.Flush(); err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	return nil
+}
+
+func TestServer_NoEcho(t *testing.T) {
+	t.Parallel()
+
+	for _, noEcho := range []bool{true, false} {
+		s := &sse.Server{
+			Provider: &originProvider{},
+			OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+				return sse.Subscription{Client: s, Topics: []string{sse.DefaultTopic}, Identity: "alice", NoEcho: noEcho}, true
+			},
+		}
+
+		rec := httptest.NewRecorder()
+		req, cancel := request(t, "", "http://localhost", nil)
+		go cancel()
+		s.ServeHTTP(rec, req)
+
+		if noEcho {
+			require.Equal(t, "data: from bob\n\n", rec.Body.String(), "own message was echoed")
+		} else {
+			require.Equal(t, "data: from alice\n\ndata: from bob\n\n", rec.Body.String(), "own message should be echoed by default")
+		}
+	}
+}
+
+func TestServer_Filter(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		Provider: &originProvider{},
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{
+				Client: s,
+				Topics: []string{sse.DefaultTopic},
+				Filter: func(m *sse.Message) bool { return m.Origin == "bob" },
+			}, true
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req, cancel := request(t, "", "http://localhost", nil)
+	go cancel()
+	s.ServeHTTP(rec, req)
+
+	require.Equal(t, "data: from bob\n\n", rec.Body.String(), "filtered out message was received")
+}
+
+func TestServer_Beacon(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{Beacon: true}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL) //nolint:noctx // irrelevant
+	require.NoError(t, err, "request failed")
+	defer res.Body.Close()
+
+	var head strings.Builder
+	buf := make([]byte, 1)
+	for !strings.HasSuffix(head.String(), "\n\n") {
+		_, err = res.Body.Read(buf)
+		require.NoError(t, err, "failed to read session ID")
+		head.Write(buf)
+	}
+
+	ev := toEv(t, head.String())
+	require.Equal(t, sse.SessionIDEventType, ev.Type, "invalid event type")
+	require.Equal(t, sse.BeaconStats{Active: 1}, s.BeaconStats(), "invalid stats")
+
+	rec := httptest.NewRecorder()
+	s.BeaconHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code, "only POST should be accepted")
+
+	for _, id := range []string{ev.Data, ev.Data} {
+		rec = httptest.NewRecorder()
+		s.BeaconHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(id)))
+		require.Equal(t, http.StatusNoContent, rec.Code, "invalid status")
+	}
+
+	_, err = io.ReadAll(res.Body)
+	require.NoError(t, err, "session should end after the beacon")
+	require.Equal(t, sse.BeaconStats{Released: 1, Unknown: 1}, s.BeaconStats(), "invalid stats")
+}
+
+func TestSamplingProvider(t *testing.T) {
+	t.Parallel()
+
+	p := newMockProvider(t, nil)
+	s := &sse.SamplingProvider{
+		Provider: p,
+		Topics: map[string]sse.TopicSampling{
+			"debug": {Rate: 2, Period: time.Hour, Key: func(m *sse.Message) string { return m.Type.String() }},
+			"keyed": {Probability: 0.5, Key: func(m *sse.Message) string { return m.ID.String() }},
+		},
+	}
+
+	m := &sse.Message{Type: sse.Type("a")}
+	for i := 0; i < 3; i++ {
+		p.PubTopics = nil
+		require.NoError(t, s.Publish(m, []string{"debug", sse.DefaultTopic}), "unexpected publish error")
+	}
+	require.Equal(t, []string{sse.DefaultTopic}, p.PubTopics, "message over rate should be published only to unsampled topics")
+	require.Equal(t, sse.SamplingStats{Delivered: 2, SampledOut: 1}, s.Stats("debug"), "invalid stats")
+	require.Equal(t, sse.SamplingStats{}, s.Stats(sse.DefaultTopic), "unsampled topics should have no stats")
+
+	p.Published = false
+	require.NoError(t, s.Publish(&sse.Message{Type: sse.Type("b")}, []string{"debug"}), "unexpected publish error")
+	require.True(t, p.Published, "keys should be limited separately")
+
+	for i := 0; i < 100; i++ {
+		m := &sse.Message{ID: sse.ID(strconv.Itoa(i))}
+		p.Published = false
+		require.NoError(t, s.Publish(m, []string{"keyed"}), "unexpected publish error")
+		first := p.Published
+
+		p.Published = false
+		require.NoError(t, s.Publish(m, []string{"keyed"}), "unexpected publish error")
+		require.Equal(t, first, p.Published, "messages with the same key should be sampled alike")
+	}
+
+	st := s.Stats("keyed")
+	require.Equal(t, uint64(200), st.Delivered+st.SampledOut, "invalid stats")
+	require.NotZero(t, st.Delivered, "no messages delivered")
+	require.NotZero(t, st.SampledOut, "no messages sampled out")
+}
+
+func TestServerConfigFromEnv(t *testing.T) {
+	t.Setenv(sse.EnvSessionLimit, "4")
+	t.Setenv(sse.EnvQuotaPeriod, "1h")
+	t.Setenv(sse.EnvReplayTTL, "5m")
+	t.Setenv(sse.EnvHighWaterMarks, "orders=10, debug=2")
+	t.Setenv(sse.EnvCongestionPolicy, "block")
+	t.Setenv(sse.EnvKeepAlive, "15s")
+	t.Setenv(sse.EnvKeepAliveComment, "keep-alive")
+	t.Setenv(sse.EnvMetrics, "true")
+
+	c, err := sse.ServerConfigFromEnv()
+	require.NoError(t, err, "unexpected error")
+	require.Equal(t, sse.ServerConfig{
+		SessionLimit:     4,
+		QuotaPeriod:      time.Hour,
+		ReplayTTL:        5 * time.Minute,
+		HighWaterMarks:   map[string]int{"orders": 10, "debug": 2},
+		CongestionPolicy: sse.CongestionBlock,
+		KeepAlive:        15 * time.Second,
+		KeepAliveComment: "keep-alive",
+		Metrics:          true,
+	}, c, "invalid config")
+
+	s := &sse.Server{}
+	c.Apply(s)
+	require.Equal(t, 4, s.SessionLimit, "session limit not applied")
+	require.Equal(t, 15*time.Second, s.KeepAlive, "keep-alive not applied")
+	require.Equal(t, "keep-alive", s.KeepAliveComment, "keep-alive comment not applied")
+
+	j, ok := s.Provider.(*sse.Joe)
+	require.True(t, ok, "replay provider should be configured using Joe")
+	require.Equal(t, 5*time.Minute, j.ReplayGCInterval, "GC interval should default to the TTL")
+	require.Equal(t, &sse.ValidReplayProvider{TTL: 5 * time.Minute}, j.ReplayProvider, "invalid replay provider")
+
+	t.Setenv(sse.EnvQuota, "lots")
+	_, err = sse.ServerConfigFromEnv()
+	require.ErrorContains(t, err, sse.EnvQuota, "invalid value should be reported")
+}
+
+func TestReadServerConfig(t *testing.T) {
+	t.Parallel()
+
+	c, err := sse.ReadServerConfig(strings.NewReader(`{"quota": 1024, "quotaPeriod": "24h", "replayCount": 100, "beacon": true, "keepAlive": "15s", "metrics": true}`))
+	require.NoError(t, err, "unexpected error")
+	require.Equal(t, sse.ServerConfig{Quota: 1024, QuotaPeriod: 24 * time.Hour, ReplayCount: 100, Beacon: true, KeepAlive: 15 * time.Second, Metrics: true}, c, "invalid config")
+
+	_, err = sse.ReadServerConfig(strings.NewReader(`{"replayLimit": 10}`))
+	require.Error(t, err, "unknown keys should be rejected")
+}
+
+func TestServer_PublishContext(t *testing.T) {
+	t.Parallel()
+
+	p := newMockProvider(t, nil)
+	s := &sse.Server{Provider: p}
+
+	m := &sse.Message{}
+	m.AppendData("hello")
+
+	require.NoError(t, s.PublishContext(sse.ContextWithTraceID(context.Background(), "abc"), m), "unexpected publish error")
+	require.Equal(t, "trace: abc\ndata: hello\n\n", p.Pub.String(), "trace ID not set")
+	require.Empty(t, m.TraceID, "published message should not be modified")
+
+	m.TraceID = "own"
+	require.NoError(t, s.PublishContext(sse.ContextWithTraceID(context.Background(), "abc"), m), "unexpected publish error")
+	require.Equal(t, "own", p.Pub.TraceID, "existing trace ID should be kept")
+}
+
+func TestServer_PublishQuota(t *testing.T) {
+	t.Parallel()
+
+	p := newMockProvider(t, nil)
+	s := &sse.Server{
+		Provider: p,
+		PublishQuota: func(publisher string) int64 {
+			if publisher == "team-a" {
+				return 2
+			}
+			return 0
+		},
+		PublishQuotaPeriod: time.Hour,
+	}
+
+	publish := func(publisher string) error {
+		m := &sse.Message{}
+		m.AppendData("hello")
+		return s.PublishContext(sse.ContextWithPublisher(context.Background(), publisher), m)
+	}
+
+	require.NoError(t, publish("team-a"), "unexpected publish error")
+	require.Equal(t, "team-a", p.Pub.Origin, "publisher not attached to message")
+	require.NoError(t, publish("team-a"), "unexpected publish error")
+
+	err := publish("team-a")
+	require.ErrorIs(t, err, sse.ErrPublishQuotaExceeded, "quota not enforced")
+	var qerr *sse.PublishQuotaError
+	require.ErrorAs(t, err, &qerr, "invalid error type")
+	require.Equal(t, "team-a", qerr.Publisher, "invalid publisher")
+	require.Equal(t, int64(2), qerr.Quota, "invalid quota")
+	require.InDelta(t, time.Hour, qerr.ResetsIn, float64(time.Minute), "invalid reset time")
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, publish("team-b"), "unlimited publisher should not be rejected")
+	}
+
+	require.Equal(t, map[string]sse.PublisherStats{
+		"team-a": {Published: 2, Rejected: 1, Usage: 2},
+		"team-b": {Published: 3, Usage: 3},
+	}, s.PublisherStats(), "invalid publisher stats")
+}
+
+type duplicatingProvider struct {
+	mockProvider
+	topics []string
+}
+
+func (d *duplicatingProvider) Subscribe(_ context.Context, sub sse.Subscription) error {
+	d.topics = sub.Topics
+
+	m := &sse.Message{}
+	m.AppendData("once")
+	for i := 0; i < 2; i++ {
+		if err := sub.Client.Send(m); err != nil {
+			return err
+		}
+	}
+
+	return sub.Client.Flush()
+}
+
+func TestServer_TopicOverlap(t *testing.T) {
+	t.Parallel()
+
+	p := &duplicatingProvider{}
+	s := &sse.Server{
+		Provider:            p,
+		DeduplicateMessages: true,
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{"a", "b", "a"}}, true
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req, cancel := request(t, "", "http://localhost", nil)
+	defer cancel()
+	s.ServeHTTP(rec, req)
+
+	require.Equal(t, []string{"a", "b"}, p.topics, "duplicate topics should be removed")
+	require.Equal(t, "data: once\n\n", rec.Body.String(), "message should be sent once")
+	require.Equal(t, sse.TopicOverlapStats{Sessions: 1, DroppedMessages: 1}, s.TopicOverlapStats(), "invalid stats")
+}
+
+func TestServer_TopicOverlap_patterns(t *testing.T) {
+	t.Parallel()
+
+	p := &duplicatingProvider{}
+	s := &sse.Server{
+		Provider: p,
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{"a.b", "a.*", "c", "a.b.*", "a.**", "*.c"}}, true
+		},
+	}
+
+	rec := httptest.NewRecorder()
+	req, cancel := request(t, "", "http://localhost", nil)
+	defer cancel()
+	s.ServeHTTP(rec, req)
+
+	require.Equal(t, []string{"a.*", "c", "*.c"}, p.topics, "topics matched by patterns should be removed")
+	require.Equal(t, uint64(1), s.TopicOverlapStats().Sessions, "invalid stats")
+}
+
+func TestServer_MaxSessionAge(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		Provider:        &helloProvider{},
+		MaxSessionAge:   10 * time.Millisecond,
+		ReconnectJitter: time.Second,
+	}
+
+	rec := httptest.NewRecorder()
+	req, cancel := request(t, "", "http://localhost", nil)
+	defer cancel()
+	s.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	require.True(t, strings.HasPrefix(body, "data: hello\n\n"), "session should receive messages until it expires")
+
+	ev := toEv(t, strings.TrimPrefix(body, "data: hello\n\n"))
+	require.Equal(t, sse.ReconnectEventType, ev.Type, "invalid event type")
+	require.Contains(t, ev.Data, `"reason":"max_session_age"`, "invalid event data")
+	require.Contains(t, body, "retry: ", "reconnect event should have a retry hint")
+	require.Equal(t, uint64(1), s.ExpiredSessions(), "invalid expired sessions count")
+}
+
+func TestServer_EventVersions(t *testing.T) {
+	t.Parallel()
+
+	versions := &sse.EventVersions{}
+	versions.Register("order.created", "v1", func(m *sse.Message) (*sse.Message, error) {
+		v1 := &sse.Message{ID: m.ID}
+		v1.AppendData("legacy")
+		return v1, nil
+	})
+	versions.Register("order.created", "v2", nil)
+	require.Equal(t, []string{"order.created.v1", "order.created.v2"}, versions.Versions("order.created"), "invalid versions")
+
+	p := &recordingProvider{}
+	s := &sse.Server{Provider: p, EventVersions: versions}
+
+	m := &sse.Message{ID: sse.ID("1"), Type: sse.Type("order.created")}
+	m.AppendData("order")
+	require.NoError(t, s.Publish(m, "orders"), "unexpected publish error")
+
+	other := &sse.Message{Type: sse.Type("order.deleted")}
+	other.AppendData("order")
+	require.NoError(t, s.Publish(other, "orders"), "unexpected publish error")
+
+	require.Equal(t, []string{
+		"orders id: 1\nevent: order.created.v1\ndata: legacy\n\n",
+		"orders id: 1\nevent: order.created.v2\ndata: order\n\n",
+		"orders event: order.deleted\ndata: order\n\n",
+	}, p.published, "invalid published messages")
+	require.Equal(t, "order.created", m.Type.String(), "published message should not be modified")
+
+	versions.Register("order.created", "v1", func(*sse.Message) (*sse.Message, error) { return nil, errors.New("fail") })
+	require.Error(t, s.Publish(m, "orders"), "transformer error should be returned")
+	require.Len(t, p.published, 3, "no version should be published on error")
+}
+
+func TestSplitEventVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		typ, name, version string
+	}{
+		{"order.created.v2", "order.created", "v2"},
+		{"order.created.v12", "order.created", "v12"},
+		{"order.created", "order.created", ""},
+		{"order.v", "order.v", ""},
+		{"order.vx", "order.vx", ""},
+		{"v1", "v1", ""},
+	}
+
+	for _, test := range tests {
+		name, version := sse.SplitEventVersion(test.typ)
+		require.Equal(t, test.name, name, "invalid name for %q", test.typ)
+		require.Equal(t, test.version, version, "invalid version for %q", test.typ)
+	}
+}
+
+type unhealthyProvider struct {
+	mockProvider
+	err error
+}
+
+func (u *unhealthyProvider) Healthy() error { return u.err }
+
+func TestServer_Healthy(t *testing.T) {
+	t.Parallel()
+
+	check := func(s *sse.Server) (int, string) {
+		rec := httptest.NewRecorder()
+		s.HealthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+		return rec.Code, rec.Body.String()
+	}
+
+	s := &sse.Server{}
+	require.NoError(t, s.Healthy(), "new server should be healthy")
+	code, body := check(s)
+	require.Equal(t, http.StatusOK, code, "invalid status for healthy server")
+	require.Equal(t, `{"healthy":true,"draining":false}`+"\n", body, "invalid body for healthy server")
+
+	require.NoError(t, s.Shutdown(context.Background()), "unexpected shutdown error")
+	require.ErrorIs(t, s.Healthy(), sse.ErrServerDraining, "shut down server should be draining")
+	code, body = check(s)
+	require.Equal(t, http.StatusServiceUnavailable, code, "invalid status for draining server")
+	require.Contains(t, body, `"draining":true`, "draining not reported")
+
+	j := &sse.Joe{}
+	require.NoError(t, j.Shutdown(context.Background()), "unexpected shutdown error")
+	require.ErrorIs(t, (&sse.Server{Provider: j}).Healthy(), sse.ErrProviderClosed, "closed Joe should be unhealthy")
+
+	errDown := errors.New("redis is down")
+	s = &sse.Server{Provider: &sse.SamplingProvider{Provider: &unhealthyProvider{err: errDown}}}
+	require.ErrorIs(t, s.Healthy(), errDown, "provider error not reported")
+	code, body = check(s)
+	require.Equal(t, http.StatusServiceUnavailable, code, "invalid status for unhealthy provider")
+	require.Contains(t, body, "redis is down", "provider error not in body")
+}
+
+func TestServer_RetireTopic(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		// The session ID is sent immediately, so the clients are connected before anything is published.
+		Beacon: true,
+		OnSession: func(sess *sse.Session) (sse.Subscription, bool) {
+			if sess.Req.URL.Query().Has("end") {
+				require.NoError(t, sess.EndStream(), "unexpected EndStream error")
+				return sse.Subscription{}, false
+			}
+
+			return sse.Subscription{Client: sess, Topics: sess.Req.URL.Query()["topic"]}, true
+		},
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL + "?end")
+	require.NoError(t, err, "unexpected request error")
+	_ = res.Body.Close()
+	require.Equal(t, http.StatusNoContent, res.StatusCode, "session should be ended")
+
+	connected := make(chan struct{}, 2)
+	connect := func(query string) <-chan error {
+		done := make(chan error, 1)
+		go func() {
+			c := &sse.Client{HTTPClient: ts.Client()}
+			conn := c.NewConnection(req(t, "", ts.URL+"?"+query, nil))
+			conn.SubscribeToAllWithLifecycle(func(e sse.Event) {
+				if e.Lifecycle == sse.LifecycleConnected {
+					connected <- struct{}{}
+				}
+			})
+			done <- conn.Connect()
+		}()
+		return done
+	}
+
+	retiredOnly := connect("topic=old")
+	mixed := connect("topic=old&topic=new")
+	<-connected
+	<-connected
+
+	require.Equal(t, 1, s.RetireTopic("old"), "only the session subscribed just to the retired topic should be ended")
+	require.Equal(t, []string{"old"}, s.RetiredTopics(), "invalid retired topics")
+
+	require.Error(t, <-retiredOnly, "ended session should be disconnected")
+
+	err = (&sse.Client{HTTPClient: ts.Client()}).NewConnection(req(t, "", ts.URL+"?topic=old", nil)).Connect()
+	require.ErrorIs(t, err, sse.ErrStreamEnded, "reconnections to retired topics should be rejected")
+
+	select {
+	case err := <-mixed:
+		t.Fatalf("session with active topics should not be ended: %v", err)
+	default:
+	}
+
+	require.NoError(t, s.Shutdown(context.Background()), "unexpected shutdown error")
+	<-mixed
+}
+
+func TestServer_RetireTopic_mixed(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		Beacon: true,
+		OnSession: func(sess *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: sess, Topics: sess.Req.URL.Query()["topic"]}, true
+		},
+	}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	defer func() { _ = s.Shutdown(context.Background()) }()
+
+	connect := func() <-chan string {
+		connected := make(chan struct{})
+		data := make(chan string, 4)
+
+		c := &sse.Client{HTTPClient: ts.Client()}
+		conn := c.NewConnection(req(t, "", ts.URL+"?topic=old&topic=new", nil))
+		conn.SubscribeToAllWithLifecycle(func(e sse.Event) {
+			if e.Lifecycle == sse.LifecycleConnected {
+				close(connected)
+			}
+		})
+		conn.SubscribeMessages(func(e sse.Event) { data <- e.Data })
+		go func() { _ = conn.Connect() }()
+
+		<-connected
+		// Give the session time to subscribe to the provider.
+		time.Sleep(time.Millisecond * 50)
+
+		return data
+	}
+	publish := func(data, topic string) {
+		m := &sse.Message{}
+		m.AppendData(data)
+		require.NoError(t, s.Publish(m, topic), "unexpected publish error")
+	}
+	receive := func(data <-chan string) string {
+		select {
+		case d := <-data:
+			return d
+		case <-time.After(time.Second * 2):
+			t.Fatal("no message received")
+			return ""
+		}
+	}
+
+	existing := connect()
+	require.Zero(t, s.RetireTopic("old"), "session with active topics should not be ended")
+
+	publish("before reconnecting", "old")
+	require.Equal(t, "before reconnecting", receive(existing), "existing session should still receive the retired topic")
+
+	reconnected := connect()
+	publish("retired", "old")
+	publish("active", "new")
+	require.Equal(t, "active", receive(reconnected), "new session should not receive the retired topic")
+}
+
+func TestServer_Heartbeat(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{HeartbeatInterval: time.Millisecond}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := &sse.Client{HTTPClient: ts.Client()}
+	conn := c.NewConnection(reqCtx(t, ctx, "", ts.URL, nil))
+
+	var heartbeats []time.Time
+	conn.SubscribeEvent(sse.HeartbeatEventType, func(e sse.Event) {
+		if len(heartbeats) == 3 {
+			// Heartbeats already received when canceling are still dispatched.
+			return
+		}
+
+		var hb struct{ Time time.Time }
+		require.NoError(t, e.DecodePayload(&hb), "invalid heartbeat data")
+		heartbeats = append(heartbeats, hb.Time)
+		if len(heartbeats) == 3 {
+			cancel()
+		}
+	})
+
+	require.ErrorIs(t, conn.Connect(), context.Canceled, "unexpected Connect error")
+	require.Len(t, heartbeats, 3, "heartbeats not received")
+	require.True(t, heartbeats[0].Before(heartbeats[2]), "heartbeats should have increasing times")
+
+	rec := httptest.NewRecorder()
+	s.EchoHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo", http.NoBody))
+	require.Contains(t, rec.Body.String(), `{"time":"`, "invalid echo response")
+}
+
+func TestServer_KeepAlive(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{KeepAlive: 10 * time.Millisecond, KeepAliveComment: "still here"}
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := ts.Client().Do(reqCtx(t, ctx, "", ts.URL, nil))
+	require.NoError(t, err, "request failed")
+	defer res.Body.Close()
+
+	r := bufio.NewReader(res.Body)
+	for i := 0; i < 2; i++ {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err, "keep-alive comments not received")
+		require.Equal(t, ": still here\n", line, "invalid keep-alive comment")
+
+		line, err = r.ReadString('\n')
+		require.NoError(t, err, "keep-alive comments not received")
+		require.Equal(t, "\n", line, "keep-alive comment should be a message")
+	}
+}
+
+func TestServer_Presence(t *testing.T) {
+	t.Parallel()
+
+	presence := &sse.Presence{
+		HalfLife: 100 * time.Millisecond,
+		Identity: func(r *http.Request) string { return r.Header.Get("X-User") },
+	}
+	s := &sse.Server{
+		Presence: presence,
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{sse.DefaultTopic}, Identity: "alice"}, true
+		},
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ctx, cancelWatch := context.WithCancel(context.Background())
+	changes := presence.Watch(ctx, "alice")
+	require.Equal(t, sse.PresenceChange{UserID: "alice"}, <-changes, "invalid initial presence")
+	require.False(t, presence.Online("alice"), "unseen user should be offline")
+
+	req, cancel := request(t, "", "http://localhost", nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}()
+
+	change := <-changes
+	require.True(t, change.Online, "user should come online when a session is opened")
+	require.True(t, presence.Online("alice"), "user with sessions should be online")
+	require.Equal(t, 1.0, presence.Score("alice"), "user with sessions should have the maximum score")
+
+	cancel()
+	<-done
+
+	require.True(t, presence.Online("alice"), "user should stay online for a while after the session is closed")
+	require.Less(t, presence.Score("alice"), 1.0, "score should decay after the session is closed")
+
+	change = <-changes
+	require.False(t, change.Online, "user should go offline after the half-life")
+	require.False(t, presence.Online("alice"), "user should be offline")
+	lastSeen, ok := presence.LastSeen("alice")
+	require.True(t, ok, "last seen time should be kept")
+	require.Equal(t, change.LastSeen, lastSeen, "invalid last seen time")
+
+	echo := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	echo.Header.Set("X-User", "alice")
+	s.EchoHandler().ServeHTTP(httptest.NewRecorder(), echo)
+	require.True(t, (<-changes).Online, "heartbeat acknowledgement should touch the user")
+
+	cancelWatch()
+	for range changes { //nolint:revive // Drain the channel.
+	}
+}
+
+func TestServer_WriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	// Beacon makes the server respond with the headers before subscribing the sessions.
+	s := &sse.Server{WriteTimeout: 50 * time.Millisecond, Beacon: true}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	slow, err := http.Get(ts.URL) //nolint:noctx // irrelevant
+	require.NoError(t, err, "request failed")
+	defer slow.Body.Close()
+
+	fast, err := http.Get(ts.URL) //nolint:noctx // irrelevant
+	require.NoError(t, err, "request failed")
+	defer fast.Body.Close()
+	go func() { _, _ = io.Copy(io.Discard, fast.Body) }()
+
+	m := &sse.Message{}
+	m.AppendData(strings.Repeat("x", 1<<18))
+
+	require.Eventually(t, func() bool {
+		require.NoError(t, s.Publish(m), "unexpected publish error")
+		return s.SlowSessions() == 1
+	}, 10*time.Second, time.Millisecond, "slow session not closed")
+
+	require.Never(t, func() bool {
+		require.NoError(t, s.Publish(m), "unexpected publish error")
+		return s.SlowSessions() > 1
+	}, 200*time.Millisecond, 10*time.Millisecond, "fast session should not be closed")
+}
+
+func TestServer_CloseSessions(t *testing.T) {
+	t.Parallel()
+
+	ended := make(chan sse.CloseReason, 1)
+	s := &sse.Server{
+		Beacon: true,
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{sse.DefaultTopic}, Identity: "alice"}, true
+		},
+		OnSessionEnd: func(_ *sse.Session, r sse.CloseReason) { ended <- r },
+		CloseEvents:  true,
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	c := &sse.Client{HTTPClient: ts.Client()}
+	conn := c.NewConnection(req(t, "", ts.URL, nil))
+	conn.SubscribeEvent(sse.SessionIDEventType, func(sse.Event) {
+		require.Equal(t, 1, s.CloseSessions("alice", sse.CloseAuthRevoked), "session not closed")
+	})
+
+	err := conn.Connect()
+	var closed *sse.ClosedError
+	require.ErrorAs(t, err, &closed, "close reason not received")
+	require.Equal(t, sse.CloseAuthRevoked, closed.Reason, "invalid close reason")
+	require.NotEmpty(t, closed.Message, "close message not received")
+
+	require.Equal(t, sse.CloseAuthRevoked, <-ended, "invalid reason given to OnSessionEnd")
+	require.Equal(t, map[sse.CloseReason]uint64{sse.CloseAuthRevoked: 1}, s.CloseReasons(), "invalid close reason counts")
+	require.Zero(t, s.CloseSessions("alice", sse.CloseAuthRevoked), "closed sessions should be removed")
+}
+
+func TestServer_CloseSessions_noCloseEvents(t *testing.T) {
+	t.Parallel()
+
+	ended := make(chan sse.CloseReason, 1)
+	s := &sse.Server{
+		Beacon: true,
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{sse.DefaultTopic}, Identity: "alice"}, true
+		},
+		OnSessionEnd: func(_ *sse.Session, r sse.CloseReason) { ended <- r },
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	var types []string
+	c := &sse.Client{HTTPClient: ts.Client()}
+	conn := c.NewConnection(req(t, "", ts.URL, nil))
+	conn.SubscribeToAll(func(e sse.Event) { types = append(types, e.Type) })
+	conn.SubscribeEvent(sse.SessionIDEventType, func(sse.Event) {
+		require.Equal(t, 1, s.CloseSessions("alice", sse.CloseAuthRevoked), "session not closed")
+	})
+
+	require.ErrorIs(t, conn.Connect(), io.EOF, "close reason should not be sent")
+	require.NotContains(t, types, sse.CloseEventType, "close event should not be sent")
+	require.Equal(t, sse.CloseAuthRevoked, <-ended, "invalid reason given to OnSessionEnd")
+}
+
+func TestServer_SubscribeSessions(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		OnSession: func(s *sse.Session) (sse.Subscription, bool) {
+			return sse.Subscription{Client: s, Topics: []string{"a"}, Identity: "alice"}, true
+		},
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The response is sent only after the first message is published, so it is received concurrently.
+	received := make(chan string)
+	go func() {
+		defer close(received)
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, http.NoBody)
+		if err != nil {
+			return
+		}
+		res, err := http.DefaultClient.Do(r)
+		if err != nil {
+			return
+		}
+		defer res.Body.Close()
+
+		sc := bufio.NewScanner(res.Body)
+		for sc.Scan() {
+			if data, ok := strings.CutPrefix(sc.Text(), "data: "); ok {
+				received <- data
+			}
+		}
+	}()
+
+	// The session may not be (re)subscribed yet, so the message is published until it is received.
+	awaitTopic := func(topic string) {
+		t.Helper()
+
+		for {
+			m := &sse.Message{}
+			m.AppendData(topic)
+			require.NoError(t, s.Publish(m, topic), "unexpected Publish error")
+
+			select {
+			case data := <-received:
+				require.Equal(t, topic, data, "message received from an unsubscribed topic")
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	awaitTopic("a")
+
+	require.Equal(t, 1, s.SubscribeSessions("alice", "b"), "invalid number of sessions subscribed")
+	require.Equal(t, 1, s.UnsubscribeSessions("alice", "a"), "invalid number of sessions unsubscribed")
+	require.Zero(t, s.SubscribeSessions("bob", "b"), "sessions of other identities should not be changed")
+
+	awaitTopic("b")
+
+	m := &sse.Message{}
+	m.AppendData("a")
+	require.NoError(t, s.Publish(m, "a"), "unexpected Publish error")
+	awaitTopic("b")
+}
+
+func TestServer_Compression(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		Compression: true,
+		OnSession: func(sess *sse.Session) (sse.Subscription, bool) {
+			m := &sse.Message{}
+			m.AppendData(`{"verbose":"payload"}`)
+			_ = sess.Send(m)
+			_ = sess.Flush()
+
+			return sse.Subscription{}, false
+		},
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	tests := []struct {
+		accept   string
+		encoding string
+	}{
+		{accept: "gzip", encoding: "gzip"},
+		{accept: "deflate, gzip;q=0.5", encoding: "deflate"},
+		{accept: "gzip;q=0, deflate", encoding: "deflate"},
+		{accept: "br", encoding: ""},
+	}
+
+	for _, tt := range tests {
+		var encoding string
+		c := &sse.Client{
+			HTTPClient:     &http.Client{Transport: &http.Transport{DisableCompression: true}},
+			AcceptEncoding: tt.accept,
+			ResponseValidator: func(r *http.Response) error {
+				encoding = r.Header.Get("Content-Encoding")
+				return nil
+			},
+		}
+		conn := c.NewConnection(req(t, "", ts.URL, nil))
+
+		var data string
+		conn.SubscribeMessages(func(e sse.Event) { data = e.Data })
+
+		require.ErrorIs(t, conn.Connect(), io.EOF, "unexpected Connect error for %q", tt.accept)
+		require.Equal(t, tt.encoding, encoding, "invalid encoding for %q", tt.accept)
+		require.Equal(t, `{"verbose":"payload"}`, data, "invalid data for %q", tt.accept)
+	}
+}
+
+func TestServer_KeepAlive_sessionEnd(t *testing.T) {
+	t.Parallel()
+
+	s := &sse.Server{
+		KeepAlive:           time.Millisecond,
+		DeduplicateMessages: true,
+		MaxSessionAge:       50 * time.Millisecond,
+		OnSession: func(sess *sse.Session) (sse.Subscription, bool) {
+			sub := sess.Subscribe()
+			// The keep-alive comments must not be filtered.
+			sub.Filter = func(*sse.Message) bool { return false }
+			return sub, true
+		},
+	}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // irrelevant
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	res, err := ts.Client().Get(ts.URL)
+	require.NoError(t, err, "request failed")
+	defer res.Body.Close()
+
+	var comments int
+	sc := bufio.NewScanner(res.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case line == ": ping":
+			comments++
+		case line == "", strings.HasPrefix(line, "event: "), strings.HasPrefix(line, "data: "), strings.HasPrefix(line, "retry: "):
+		default:
+			t.Fatalf("malformed line %q", line)
+		}
+	}
+
+	require.NoError(t, sc.Err(), "unexpected read error")
+	require.Greater(t, comments, 1, "keep-alive comments should not be deduplicated")
+	require.Zero(t, s.TopicOverlapStats().DroppedMessages, "keep-alive comments should not be dropped")
+}
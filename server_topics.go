@@ -0,0 +1,155 @@
+package sse
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// SubscribeSessions adds the given topics to the subscriptions of the open sessions with the given
+// Identity, so applications can implement subscribing to topics over a single connection – for example,
+// when receiving a control message from the user. It returns the number of sessions changed.
+//
+// The sessions are resubscribed to the provider with their new topics, without events being replayed,
+// so the messages published while they are resubscribed may be lost.
+func (s *Server) SubscribeSessions(identity string, topics ...string) int {
+	return s.topics.update(identity, func(current []string) []string {
+		for _, t := range topics {
+			if !containsTopic(current, t) {
+				current = append(current, t)
+			}
+		}
+
+		return current
+	})
+}
+
+// UnsubscribeSessions removes the given topics from the subscriptions of the open sessions with the
+// given Identity. It returns the number of sessions changed. The sessions which are unsubscribed from
+// all their topics remain open, but receive no messages until they are subscribed to a topic again.
+// See SubscribeSessions for more info.
+func (s *Server) UnsubscribeSessions(identity string, topics ...string) int {
+	return s.topics.update(identity, func(current []string) []string {
+		kept := current[:0]
+		for _, t := range current {
+			if !containsTopic(topics, t) {
+				kept = append(kept, t)
+			}
+		}
+
+		return kept
+	})
+}
+
+// subscribe subscribes the session to the provider until the context is done, resubscribing it
+// each time its topics are changed using SubscribeSessions or UnsubscribeSessions.
+func (s *Server) subscribe(ctx context.Context, sub Subscription) error {
+	if sub.Identity == "" {
+		return s.provider.Subscribe(ctx, sub)
+	}
+
+	ts, unregister := s.topics.register(sub.Identity, sub.Topics)
+	defer unregister()
+
+	for {
+		subCtx, cancel := context.WithCancel(ctx)
+
+		// Sessions without topics aren't subscribed, as providers may not accept them.
+		var errc chan error
+		if len(sub.Topics) > 0 {
+			errc = make(chan error, 1)
+			go func(sub Subscription) { errc <- s.provider.Subscribe(subCtx, sub) }(sub)
+		}
+
+		select {
+		case err := <-errc:
+			cancel()
+			return err
+		case <-ctx.Done():
+			cancel()
+			if errc == nil {
+				return nil
+			}
+			return <-errc
+		case <-ts.changed:
+			cancel()
+			if errc != nil {
+				if err := <-errc; err != nil && !errors.Is(err, context.Canceled) {
+					return err
+				}
+			}
+		}
+
+		sub.Topics, _ = dedupeTopics(s.resolveTopics(s.topics.get(ts), false))
+		// Only the first subscription replays the events the client missed.
+		sub.LastEventID, sub.ReplaySince = EventID{}, time.Time{}
+	}
+}
+
+type topicSession struct {
+	changed chan struct{}
+	topics  []string
+}
+
+type sessionTopics struct {
+	sessions map[string]map[*topicSession]struct{}
+	mu       sync.Mutex
+}
+
+func (t *sessionTopics) register(identity string, topics []string) (*topicSession, func()) {
+	ts := &topicSession{changed: make(chan struct{}, 1), topics: append([]string(nil), topics...)}
+
+	t.mu.Lock()
+	if t.sessions == nil {
+		t.sessions = map[string]map[*topicSession]struct{}{}
+	}
+	if t.sessions[identity] == nil {
+		t.sessions[identity] = map[*topicSession]struct{}{}
+	}
+	t.sessions[identity][ts] = struct{}{}
+	t.mu.Unlock()
+
+	return ts, func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		delete(t.sessions[identity], ts)
+		if len(t.sessions[identity]) == 0 {
+			delete(t.sessions, identity)
+		}
+	}
+}
+
+func (t *sessionTopics) get(ts *topicSession) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]string(nil), ts.topics...)
+}
+
+func (t *sessionTopics) update(identity string, fn func([]string) []string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ts := range t.sessions[identity] {
+		ts.topics = fn(ts.topics)
+
+		select {
+		case ts.changed <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(t.sessions[identity])
+}
+
+func containsTopic(topics []string, topic string) bool {
+	for _, t := range topics {
+		if t == topic {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,114 @@
+package sse
+
+import (
+	"strings"
+	"sync"
+)
+
+// EventTransformer converts a message to the envelope of a version of its type. The given message
+// is a clone of the published one, which can be modified. Its type is set to the versioned type
+// after the transformer returns.
+type EventTransformer func(*Message) (*Message, error)
+
+// EventVersions is a registry of the versions of event types, used to publish the same logical
+// message under multiple versions during rolling upgrades of producers and consumers – for example,
+// a message with the type "order.created" as both "order.created.v1" and "order.created.v2".
+// Set it as the Server's EventVersions. The zero value is ready to use.
+type EventVersions struct {
+	versions map[string][]eventVersion
+	mu       sync.RWMutex
+}
+
+type eventVersion struct {
+	transform EventTransformer
+	typ       EventType
+}
+
+// Register registers a version of the given event type. The messages published with the unversioned
+// type are converted by the transformer and published with the type "<typ>.<version>", in the order
+// the versions were registered. If the transformer is nil, the message is published unchanged.
+// Registering a version again replaces its transformer. It panics if the versioned type is not a valid event type.
+func (v *EventVersions) Register(typ, version string, transform EventTransformer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.versions == nil {
+		v.versions = map[string][]eventVersion{}
+	}
+
+	ev := eventVersion{transform: transform, typ: Type(typ + "." + version)}
+	for i, existing := range v.versions[typ] {
+		if existing.typ == ev.typ {
+			v.versions[typ][i] = ev
+			return
+		}
+	}
+
+	v.versions[typ] = append(v.versions[typ], ev)
+}
+
+// Versions returns the versioned types registered for the given event type, in the order they are published.
+func (v *EventVersions) Versions(typ string) []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	types := make([]string, 0, len(v.versions[typ]))
+	for _, ev := range v.versions[typ] {
+		types = append(types, ev.typ.String())
+	}
+
+	return types
+}
+
+// transform returns the versions of the message, or nil if its type has no registered versions.
+func (v *EventVersions) transform(m *Message) ([]*Message, error) {
+	v.mu.RLock()
+	versions := v.versions[m.Type.String()]
+	v.mu.RUnlock()
+
+	if len(versions) == 0 {
+		return nil, nil
+	}
+
+	msgs := make([]*Message, 0, len(versions))
+	for _, ev := range versions {
+		vm := m.Clone()
+		if ev.transform != nil {
+			var err error
+			if vm, err = ev.transform(vm); err != nil {
+				return nil, err
+			}
+		}
+
+		vm.Type = ev.typ
+		msgs = append(msgs, vm)
+	}
+
+	return msgs, nil
+}
+
+// SplitEventVersion splits a versioned event type, such as "order.created.v2", into the logical
+// type and the version ("order.created" and "v2"). The version is the part after the last dot,
+// if it is a "v" followed by digits. Otherwise, the version is empty.
+func SplitEventVersion(typ string) (name, version string) {
+	i := strings.LastIndexByte(typ, '.')
+	if i < 0 || !isVersion(typ[i+1:]) {
+		return typ, ""
+	}
+
+	return typ[:i], typ[i+1:]
+}
+
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,7 +2,9 @@ package sse
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 )
 
 // ResponseWriter is a http.ResponseWriter augmented with a Flush method.
@@ -36,8 +38,53 @@ type Session struct {
 	// Last event ID of the client. It is unset if no ID was provided in the Last-Event-Id
 	// request header.
 	LastEventID EventID
+	// The capabilities the client advertised using the Sse-Capabilities header.
+	// It is never nil – if the client didn't advertise anything, it is empty.
+	Capabilities Capabilities
+	// The time from which the client requested events to be replayed, using either the Sse-Replay-Since
+	// header or the replaySince query parameter. It is zero if the client requested nothing. When subscribing
+	// the session, set the Subscription's ReplaySince field to this value to honor the request.
+	// See ParseReplaySince for the accepted values.
+	ReplaySince time.Time
 
 	didUpgrade bool
+	ended      bool
+}
+
+// Subscribe returns a subscription of the session to the given topics, which resumes the stream from
+// the session's last event ID or replay time, if any. Use it in the Server's OnSession callback:
+//
+//	s := &sse.Server{
+//		OnSession: func(sess *sse.Session) (sse.Subscription, bool) {
+//			return sess.Subscribe("orders", "users"), true
+//		},
+//	}
+//
+// If no topics are given, the session is subscribed to the default topic. Set the other fields
+// of the returned subscription, if needed, before returning it.
+func (s *Session) Subscribe(topics ...string) Subscription {
+	return Subscription{
+		Client:      s,
+		LastEventID: s.LastEventID,
+		ReplaySince: s.ReplaySince,
+		Topics:      topics,
+	}
+}
+
+// EndStream responds to the client with 204 No Content, which tells it to stop reconnecting –
+// for example, when retiring an endpoint. It must be called before anything is sent, and the
+// session must not be used afterwards. It returns ErrSessionStarted if the session was upgraded.
+func (s *Session) EndStream() error {
+	if s.didUpgrade {
+		return ErrSessionStarted
+	}
+
+	if !s.ended {
+		s.Res.WriteHeader(http.StatusNoContent)
+		s.ended = true
+	}
+
+	return nil
 }
 
 // Send sends the given event to the client. It returns any errors that occurred while writing the event.
@@ -96,12 +143,52 @@ func Upgrade(w http.ResponseWriter, r *http.Request) (*Session, error) {
 		id, _ = NewID(h[0])
 	}
 
-	return &Session{Req: r, Res: rw, LastEventID: id}, nil
+	since := r.Header.Get(HeaderReplaySince)
+	if since == "" {
+		since = r.URL.Query().Get(QueryReplaySince)
+	}
+	// Invalid values are ignored, as with invalid event IDs.
+	replaySince, _ := ParseReplaySince(since, time.Now())
+
+	return &Session{
+		Req:          r,
+		Res:          rw,
+		LastEventID:  id,
+		Capabilities: ParseCapabilities(r.Header.Get(HeaderCapabilities)),
+		ReplaySince:  replaySince,
+	}, nil
+}
+
+// The header and the query parameter a client can use to request the events published since a given time.
+// Browsers can't set headers when using EventSource, so the query parameter is also accepted.
+const (
+	HeaderReplaySince = "Sse-Replay-Since"
+	QueryReplaySince  = "replaySince"
+)
+
+// ParseReplaySince parses a replay time requested by a client. The value is either a duration
+// relative to now, such as "10m", which requests the events of the last 10 minutes, or
+// an RFC 3339 timestamp. A zero time is returned for an empty value.
+func ParseReplaySince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("go-sse.server: negative replay duration %q", value)
+		}
+		return now.Add(-d), nil
+	}
+
+	return time.Parse(time.RFC3339, value)
 }
 
 // ErrUpgradeUnsupported is returned when a request can't be upgraded to support server-sent events.
 var ErrUpgradeUnsupported = errors.New("go-sse.server: upgrade unsupported")
 
+// ErrSessionStarted is returned by Session.EndStream when events were already sent to the client.
+var ErrSessionStarted = errors.New("go-sse.server: session already started")
+
 // Canonicalized header keys.
 const (
 	headerLastEventID = "Last-Event-Id"
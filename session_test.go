@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"github.com/tmaxmax/go-sse"
@@ -85,3 +86,40 @@ func TestUpgradedRequest_Send_error(t *testing.T) {
 	require.ErrorIs(t, conn.Send(&sse.Message{ID: sse.ID("")}), errWriteFailed, "invalid Send error")
 	require.True(t, rec.Flushed, "writer wasn't flushed")
 }
+
+func TestUpgrade_replaySince(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/?replaySince=2023-11-19T10:00:00Z", http.NoBody)
+	sess, err := sse.Upgrade(httptest.NewRecorder(), req)
+	require.NoError(t, err, "unexpected Upgrade error")
+	require.Equal(t, time.Date(2023, 11, 19, 10, 0, 0, 0, time.UTC), sess.ReplaySince, "invalid time from query")
+
+	req.Header.Set(sse.HeaderReplaySince, "10m")
+	before := time.Now()
+	sess, err = sse.Upgrade(httptest.NewRecorder(), req)
+	require.NoError(t, err, "unexpected Upgrade error")
+	require.WithinRange(t, sess.ReplaySince, before.Add(-10*time.Minute), time.Now().Add(-10*time.Minute), "invalid time from header")
+
+	_, err = sse.ParseReplaySince("-10m", before)
+	require.Error(t, err, "negative durations should be rejected")
+	_, err = sse.ParseReplaySince("yesterday", before)
+	require.Error(t, err, "invalid values should be rejected")
+}
+
+func TestSession_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+	req.Header.Set("Last-Event-Id", "hello")
+
+	sess, err := sse.Upgrade(httptest.NewRecorder(), req)
+	require.NoError(t, err, "unexpected Upgrade error")
+
+	sub := sess.Subscribe("a", "b")
+	require.Equal(t, sse.Subscription{
+		Client:      sess,
+		LastEventID: sess.LastEventID,
+		Topics:      []string{"a", "b"},
+	}, sub, "invalid subscription")
+}
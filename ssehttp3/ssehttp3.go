@@ -0,0 +1,41 @@
+// Package ssehttp3 creates go-sse clients which connect to servers using HTTP/3, over QUIC.
+//
+// It is a separate module, so applications which don't use HTTP/3 don't depend on quic-go.
+package ssehttp3
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/tmaxmax/go-sse"
+)
+
+// NewClient creates a client with the settings of sse.DefaultClient, whose HTTP client connects using
+// HTTP/3 and is configured for streams which stay open indefinitely: there are no request or response
+// timeouts, the flow-control windows are sized for long-lived streams and keep-alives are sent, so idle
+// streams aren't closed and dead connections are detected. Multiple connections to the same server
+// share a single QUIC connection.
+//
+// The HTTP client's transport is an *http3.Transport. Close it when the client isn't used anymore,
+// to release its UDP socket. Configure the client further before using it, if needed.
+func NewClient() *sse.Client {
+	t := &http3.Transport{
+		QUICConfig: &quic.Config{
+			// The windows start large enough for bursts of events and grow up to the maximum
+			// if the streams are read fast enough.
+			InitialStreamReceiveWindow:     1 << 20,
+			MaxStreamReceiveWindow:         8 << 20,
+			InitialConnectionReceiveWindow: 16 << 20,
+			MaxConnectionReceiveWindow:     64 << 20,
+			MaxIdleTimeout:                 time.Minute,
+			KeepAlivePeriod:                15 * time.Second,
+		},
+	}
+
+	c := *sse.DefaultClient
+	c.HTTPClient = &http.Client{Transport: t}
+
+	return &c
+}
@@ -0,0 +1,54 @@
+package ssehttp3_test
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/quic-go/quic-go/http3"
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/ssehttp3"
+)
+
+func TestNewClient(t *testing.T) {
+	// The test server is used only for its certificate and the client configuration which trusts it.
+	ts := httptest.NewTLSServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err, "failed to listen")
+
+	s := &http3.Server{
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: ts.TLS.Certificates, MinVersion: tls.VersionTLS13}),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", r.Proto)
+		}),
+	}
+	go func() { _ = s.Serve(udp) }()
+	defer s.Close()
+
+	c := ssehttp3.NewClient()
+	require.Zero(t, c.HTTPClient.Timeout, "streams should not time out")
+
+	transport := c.HTTPClient.Transport.(*http3.Transport) //nolint:forcetypeassert // The test must panic if this is not true.
+	defer transport.Close()
+	transport.TLSClientConfig = ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone() //nolint:forcetypeassert // Same as above.
+
+	r, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://"+udp.LocalAddr().String(), http.NoBody)
+	require.NoError(t, err, "failed to create request")
+
+	conn := c.NewConnection(r)
+
+	var proto string
+	conn.SubscribeMessages(func(e sse.Event) { proto = e.Data })
+
+	require.ErrorIs(t, conn.Connect(), io.EOF, "unexpected Connect error")
+	require.Equal(t, "HTTP/3.0", proto, "HTTP/3 should be used")
+}
@@ -0,0 +1,176 @@
+package sseotel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type clientMetrics struct {
+	duration metric.Float64Histogram
+	events   metric.Int64Counter
+	retries  metric.Int64Counter
+	bytes    metric.Int64Counter
+}
+
+func newClientMetrics(m metric.Meter) clientMetrics {
+	var (
+		cm  clientMetrics
+		err error
+	)
+
+	cm.duration, err = m.Float64Histogram("sse.client.connection.duration",
+		metric.WithDescription("The duration of the connections."), metric.WithUnit("s"))
+	report(err)
+	cm.events, err = m.Int64Counter("sse.client.events",
+		metric.WithDescription("The number of events received."), metric.WithUnit("{event}"))
+	report(err)
+	cm.retries, err = m.Int64Counter("sse.client.retries",
+		metric.WithDescription("The number of reconnection attempts."), metric.WithUnit("{attempt}"))
+	report(err)
+	cm.bytes, err = m.Int64Counter("sse.client.bytes",
+		metric.WithDescription("The number of bytes received."), metric.WithUnit("By"))
+	report(err)
+
+	return cm
+}
+
+// InstrumentClient returns a copy of the client whose connection attempts are traced and measured.
+// The attempts' spans start when the request is sent and end when the connection is closed;
+// they are children of the span in the context of the connection's request, if there is one.
+//
+// Use InstrumentConnection to also count the events received.
+func InstrumentClient(c *sse.Client, opts ...Option) *sse.Client {
+	cfg := newConfig(opts)
+	metrics := newClientMetrics(cfg.meter())
+
+	instrumented := *c
+
+	httpClient := http.DefaultClient
+	if c.HTTPClient != nil {
+		httpClient = c.HTTPClient
+	}
+	hc := *httpClient
+	hc.Transport = &transport{
+		base:        hc.Transport,
+		tracer:      cfg.tracer(),
+		propagators: cfg.propagators,
+		metrics:     metrics,
+	}
+	instrumented.HTTPClient = &hc
+
+	onRetry := c.OnRetry
+	instrumented.OnRetry = func(err error, d time.Duration) {
+		metrics.retries.Add(context.Background(), 1)
+		if onRetry != nil {
+			onRetry(err, d)
+		}
+	}
+
+	return &instrumented
+}
+
+// InstrumentConnection counts the events received by the connection.
+// Remove the instrumentation by calling the returned function.
+func InstrumentConnection(conn *sse.Connection, opts ...Option) sse.EventCallbackRemover {
+	metrics := newClientMetrics(newConfig(opts).meter())
+
+	return conn.SubscribeToAllContext(func(ctx context.Context, _ sse.Event) {
+		metrics.events.Add(ctx, 1)
+	})
+}
+
+type transport struct {
+	base        http.RoundTripper
+	tracer      trace.Tracer
+	propagators propagation.TextMapPropagator
+	metrics     clientMetrics
+}
+
+func (t *transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(r.Context(), "sse.connect",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("server.address", r.URL.Host),
+			attribute.String("url.path", r.URL.Path),
+		),
+	)
+
+	// The request must not be modified by the transport, so the headers are added to a copy.
+	r = r.Clone(ctx)
+	t.propagators.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	res, err := base.RoundTrip(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.End()
+		return nil, err
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+	if res.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+	}
+
+	res.Body = &clientBody{ReadCloser: res.Body, ctx: ctx, span: span, metrics: t.metrics, start: time.Now()}
+
+	return res, nil
+}
+
+// clientBody measures the response body as it is read and ends the attempt's span when it is closed.
+type clientBody struct {
+	io.ReadCloser
+	ctx     context.Context //nolint:containedctx // The context of the attempt's span.
+	span    trace.Span
+	metrics clientMetrics
+	start   time.Time
+
+	read  int64
+	err   error
+	close sync.Once
+}
+
+func (b *clientBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.read += int64(n)
+		b.metrics.bytes.Add(b.ctx, int64(n))
+	}
+	if err != nil && err != io.EOF { //nolint:errorlint // io.EOF is returned unwrapped
+		b.err = err
+	}
+
+	return n, err
+}
+
+func (b *clientBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	b.close.Do(func() {
+		b.metrics.duration.Record(b.ctx, time.Since(b.start).Seconds())
+
+		b.span.SetAttributes(attribute.Int64("sse.bytes", b.read))
+		if b.err != nil && b.ctx.Err() == nil {
+			b.span.RecordError(b.err)
+		}
+		b.span.End()
+	})
+
+	return err
+}
@@ -0,0 +1,125 @@
+package sseotel
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tmaxmax/go-sse"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type serverMetrics struct {
+	duration metric.Float64Histogram
+	events   metric.Int64Counter
+	bytes    metric.Int64Counter
+}
+
+func newServerMetrics(m metric.Meter) serverMetrics {
+	var (
+		sm  serverMetrics
+		err error
+	)
+
+	sm.duration, err = m.Float64Histogram("sse.server.session.duration",
+		metric.WithDescription("The duration of the sessions."), metric.WithUnit("s"))
+	report(err)
+	sm.events, err = m.Int64Counter("sse.server.events",
+		metric.WithDescription("The number of events sent."), metric.WithUnit("{event}"))
+	report(err)
+	sm.bytes, err = m.Int64Counter("sse.server.bytes",
+		metric.WithDescription("The number of bytes sent."), metric.WithUnit("By"))
+	report(err)
+
+	return sm
+}
+
+// InstrumentServer returns a handler which serves the requests using the server, tracing and measuring
+// each session. The sessions' spans continue the trace propagated by the clients, if any, and they are
+// in the context of the requests received by the server's callbacks, such as OnSession.
+//
+// The server's OnSend callback is replaced with one which counts the events sent after calling
+// the previous one, so InstrumentServer must be called before the server is used.
+func InstrumentServer(s *sse.Server, opts ...Option) http.Handler {
+	cfg := newConfig(opts)
+	tracer := cfg.tracer()
+	metrics := newServerMetrics(cfg.meter())
+
+	onSend := s.OnSend
+	s.OnSend = func(sess *sse.Session, m *sse.Message) *sse.Message {
+		if onSend != nil {
+			if m = onSend(sess, m); m == nil {
+				return nil
+			}
+		}
+
+		metrics.events.Add(sess.Req.Context(), 1)
+
+		return m
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := cfg.propagators.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, "sse.session",
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &sessionWriter{ResponseWriter: w, r: r.WithContext(ctx), metrics: metrics, status: http.StatusOK}
+		start := time.Now()
+
+		s.ServeHTTP(sw, sw.r)
+
+		metrics.duration.Record(ctx, time.Since(start).Seconds())
+
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", sw.status),
+			attribute.Int64("sse.bytes", sw.written.Load()),
+		)
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// sessionWriter measures the responses written by the server. It implements a flush method,
+// so the server writes the messages through it instead of unwrapping it. The messages are
+// written from the provider's goroutines, so the number of bytes written is atomic.
+type sessionWriter struct {
+	http.ResponseWriter
+	r       *http.Request
+	metrics serverMetrics
+	status  int
+	written atomic.Int64
+}
+
+func (w *sessionWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if n > 0 {
+		w.written.Add(int64(n))
+		w.metrics.bytes.Add(w.r.Context(), int64(n))
+	}
+
+	return n, err
+}
+
+func (w *sessionWriter) FlushError() error {
+	return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
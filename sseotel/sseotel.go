@@ -0,0 +1,84 @@
+// Package sseotel instruments go-sse clients and servers with OpenTelemetry traces and metrics.
+//
+// Each connection attempt of an instrumented Client is traced with a span which lasts for as long as
+// the connection is open and the trace context is sent to the server in the request's headers. An
+// instrumented Server continues the trace of the clients in the span of each session. Both record
+// the duration of the connections, the events received or sent, the reconnections and the bytes
+// transferred using the following instruments:
+//
+//	sse.client.connection.duration  histogram  the duration of the connections, in seconds
+//	sse.client.events               counter    the events received (see InstrumentConnection)
+//	sse.client.retries              counter    the reconnection attempts
+//	sse.client.bytes                counter    the bytes received
+//	sse.server.session.duration     histogram  the duration of the sessions, in seconds
+//	sse.server.events               counter    the events sent
+//	sse.server.bytes                counter    the bytes sent
+//
+// The global providers are used, unless others are given using the options.
+package sseotel
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The name of the instrumentation scope of the tracers and meters.
+const ScopeName = "github.com/tmaxmax/go-sse/sseotel"
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithTracerProvider sets the provider of the tracer which creates the spans.
+// Defaults to the global provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = tp }
+}
+
+// WithMeterProvider sets the provider of the meter which creates the instruments.
+// Defaults to the global provider.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) { c.meterProvider = mp }
+}
+
+// WithPropagators sets the propagators which send the trace context to the server
+// and extract it from the clients' requests. Defaults to the global propagators.
+func WithPropagators(p propagation.TextMapPropagator) Option {
+	return func(c *config) { c.propagators = p }
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	propagators    propagation.TextMapPropagator
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+func (c config) tracer() trace.Tracer {
+	return c.tracerProvider.Tracer(ScopeName)
+}
+
+func (c config) meter() metric.Meter {
+	return c.meterProvider.Meter(ScopeName)
+}
+
+// report passes the errors of creating the instruments to the global error handler,
+// as the instruments are still usable when they fail to be created.
+func report(err error) {
+	if err != nil {
+		otel.Handle(err)
+	}
+}
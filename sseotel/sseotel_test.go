@@ -0,0 +1,102 @@
+package sseotel_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/sseotel"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestInstrument(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	reader := sdkmetric.NewManualReader()
+	opts := []sseotel.Option{
+		sseotel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans))),
+		sseotel.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		sseotel.WithPropagators(propagation.TraceContext{}),
+	}
+
+	s := &sse.Server{}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // The test is over.
+
+	ts := httptest.NewServer(sseotel.InstrumentServer(s, opts...))
+	defer ts.Close()
+
+	c := sseotel.InstrumentClient(&sse.Client{HTTPClient: ts.Client()}, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, http.NoBody)
+	require.NoError(t, err, "failed to create request")
+
+	conn := c.NewConnection(r)
+	sseotel.InstrumentConnection(conn, opts...)
+	conn.SubscribeMessages(func(sse.Event) { cancel() })
+
+	// The session may not be subscribed yet when the client connects, so the message is published until received.
+	go func() {
+		m := &sse.Message{}
+		m.AppendData("hello")
+
+		for ctx.Err() == nil {
+			_ = s.Publish(m)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	require.ErrorIs(t, conn.Connect(), context.Canceled, "unexpected Connect error")
+
+	var client, server sdktrace.ReadOnlySpan
+	require.Eventually(t, func() bool {
+		for _, span := range spans.Ended() {
+			switch span.Name() {
+			case "sse.connect":
+				client = span
+			case "sse.session":
+				server = span
+			}
+		}
+		return client != nil && server != nil
+	}, time.Second, 10*time.Millisecond, "spans not ended")
+
+	require.Equal(t, client.SpanContext().TraceID(), server.SpanContext().TraceID(), "trace not propagated")
+	require.Equal(t, client.SpanContext().SpanID(), server.Parent().SpanID(), "session span should be a child of the connection span")
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm), "failed to collect metrics")
+
+	sums := map[string]int64{}
+	histograms := map[string]uint64{}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					sums[m.Name] += dp.Value
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					histograms[m.Name] += dp.Count
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{"sse.client.events", "sse.client.bytes", "sse.server.events", "sse.server.bytes"} {
+		require.Positive(t, sums[name], "nothing measured by %s", name)
+	}
+	for _, name := range []string{"sse.client.connection.duration", "sse.server.session.duration"} {
+		require.Equal(t, uint64(1), histograms[name], "duration not measured by %s", name)
+	}
+}
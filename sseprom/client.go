@@ -0,0 +1,119 @@
+package sseprom
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmaxmax/go-sse"
+)
+
+// ClientMetrics is a Prometheus collector of metrics about the connections of a client
+// and the events they receive:
+//
+//	sse_client_connections_total          counter    the connections established, including reconnections
+//	sse_client_reconnects_total           counter    the reconnection attempts
+//	sse_client_open_connections           gauge      the connections open currently
+//	sse_client_events_received_total      counter    the events received, by type
+//	sse_client_event_size_bytes           histogram  the size of the received events' data
+//	sse_client_dispatch_latency_seconds   histogram  the time from receiving an event until it is dispatched
+//
+// The dispatch latency is measured only for the connections of clients which record the events'
+// metadata – see sse.Client.RecordEventMeta. Instrument each connection using Instrument.
+type ClientMetrics struct {
+	connections     prometheus.Counter
+	reconnects      prometheus.Counter
+	open            prometheus.Gauge
+	events          *prometheus.CounterVec
+	eventSize       prometheus.Histogram
+	dispatchLatency prometheus.Histogram
+}
+
+// NewClientMetrics creates the metrics of a client.
+func NewClientMetrics() *ClientMetrics {
+	return &ClientMetrics{
+		connections: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "sse", Subsystem: "client", Name: "connections_total",
+			Help: "The number of connections established, including reconnections.",
+		}),
+		reconnects: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "sse", Subsystem: "client", Name: "reconnects_total",
+			Help: "The number of reconnection attempts.",
+		}),
+		open: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "sse", Subsystem: "client", Name: "open_connections",
+			Help: "The number of connections open currently.",
+		}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sse", Subsystem: "client", Name: "events_received_total",
+			Help: "The number of events received, by type.",
+		}, []string{"type"}),
+		eventSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "sse", Subsystem: "client", Name: "event_size_bytes",
+			Help:    "The size of the received events' data, in bytes.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}),
+		dispatchLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "sse", Subsystem: "client", Name: "dispatch_latency_seconds",
+			Help:    "The time from receiving an event until it is dispatched.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *ClientMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.connections.Describe(ch)
+	m.reconnects.Describe(ch)
+	m.open.Describe(ch)
+	m.events.Describe(ch)
+	m.eventSize.Describe(ch)
+	m.dispatchLatency.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *ClientMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.connections.Collect(ch)
+	m.reconnects.Collect(ch)
+	m.open.Collect(ch)
+	m.events.Collect(ch)
+	m.eventSize.Collect(ch)
+	m.dispatchLatency.Collect(ch)
+}
+
+// Instrument collects the metrics of the given connection. Call it before connecting.
+// Stop collecting them by calling the returned function.
+func (m *ClientMetrics) Instrument(conn *sse.Connection) sse.EventCallbackRemover {
+	// The state callbacks are called from the goroutine which called Connect, so this isn't shared.
+	open := false
+
+	removeState := conn.OnStateChange(func(s sse.ConnectionState) {
+		switch s {
+		case sse.StateOpen:
+			m.connections.Inc()
+		case sse.StateRetrying:
+			m.reconnects.Inc()
+		}
+
+		if isOpen := s == sse.StateOpen; isOpen != open {
+			open = isOpen
+			if open {
+				m.open.Inc()
+			} else {
+				m.open.Dec()
+			}
+		}
+	})
+
+	removeEvents := conn.SubscribeToAll(func(e sse.Event) {
+		m.events.WithLabelValues(e.Type).Inc()
+		m.eventSize.Observe(float64(len(e.Data)))
+		if meta, ok := e.Meta(); ok {
+			m.dispatchLatency.Observe(time.Since(meta.ReceivedAt).Seconds())
+		}
+	})
+
+	return func() {
+		removeState()
+		removeEvents()
+	}
+}
@@ -0,0 +1,188 @@
+// Package sseprom exposes Prometheus metrics about go-sse clients and servers.
+//
+// Create a ServerMetrics or a ClientMetrics for each server or client you want to measure,
+// register it with a Prometheus registerer and instrument the server's provider or the
+// client's connections with it. To tell the instances apart, register their metrics using
+// prometheus.WrapRegistererWith with different labels.
+package sseprom
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tmaxmax/go-sse"
+)
+
+// ServerMetrics is a Prometheus collector of metrics about the sessions of a server
+// and the messages published to them:
+//
+//	sse_server_sessions_total             counter    the sessions subscribed
+//	sse_server_subscribers                gauge      the sessions subscribed currently
+//	sse_server_events_published_total     counter    the messages published, by topic
+//	sse_server_event_size_bytes           histogram  the size of the published messages
+//	sse_server_send_duration_seconds      histogram  the time taken to send a message to a session
+//
+// Wrap the server's provider using Provider to collect them.
+type ServerMetrics struct {
+	sessions     prometheus.Counter
+	subscribers  prometheus.Gauge
+	events       *prometheus.CounterVec
+	eventSize    prometheus.Histogram
+	sendDuration prometheus.Histogram
+}
+
+// NewServerMetrics creates the metrics of a server.
+func NewServerMetrics() *ServerMetrics {
+	return &ServerMetrics{
+		sessions: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "sse", Subsystem: "server", Name: "sessions_total",
+			Help: "The number of sessions subscribed.",
+		}),
+		subscribers: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "sse", Subsystem: "server", Name: "subscribers",
+			Help: "The number of sessions subscribed currently.",
+		}),
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "sse", Subsystem: "server", Name: "events_published_total",
+			Help: "The number of messages published, by topic.",
+		}, []string{"topic"}),
+		eventSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "sse", Subsystem: "server", Name: "event_size_bytes",
+			Help:    "The size of the published messages, in bytes.",
+			Buckets: prometheus.ExponentialBuckets(64, 4, 8),
+		}),
+		sendDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "sse", Subsystem: "server", Name: "send_duration_seconds",
+			Help:    "The time taken to send a message to a session.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 4, 8),
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *ServerMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.sessions.Describe(ch)
+	m.subscribers.Describe(ch)
+	m.events.Describe(ch)
+	m.eventSize.Describe(ch)
+	m.sendDuration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *ServerMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.sessions.Collect(ch)
+	m.subscribers.Collect(ch)
+	m.events.Collect(ch)
+	m.eventSize.Collect(ch)
+	m.sendDuration.Collect(ch)
+}
+
+// Provider returns a provider which collects the metrics of the sessions subscribed to and
+// the messages published using the given provider. Use it as the server's provider:
+//
+//	metrics := sseprom.NewServerMetrics()
+//	prometheus.MustRegister(metrics)
+//
+//	s := &sse.Server{Provider: metrics.Provider(&sse.Joe{})}
+func (m *ServerMetrics) Provider(p sse.Provider) sse.Provider {
+	return &provider{Provider: p, metrics: m}
+}
+
+// ConfigureServer applies the configuration to the server, as sse.ServerConfig.Apply does. If the
+// configuration's Metrics option is set, it also registers the server's metrics with the given
+// registerer and wraps the server's provider, which defaults to a Joe, to collect them:
+//
+//	c, err := sse.ServerConfigFromEnv()
+//	// handle error
+//	s := &sse.Server{}
+//	if _, err := sseprom.ConfigureServer(s, c, prometheus.DefaultRegisterer); err != nil {
+//		// handle error
+//	}
+//
+// It returns the server's metrics, or nil if they aren't collected.
+func ConfigureServer(s *sse.Server, c sse.ServerConfig, reg prometheus.Registerer) (*ServerMetrics, error) {
+	c.Apply(s)
+	if !c.Metrics {
+		return nil, nil
+	}
+
+	m := NewServerMetrics()
+	if err := reg.Register(m); err != nil {
+		return nil, err
+	}
+
+	p := s.Provider
+	if p == nil {
+		p = &sse.Joe{ErrorReporter: s.ErrorReporter}
+	}
+	s.Provider = m.Provider(p)
+
+	return m, nil
+}
+
+type provider struct {
+	sse.Provider
+	metrics *ServerMetrics
+}
+
+func (p *provider) Subscribe(ctx context.Context, sub sse.Subscription) error {
+	p.metrics.sessions.Inc()
+	p.metrics.subscribers.Inc()
+	defer p.metrics.subscribers.Dec()
+
+	sub.Client = &timedWriter{MessageWriter: sub.Client, duration: p.metrics.sendDuration}
+
+	return p.Provider.Subscribe(ctx, sub)
+}
+
+func (p *provider) Publish(msg *sse.Message, topics []string) error {
+	if err := p.Provider.Publish(msg, topics); err != nil {
+		return err
+	}
+
+	for _, topic := range topics {
+		p.metrics.events.WithLabelValues(topic).Inc()
+	}
+	if n, err := msg.WriteTo(io.Discard); err == nil {
+		p.metrics.eventSize.Observe(float64(n))
+	}
+
+	return nil
+}
+
+// SetReplayProvider replaces the wrapped provider's replay provider, if it supports it.
+func (p *provider) SetReplayProvider(replay sse.ReplayProvider, overlap time.Duration) error {
+	sp, ok := p.Provider.(interface {
+		SetReplayProvider(sse.ReplayProvider, time.Duration) error
+	})
+	if !ok {
+		return sse.ErrReplaySwapUnsupported
+	}
+
+	return sp.SetReplayProvider(replay, overlap)
+}
+
+// Healthy returns the wrapped provider's health, if it is a HealthChecker, and nil otherwise.
+func (p *provider) Healthy() error {
+	if hc, ok := p.Provider.(sse.HealthChecker); ok {
+		return hc.Healthy()
+	}
+
+	return nil
+}
+
+// timedWriter measures how long sending the messages to a session takes.
+type timedWriter struct {
+	sse.MessageWriter
+	duration prometheus.Histogram
+}
+
+func (w *timedWriter) Send(m *sse.Message) error {
+	start := time.Now()
+	err := w.MessageWriter.Send(m)
+	w.duration.Observe(time.Since(start).Seconds())
+
+	return err
+}
@@ -0,0 +1,134 @@
+package sseprom_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/sseprom"
+)
+
+// values returns the value of each gathered metric, summed over its labels.
+// The value of histograms is their sample count.
+func values(t *testing.T, g prometheus.Gatherer) map[string]float64 {
+	t.Helper()
+
+	families, err := g.Gather()
+	require.NoError(t, err, "failed to gather metrics")
+
+	v := map[string]float64{}
+	for _, f := range families {
+		for _, m := range f.GetMetric() {
+			switch {
+			case m.Counter != nil:
+				v[f.GetName()] += m.GetCounter().GetValue()
+			case m.Gauge != nil:
+				v[f.GetName()] += m.GetGauge().GetValue()
+			case m.Histogram != nil:
+				v[f.GetName()] += float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	return v
+}
+
+func TestMetrics(t *testing.T) {
+	serverMetrics, clientMetrics := sseprom.NewServerMetrics(), sseprom.NewClientMetrics()
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(serverMetrics), "failed to register server metrics")
+	require.NoError(t, reg.Register(clientMetrics), "failed to register client metrics")
+
+	s := &sse.Server{Provider: serverMetrics.Provider(&sse.Joe{})}
+	defer s.Shutdown(context.Background()) //nolint:errcheck // The test is over.
+
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, http.NoBody)
+	require.NoError(t, err, "failed to create request")
+
+	c := &sse.Client{HTTPClient: ts.Client(), RecordEventMeta: true}
+	conn := c.NewConnection(r)
+	clientMetrics.Instrument(conn)
+
+	var connected map[string]float64
+	conn.SubscribeMessages(func(sse.Event) {
+		connected = values(t, reg)
+		cancel()
+	})
+
+	// The session may not be subscribed yet when the client connects, so the message is published until received.
+	go func() {
+		m := &sse.Message{}
+		m.AppendData("hello")
+
+		for ctx.Err() == nil {
+			_ = s.Publish(m)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	require.ErrorIs(t, conn.Connect(), context.Canceled, "unexpected Connect error")
+
+	require.Equal(t, float64(1), connected["sse_server_subscribers"], "session not counted while subscribed")
+	require.Equal(t, float64(1), connected["sse_client_open_connections"], "connection not counted while open")
+
+	for _, name := range []string{"sse_server_events_published_total", "sse_server_event_size_bytes", "sse_server_send_duration_seconds"} {
+		require.Positive(t, connected[name], "nothing measured by %s", name)
+	}
+
+	after := values(t, reg)
+	expected := map[string]float64{
+		"sse_client_connections_total":        1,
+		"sse_client_reconnects_total":         0,
+		"sse_client_open_connections":         0,
+		"sse_client_events_received_total":    1,
+		"sse_client_event_size_bytes":         1,
+		"sse_client_dispatch_latency_seconds": 1,
+		"sse_server_sessions_total":           1,
+	}
+	for name, value := range expected {
+		require.Equal(t, value, after[name], "invalid value of %s", name)
+	}
+
+	require.Eventually(t, func() bool {
+		return values(t, reg)["sse_server_subscribers"] == 0
+	}, time.Second, 10*time.Millisecond, "session not removed after the client disconnected")
+}
+
+func TestConfigureServer(t *testing.T) {
+	t.Parallel()
+
+	reg := prometheus.NewRegistry()
+
+	s := &sse.Server{}
+	m, err := sseprom.ConfigureServer(s, sse.ServerConfig{SessionLimit: 2}, reg)
+	require.NoError(t, err, "unexpected error")
+	require.Nil(t, m, "metrics should not be collected unless enabled")
+	require.Nil(t, s.Provider, "provider should not be wrapped")
+	require.Equal(t, 2, s.SessionLimit, "configuration not applied")
+
+	m, err = sseprom.ConfigureServer(s, sse.ServerConfig{Metrics: true, KeepAlive: time.Second}, reg)
+	require.NoError(t, err, "unexpected error")
+	require.NotNil(t, m, "metrics should be collected")
+	require.NotNil(t, s.Provider, "provider should be wrapped")
+	require.Equal(t, time.Second, s.KeepAlive, "configuration not applied")
+
+	msg := &sse.Message{}
+	msg.AppendData("hello")
+	require.NoError(t, s.Publish(msg), "unexpected publish error")
+	require.Equal(t, 1.0, values(t, reg)["sse_server_events_published_total"], "published message not counted")
+
+	_, err = sseprom.ConfigureServer(&sse.Server{}, sse.ServerConfig{Metrics: true}, reg)
+	require.Error(t, err, "registering the metrics twice should fail")
+}
@@ -0,0 +1,122 @@
+// Package ssetest provides helpers for testing code which receives server-sent events.
+//
+// The assertions check the properties replay and deduplication bugs violate – the order of the events,
+// the monotonicity of their IDs and the absence of duplicates – and report every violation using
+// the given testing.TB. They return whether the property holds, so further checks can be skipped.
+package ssetest
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/tmaxmax/go-sse"
+)
+
+// A Key extracts from an event the value by which events are ordered.
+type Key func(sse.Event) (int64, error)
+
+// ByNumericID orders events by their LastEventID, which must be an integer.
+func ByNumericID(e sse.Event) (int64, error) {
+	return strconv.ParseInt(e.LastEventID, 10, 64)
+}
+
+// ByNumericData orders events by their data, which must be an integer.
+func ByNumericData(e sse.Event) (int64, error) {
+	return strconv.ParseInt(e.Data, 10, 64)
+}
+
+// AssertOrdered checks that the events are in non-decreasing order by the given key.
+func AssertOrdered(tb testing.TB, events []sse.Event, key Key) bool {
+	tb.Helper()
+
+	return assertKeys(tb, events, key, false)
+}
+
+// AssertMonotonicIDs checks that the numeric IDs of the events are strictly increasing,
+// which is expected when each event has its own ID. IDs may have gaps.
+func AssertMonotonicIDs(tb testing.TB, events []sse.Event) bool {
+	tb.Helper()
+
+	return assertKeys(tb, events, ByNumericID, true)
+}
+
+// AssertNoDuplicates checks that no two events have the same identity. If identity is nil,
+// events are identified by their ID, type and data.
+func AssertNoDuplicates(tb testing.TB, events []sse.Event, identity func(sse.Event) string) bool {
+	tb.Helper()
+
+	if identity == nil {
+		identity = defaultIdentity
+	}
+
+	ok := true
+	seen := make(map[string]int, len(events))
+	for i, e := range events {
+		id := identity(e)
+		if first, dup := seen[id]; dup {
+			tb.Errorf("ssetest: event %d is a duplicate of event %d: %+v", i, first, e)
+			ok = false
+			continue
+		}
+		seen[id] = i
+	}
+
+	return ok
+}
+
+// AssertBefore checks that the first event matched by first is received before
+// the first event matched by second, and that both are received.
+func AssertBefore(tb testing.TB, events []sse.Event, first, second func(sse.Event) bool) bool {
+	tb.Helper()
+
+	i, j := indexOf(events, first), indexOf(events, second)
+	switch {
+	case i == -1:
+		tb.Errorf("ssetest: no event matched the first predicate")
+	case j == -1:
+		tb.Errorf("ssetest: no event matched the second predicate")
+	case i >= j:
+		tb.Errorf("ssetest: event %d should have been received before event %d: %+v, %+v", i, j, events[i], events[j])
+	default:
+		return true
+	}
+
+	return false
+}
+
+func assertKeys(tb testing.TB, events []sse.Event, key Key, strict bool) bool {
+	tb.Helper()
+
+	ok := true
+	var prev int64
+	for i, e := range events {
+		k, err := key(e)
+		if err != nil {
+			tb.Errorf("ssetest: invalid key for event %d: %v", i, err)
+			ok = false
+			continue
+		}
+
+		if i > 0 && (k < prev || strict && k == prev) {
+			tb.Errorf("ssetest: event %d is out of order: key %d after %d: %+v", i, k, prev, e)
+			ok = false
+		}
+		prev = k
+	}
+
+	return ok
+}
+
+func defaultIdentity(e sse.Event) string {
+	return e.LastEventID + "\x00" + e.Type + "\x00" + e.Data
+}
+
+func indexOf(events []sse.Event, match func(sse.Event) bool) int {
+	for i, e := range events {
+		if match(e) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,71 @@
+package ssetest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/ssetest"
+)
+
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func events(ids ...string) []sse.Event {
+	evs := make([]sse.Event, 0, len(ids))
+	for _, id := range ids {
+		evs = append(evs, sse.Event{LastEventID: id, Data: "data " + id})
+	}
+	return evs
+}
+
+func TestAssertOrdered(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+	require.True(t, ssetest.AssertOrdered(r, events("1", "2", "2", "10"), ssetest.ByNumericID), "ordered events rejected")
+	require.False(t, ssetest.AssertOrdered(r, events("1", "3", "2", "x"), ssetest.ByNumericID), "unordered events accepted")
+	require.Len(t, r.errors, 2, "each violation should be reported")
+}
+
+func TestAssertMonotonicIDs(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+	require.True(t, ssetest.AssertMonotonicIDs(r, events("1", "2", "5")), "monotonic IDs rejected")
+	require.False(t, ssetest.AssertMonotonicIDs(r, events("1", "2", "2")), "repeated ID accepted")
+	require.Len(t, r.errors, 1, "invalid error count")
+}
+
+func TestAssertNoDuplicates(t *testing.T) {
+	t.Parallel()
+
+	r := &recorder{}
+	require.True(t, ssetest.AssertNoDuplicates(r, events("1", "2"), nil), "unique events rejected")
+	require.False(t, ssetest.AssertNoDuplicates(r, events("1", "2", "1"), nil), "duplicate accepted")
+	require.False(t, ssetest.AssertNoDuplicates(r, events("1", "2", "1"), func(e sse.Event) string { return e.Type }), "custom identity ignored")
+	require.Len(t, r.errors, 3, "invalid error count")
+}
+
+func TestAssertBefore(t *testing.T) {
+	t.Parallel()
+
+	is := func(id string) func(sse.Event) bool {
+		return func(e sse.Event) bool { return e.LastEventID == id }
+	}
+
+	r := &recorder{}
+	require.True(t, ssetest.AssertBefore(r, events("1", "2"), is("1"), is("2")), "ordered events rejected")
+	require.False(t, ssetest.AssertBefore(r, events("1", "2"), is("2"), is("1")), "unordered events accepted")
+	require.False(t, ssetest.AssertBefore(r, events("1"), is("1"), is("3")), "missing event accepted")
+	require.Len(t, r.errors, 2, "invalid error count")
+}
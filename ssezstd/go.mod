@@ -0,0 +1,19 @@
+module github.com/tmaxmax/go-sse/ssezstd
+
+go 1.21
+
+replace github.com/tmaxmax/go-sse => ../
+
+require (
+	github.com/klauspost/compress v1.17.11
+	github.com/stretchr/testify v1.9.0
+	github.com/tmaxmax/go-sse v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/cenkalti/backoff/v4 v4.2.1 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/exp v0.0.0-20231110203233-9a3e6036ecaa // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
@@ -0,0 +1,120 @@
+// Package ssezstd compresses event payloads using Zstandard with a dictionary that is shared
+// out-of-band by the server and its clients.
+//
+// This is effective for high volumes of small, similar events – JSON objects with the same keys,
+// for example – which otherwise don't compress well on their own. Train a dictionary from sample
+// payloads using Train, or with "zstd --train" from the Zstandard reference implementation, and
+// distribute it to the server and to the clients. The server compresses the payloads using
+// AppendData or Compress, and the clients decompress them using Decompress with the same dictionary.
+//
+// Compressed payloads are base64 encoded, as event streams must be text.
+package ssezstd
+
+import (
+	"encoding/base64"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+	"github.com/tmaxmax/go-sse"
+)
+
+// DefaultDictionarySize is the maximum dictionary size used by Train if none is given.
+const DefaultDictionarySize = 16 << 10
+
+// Train builds a Zstandard dictionary from sample payloads. The samples should be representative
+// of the payloads that will be compressed and they should not contain duplicates. If maxSize is 0,
+// DefaultDictionarySize is used.
+//
+// Training needs a sizeable sample set – usually a few hundred payloads – and it fails if the
+// samples are too few or too small.
+func Train(samples [][]byte, maxSize int) ([]byte, error) {
+	if maxSize == 0 {
+		maxSize = DefaultDictionarySize
+	}
+
+	return dict.BuildZstdDict(samples, dict.Options{MaxDictSize: maxSize, HashBytes: 6})
+}
+
+// A Compressor compresses and decompresses event payloads using a Zstandard dictionary.
+// The payloads are independent Zstandard frames, so they can be decompressed by any Zstandard
+// implementation which has the dictionary, once they are base64 decoded.
+//
+// A Compressor is safe for concurrent use.
+type Compressor struct {
+	enc *zstd.Encoder
+	dec *zstd.Decoder
+
+	uncompressed atomic.Uint64
+	compressed   atomic.Uint64
+}
+
+// NewCompressor creates a Compressor which uses the given dictionary and compression level.
+// The dictionary must be in the Zstandard dictionary format, as returned by Train or "zstd --train".
+// Use zstd.SpeedDefault if unsure about the level – event payloads are usually small,
+// so higher levels are cheap.
+func NewCompressor(dictionary []byte, level zstd.EncoderLevel) (*Compressor, error) {
+	enc, err := zstd.NewWriter(nil,
+		zstd.WithEncoderDict(dictionary),
+		zstd.WithEncoderLevel(level),
+		// The checksum would add 4 bytes to each payload, which is a lot for small events.
+		zstd.WithEncoderCRC(false),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDicts(dictionary))
+	if err != nil {
+		_ = enc.Close()
+		return nil, err
+	}
+
+	return &Compressor{enc: enc, dec: dec}, nil
+}
+
+// Compress compresses the payload and returns its textual representation.
+func (c *Compressor) Compress(payload string) string {
+	encoded := base64.RawStdEncoding.EncodeToString(c.enc.EncodeAll([]byte(payload), nil))
+
+	c.uncompressed.Add(uint64(len(payload)))
+	c.compressed.Add(uint64(len(encoded)))
+
+	return encoded
+}
+
+// AppendData compresses the payload and appends it to the message as a data field.
+func (c *Compressor) AppendData(m *sse.Message, payload string) {
+	m.AppendData(c.Compress(payload))
+}
+
+// Decompress decodes and decompresses a payload created by Compress. Use it on the client
+// with the data of the received events. Payloads compressed with another dictionary
+// are rejected.
+func (c *Compressor) Decompress(data string) (string, error) {
+	compressed, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+
+	decompressed, err := c.dec.DecodeAll(compressed, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(decompressed), nil
+}
+
+// Stats returns the statistics of the payloads compressed so far.
+func (c *Compressor) Stats() sse.CompressionStats {
+	return sse.CompressionStats{
+		Uncompressed: c.uncompressed.Load(),
+		Compressed:   c.compressed.Load(),
+	}
+}
+
+// Close releases the resources of the Compressor. It must not be used afterwards.
+func (c *Compressor) Close() error {
+	c.dec.Close()
+	return c.enc.Close()
+}
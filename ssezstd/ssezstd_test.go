@@ -0,0 +1,103 @@
+package ssezstd_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+	"github.com/tmaxmax/go-sse/ssezstd"
+)
+
+var actions = []string{"login", "logout", "purchase", "view", "search", "comment"}
+
+func payload(i int) string {
+	return fmt.Sprintf(`{"user":"user-%d","action":%q,"timestamp":%d,"client":{"platform":"web","version":"2.%d.0"}}`,
+		i, actions[i%len(actions)], 1700000000+i*37, i%7)
+}
+
+func samples(n int) [][]byte {
+	s := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		s = append(s, []byte(payload(i)))
+	}
+	return s
+}
+
+func TestCompressor(t *testing.T) {
+	t.Parallel()
+
+	dict, err := ssezstd.Train(samples(300), 0)
+	require.NoError(t, err, "unexpected training error")
+
+	server, err := ssezstd.NewCompressor(dict, zstd.SpeedDefault)
+	require.NoError(t, err, "unexpected server compressor error")
+	t.Cleanup(func() { _ = server.Close() })
+
+	client, err := ssezstd.NewCompressor(dict, zstd.SpeedDefault)
+	require.NoError(t, err, "unexpected client compressor error")
+	t.Cleanup(func() { _ = client.Close() })
+
+	p := payload(4242)
+
+	m := &sse.Message{}
+	server.AppendData(m, p)
+
+	raw := m.String()
+	require.True(t, strings.HasPrefix(raw, "data: ") && strings.HasSuffix(raw, "\n\n"), "invalid message %q", raw)
+	data := raw[len("data: ") : len(raw)-2]
+	require.False(t, strings.ContainsAny(data, "\r\n"), "compressed payload must be a single line")
+
+	decompressed, err := client.Decompress(data)
+	require.NoError(t, err, "unexpected decompression error")
+	require.Equal(t, p, decompressed, "payload altered")
+
+	stats := server.Stats()
+	require.Equal(t, uint64(len(p)), stats.Uncompressed, "invalid uncompressed size")
+	require.Equal(t, uint64(len(data)), stats.Compressed, "invalid compressed size")
+	require.Greater(t, stats.Ratio(), 1.0, "dictionary should compress small payloads")
+	require.Zero(t, client.Stats().Ratio(), "nothing was compressed by the client")
+
+	other, err := ssezstd.Train(samples(200), 4<<10)
+	require.NoError(t, err, "unexpected training error")
+	otherCompressor, err := ssezstd.NewCompressor(other, zstd.SpeedDefault)
+	require.NoError(t, err, "unexpected compressor error")
+	t.Cleanup(func() { _ = otherCompressor.Close() })
+
+	_, err = otherCompressor.Decompress(data)
+	require.Error(t, err, "decompressing with another dictionary should fail")
+}
+
+func TestCompressor_interoperable(t *testing.T) {
+	t.Parallel()
+
+	dict, err := ssezstd.Train(samples(300), 0)
+	require.NoError(t, err, "unexpected training error")
+
+	c, err := ssezstd.NewCompressor(dict, zstd.SpeedBestCompression)
+	require.NoError(t, err, "unexpected compressor error")
+	t.Cleanup(func() { _ = c.Close() })
+
+	p := payload(7)
+	compressed, err := base64.RawStdEncoding.DecodeString(c.Compress(p))
+	require.NoError(t, err, "unexpected decoding error")
+
+	r, err := zstd.NewReader(strings.NewReader(string(compressed)), zstd.WithDecoderDicts(dict))
+	require.NoError(t, err, "unexpected reader error")
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err, "payload should be a standard zstd frame")
+	require.Equal(t, p, string(decompressed), "payload altered")
+}
+
+func TestNewCompressor_invalidDictionary(t *testing.T) {
+	t.Parallel()
+
+	_, err := ssezstd.NewCompressor([]byte("not a dictionary"), zstd.SpeedDefault)
+	require.Error(t, err, "raw dictionaries should be rejected")
+}
@@ -0,0 +1,29 @@
+package sse
+
+import "context"
+
+type traceIDKey struct{}
+
+// ContextWithTraceID returns a context which carries the given trace ID. Servers use it to fill
+// the TraceID of the messages published using PublishContext, and clients return it from Event.Context,
+// so a single pushed event can be correlated end to end. Bridge it with your tracing library,
+// for example by setting the ID of the current span.
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace ID carried by the context, if any.
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// Context returns a context derived from the given one which carries the event's trace ID,
+// if it has one. Use it in callbacks to correlate the work done for the event with the publisher's trace.
+func (e Event) Context(parent context.Context) context.Context {
+	if e.TraceID == "" {
+		return parent
+	}
+
+	return ContextWithTraceID(parent, e.TraceID)
+}
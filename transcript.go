@@ -0,0 +1,165 @@
+package sse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transcripts are made of the chunks of a stream, in the order they were received. Each chunk is
+// written as a header line with the time it was received at, in milliseconds since the first chunk,
+// and its length, followed by the chunk's raw bytes and a newline:
+//
+//	0 13
+//	data: hello
+//
+//	1500 13
+//	data: world
+//
+// The chunks are written unchanged, so transcripts can be inspected and edited by hand, as long as
+// the lengths are kept in sync.
+
+// ErrInvalidTranscript is returned by NewReplayer when the transcript is malformed.
+var ErrInvalidTranscript = errors.New("go-sse: invalid transcript")
+
+// Recorder records a stream received by a client to a transcript, together with the time each chunk
+// was received at, so the stream can be replayed later using a Replayer. Use it with Connection.Tee:
+//
+//	f, _ := os.Create("stream.transcript")
+//	conn.Tee(sse.NewRecorder(f))
+//
+// The transcript has the chunks of all the responses the connection receives. If the connection
+// reconnects, the stream received after reconnecting follows the previous one in the transcript.
+// It is safe for concurrent use.
+type Recorder struct {
+	w     io.Writer
+	start time.Time
+	mu    sync.Mutex
+}
+
+// NewRecorder creates a Recorder which writes the transcript to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{w: w}
+}
+
+// Write records the given chunk of the stream.
+func (r *Recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+
+	if _, err := fmt.Fprintf(r.w, "%d %d\n", now.Sub(r.start).Milliseconds(), len(p)); err != nil {
+		return 0, err
+	}
+	if _, err := r.w.Write(p); err != nil {
+		return 0, err
+	}
+	if _, err := r.w.Write(newline); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+type transcriptChunk struct {
+	at   time.Duration
+	data []byte
+}
+
+// Replayer serves a stream recorded by a Recorder, with its original pacing, so production streams
+// can be replayed deterministically in tests and during local development. It implements the
+// http.Handler interface, so it can be used with an httptest.Server, for example.
+// Each request receives the entire stream, after which the response is ended.
+type Replayer struct {
+	// Speed multiplies the pace of the replayed stream: 2 replays it twice as fast, for example.
+	// Use math.Inf(1) to replay it without pauses. Defaults to 1.
+	Speed float64
+
+	chunks []transcriptChunk
+}
+
+// NewReplayer creates a Replayer which serves the stream recorded in the transcript read from r.
+// It returns ErrInvalidTranscript, wrapped, if the transcript is malformed.
+func NewReplayer(r io.Reader) (*Replayer, error) {
+	br := bufio.NewReader(r)
+	rp := &Replayer{}
+
+	for {
+		header, err := br.ReadString('\n')
+		if errors.Is(err, io.EOF) && header == "" {
+			return rp, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: truncated chunk header %q", ErrInvalidTranscript, header)
+		}
+
+		at, size, ok := strings.Cut(strings.TrimSuffix(header, "\n"), " ")
+		if !ok {
+			return nil, fmt.Errorf("%w: invalid chunk header %q", ErrInvalidTranscript, header)
+		}
+		ms, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid chunk time %q", ErrInvalidTranscript, at)
+		}
+		n, err := strconv.Atoi(size)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%w: invalid chunk length %q", ErrInvalidTranscript, size)
+		}
+
+		data := make([]byte, n+1)
+		if _, err := io.ReadFull(br, data); err != nil || data[n] != '\n' {
+			return nil, fmt.Errorf("%w: chunk at %dms is truncated", ErrInvalidTranscript, ms)
+		}
+
+		rp.chunks = append(rp.chunks, transcriptChunk{at: time.Duration(ms) * time.Millisecond, data: data[:n]})
+	}
+}
+
+// ServeHTTP replays the stream to the client.
+func (r *Replayer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rw := getResponseWriter(w)
+	if rw == nil {
+		http.Error(w, "Server-sent events unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header()[headerContentType] = headerContentTypeValue
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	speed := r.Speed
+	if speed <= 0 {
+		speed = 1
+	}
+
+	start := time.Now()
+	for _, c := range r.chunks {
+		if wait := time.Until(start.Add(time.Duration(float64(c.at) / speed))); wait > 0 {
+			t := time.NewTimer(wait)
+			select {
+			case <-t.C:
+			case <-req.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+
+		if _, err := rw.Write(c.data); err != nil {
+			return
+		}
+		if err := rw.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package sse_test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	const pause = 50 * time.Millisecond
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, s := range []string{"id: 1\ndata: a\n\n", "event: b\ndata: \xff\n\n"} {
+			if i > 0 {
+				time.Sleep(pause)
+			}
+			_, _ = io.WriteString(w, s)
+			w.(http.Flusher).Flush() //nolint:forcetypeassert // The test must panic if this is not true.
+		}
+	}))
+	defer ts.Close()
+
+	receive := func(url string, tee io.Writer) []sse.Event {
+		t.Helper()
+
+		c := &sse.Client{HTTPClient: ts.Client(), UTF8Policy: sse.UTF8PassThrough}
+		conn := c.NewConnection(req(t, "", url, nil))
+		if tee != nil {
+			conn.Tee(tee)
+		}
+
+		var events []sse.Event
+		conn.SubscribeToAll(func(e sse.Event) { events = append(events, e) })
+
+		require.ErrorIs(t, conn.Connect(), io.EOF, "unexpected Connect error")
+
+		return events
+	}
+
+	var transcript bytes.Buffer
+	recorded := receive(ts.URL, sse.NewRecorder(&transcript))
+	require.Len(t, recorded, 2, "events not received")
+
+	for _, speed := range []float64{1, math.Inf(1)} {
+		rp, err := sse.NewReplayer(bytes.NewReader(transcript.Bytes()))
+		require.NoError(t, err, "unexpected NewReplayer error")
+		rp.Speed = speed
+
+		rs := httptest.NewServer(rp)
+
+		start := time.Now()
+		replayed := receive(rs.URL, nil)
+		elapsed := time.Since(start)
+
+		rs.Close()
+
+		require.Equal(t, recorded, replayed, "replayed events differ")
+		if speed == 1 {
+			require.GreaterOrEqual(t, elapsed, pause, "original pacing not kept")
+		}
+	}
+}
+
+func TestNewReplayer(t *testing.T) {
+	t.Parallel()
+
+	for _, transcript := range []string{
+		"0 5\nabc\n",
+		"0 3\nabcd\n",
+		"0\nabc\n",
+		"x 3\nabc\n",
+		"0 3",
+	} {
+		_, err := sse.NewReplayer(strings.NewReader(transcript))
+		require.ErrorIs(t, err, sse.ErrInvalidTranscript, "malformed transcript %q accepted", transcript)
+	}
+
+	_, err := sse.NewReplayer(strings.NewReader("0 4\nab\nc\n12 0\n\n"))
+	require.NoError(t, err, "valid transcript rejected")
+}
@@ -0,0 +1,126 @@
+package sse
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Writer encodes messages to any io.Writer using the event stream format, so streams can be written
+// to raw network connections, files or custom servers. It implements the MessageWriter interface,
+// so it can also be subscribed to providers directly.
+//
+// The messages are buffered: call Flush to make sure they are written, or set AutoFlush.
+// A Writer is not safe for concurrent use.
+type Writer struct {
+	// AutoFlush makes the writer flush after each message, so each message is sent as soon as it is written.
+	AutoFlush bool
+	// WriteTimeout is the maximum duration of writing a message, or of flushing the buffered messages.
+	// It is applied only if the underlying writer has a SetWriteDeadline method, such as net.Conn and
+	// os.File, after which the deadline is cleared. If it is 0, writes never time out.
+	WriteTimeout time.Duration
+
+	w   io.Writer
+	buf *bufio.Writer
+}
+
+// NewWriter creates a Writer which writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, buf: bufio.NewWriter(w)}
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(time.Time) error
+}
+
+// Send writes the message. It implements the MessageWriter interface.
+func (w *Writer) Send(m *Message) error {
+	return w.write(func() error {
+		if _, err := m.WriteTo(w.buf); err != nil {
+			return err
+		}
+		if w.AutoFlush {
+			return w.flush()
+		}
+		return nil
+	})
+}
+
+// SendEvent writes an event received by a client – for example, to relay it to other clients.
+// The event's LastEventID is written as its ID, as it is the ID the event's clients resume the
+// stream from. Lifecycle events are not written.
+func (w *Writer) SendEvent(e Event) error {
+	if e.Lifecycle != "" {
+		return nil
+	}
+
+	m := &Message{TraceID: e.TraceID}
+	if e.LastEventID != "" {
+		id, err := NewID(e.LastEventID)
+		if err != nil {
+			return err
+		}
+		m.ID = id
+	}
+	if e.Type != "" {
+		typ, err := NewType(e.Type)
+		if err != nil {
+			return err
+		}
+		m.Type = typ
+	}
+	m.AppendData(e.Data)
+
+	return w.write(func() error {
+		if e.Stream != "" && isSingleLine(e.Stream) {
+			if _, err := w.buf.WriteString("stream: " + e.Stream + "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := m.WriteTo(w.buf); err != nil {
+			return err
+		}
+		if w.AutoFlush {
+			return w.flush()
+		}
+		return nil
+	})
+}
+
+// Flush writes the buffered messages, and flushes the underlying writer, if it can be flushed.
+// It implements the MessageWriter interface.
+func (w *Writer) Flush() error {
+	return w.write(w.flush)
+}
+
+func (w *Writer) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+
+	switch f := w.w.(type) {
+	case interface{ Flush() error }:
+		return f.Flush()
+	case http.Flusher:
+		f.Flush()
+	}
+
+	return nil
+}
+
+func (w *Writer) write(fn func() error) error {
+	d, ok := w.w.(writeDeadliner)
+	if !ok || w.WriteTimeout <= 0 {
+		return fn()
+	}
+
+	if err := d.SetWriteDeadline(time.Now().Add(w.WriteTimeout)); err != nil {
+		return err
+	}
+	if err := fn(); err != nil {
+		return err
+	}
+
+	return d.SetWriteDeadline(time.Time{})
+}
@@ -0,0 +1,58 @@
+package sse_test
+
+import (
+	"net"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tmaxmax/go-sse"
+)
+
+func TestWriter(t *testing.T) {
+	t.Parallel()
+
+	rec := httptest.NewRecorder()
+	w := sse.NewWriter(rec)
+
+	m := &sse.Message{ID: sse.ID("1"), Type: sse.Type("greeting")}
+	m.AppendData("hello\nworld")
+
+	require.NoError(t, w.Send(m), "unexpected Send error")
+	require.Zero(t, rec.Body.Len(), "message should be buffered")
+
+	require.NoError(t, w.SendEvent(sse.Event{LastEventID: "2", Type: "relayed", Data: "a\nb", Stream: "s"}), "unexpected SendEvent error")
+	require.NoError(t, w.SendEvent(sse.Event{Lifecycle: sse.LifecycleConnected}), "unexpected SendEvent error")
+	require.NoError(t, w.Flush(), "unexpected Flush error")
+	require.True(t, rec.Flushed, "underlying writer should be flushed")
+
+	expected := "id: 1\nevent: greeting\ndata: hello\ndata: world\n\nstream: s\nid: 2\nevent: relayed\ndata: a\ndata: b\n\n"
+	require.Equal(t, expected, rec.Body.String(), "invalid stream written")
+
+	var sb strings.Builder
+	w = sse.NewWriter(&sb)
+	w.AutoFlush = true
+
+	require.NoError(t, w.Send(m), "unexpected Send error")
+	require.Equal(t, "id: 1\nevent: greeting\ndata: hello\ndata: world\n\n", sb.String(), "message should be flushed")
+}
+
+func TestWriter_WriteTimeout(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	w := sse.NewWriter(server)
+	w.AutoFlush = true
+	w.WriteTimeout = time.Millisecond
+
+	m := &sse.Message{}
+	m.AppendData("nobody reads this")
+
+	require.ErrorIs(t, w.Send(m), os.ErrDeadlineExceeded, "write should time out")
+}